@@ -0,0 +1,201 @@
+package audio
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+)
+
+//go:embed manifest.json effects music
+var embedded embed.FS
+
+const ManifestFile = "manifest.json"
+
+type SoundFormat string
+
+const (
+	FormatVorbis = SoundFormat("vorbis")
+	FormatWav    = SoundFormat("wav")
+	FormatMP3    = SoundFormat("mp3")
+)
+
+// one entry in manifest.json
+type SoundDefinition struct {
+	// path relative to the manifest
+	File   string      `json:"file"`
+	Format SoundFormat `json:"format"`
+	// from 0 to 1, multiplied with the volume of the bus
+	Volume float64 `json:"volume"`
+	// which mixer bus the sound plays through, such as weapons or music
+	Bus string `json:"bus"`
+	// the most plays of the sound at once
+	Limit int `json:"limit"`
+	// in seconds. a looping sound plays up to LoopEnd then goes back to LoopStart, a LoopEnd of 0 is
+	// the end of the sound
+	LoopStart float64 `json:"loop_start"`
+	LoopEnd   float64 `json:"loop_end"`
+	// decode the sound while it plays rather than all at once up front, meant for long tracks, see
+	// stream.go
+	Stream bool `json:"stream"`
+}
+
+// fields left out of the manifest get these values
+func (definition *SoundDefinition) UnmarshalJSON(data []byte) error {
+	type plain SoundDefinition
+	out := plain{Volume: 1, Limit: 10}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return err
+	}
+	*definition = SoundDefinition(out)
+	return nil
+}
+
+func (definition *SoundDefinition) validate() error {
+	if definition.File == "" {
+		return fmt.Errorf("no file")
+	}
+	if !slices.Contains([]SoundFormat{FormatVorbis, FormatWav, FormatMP3}, definition.Format) {
+		return fmt.Errorf("unknown format %q", definition.Format)
+	}
+	if definition.Volume <= 0 || definition.Volume > 1 {
+		return fmt.Errorf("volume %v is not between 0 and 1", definition.Volume)
+	}
+	if definition.Bus == "" {
+		return fmt.Errorf("no bus")
+	}
+	if definition.Limit < 1 {
+		return fmt.Errorf("limit %v is less than 1", definition.Limit)
+	}
+	if definition.LoopStart < 0 || (definition.LoopEnd != 0 && definition.LoopEnd <= definition.LoopStart) {
+		return fmt.Errorf("bad loop from %v to %v", definition.LoopStart, definition.LoopEnd)
+	}
+	return nil
+}
+
+// the loop points in sample frames at the given sample rate. end is 0 if the loop runs to the end
+func (definition *SoundDefinition) LoopFrames(sampleRate int) (int, int) {
+	return int(definition.LoopStart * float64(sampleRate)), int(definition.LoopEnd * float64(sampleRate))
+}
+
+func LoadManifest(data []byte) (map[AudioName]*SoundDefinition, error) {
+	var manifest map[AudioName]*SoundDefinition
+	err := json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifest) == 0 {
+		return nil, fmt.Errorf("no sounds defined")
+	}
+
+	for name, definition := range manifest {
+		err := definition.validate()
+		if err != nil {
+			return nil, fmt.Errorf("sound %v: %w", name, err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// looks in the override directory first and the embedded sounds second
+type overlayFS struct {
+	override fs.FS
+	base     fs.FS
+}
+
+func (overlay overlayFS) Open(name string) (fs.File, error) {
+	file, err := overlay.override.Open(name)
+	if err == nil {
+		return file, nil
+	}
+	return overlay.base.Open(name)
+}
+
+// every sound the game can play along with where its data comes from
+type Library struct {
+	Manifest map[AudioName]*SoundDefinition
+	files    fs.FS
+}
+
+// the sounds built into the game. if override is not empty then files in that directory replace the built
+// in ones, and a manifest.json there adds to or replaces entries of the built in manifest
+func OpenLibrary(override string) (*Library, error) {
+	data, err := embedded.ReadFile(ManifestFile)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := LoadManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("built in %v: %w", ManifestFile, err)
+	}
+
+	var files fs.FS = embedded
+	if override != "" {
+		overrideFiles := os.DirFS(override)
+		files = overlayFS{override: overrideFiles, base: embedded}
+
+		data, err := fs.ReadFile(overrideFiles, ManifestFile)
+		if err == nil {
+			extra, err := LoadManifest(data)
+			if err != nil {
+				return nil, fmt.Errorf("%v in %v: %w", ManifestFile, override, err)
+			}
+			for name, definition := range extra {
+				manifest[name] = definition
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return &Library{
+		Manifest: manifest,
+		files:    files,
+	}, nil
+}
+
+// every sound in the manifest, sorted by name
+func (library *Library) Names() []AudioName {
+	var names []AudioName
+	for name := range library.Manifest {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// decode a sound to 16 bit stereo pcm at the given sample rate. the sound is decoded as the stream is read
+func (library *Library) Load(name AudioName, sampleRate int) (DecodedStream, error) {
+	definition, ok := library.Manifest[name]
+	if !ok {
+		return nil, fmt.Errorf("No such audio effect %v", name)
+	}
+
+	data, err := fs.ReadFile(library.files, strings.TrimPrefix(definition.File, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	switch definition.Format {
+	case FormatVorbis:
+		return vorbis.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+	case FormatWav:
+		return wav.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+	case FormatMP3:
+		return mp3.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+	}
+
+	return nil, fmt.Errorf("Unknown format %v for %v", definition.Format, name)
+}
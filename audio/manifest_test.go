@@ -0,0 +1,123 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestDecodes(t *testing.T) {
+	library, err := OpenLibrary("")
+	if err != nil {
+		t.Fatalf("unable to open sounds: %v", err)
+	}
+
+	for _, name := range library.Names() {
+		stream, err := library.Load(name, 48000)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+
+		data, err := io.ReadAll(stream)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+		} else if len(data) == 0 {
+			t.Errorf("%v decoded to nothing", name)
+		}
+	}
+
+	for _, name := range []AudioName{AudioHit1, AudioHit2, AudioShoot1, AudioStellarPulseSong, AudioChillSong, AudioExplosion1, AudioExplosion2, AudioExplosion3, AudioEnergy, AudioBeep, AudioHealth, AudioLightning} {
+		if _, ok := library.Manifest[name]; !ok {
+			t.Errorf("%v is not in the manifest", name)
+		}
+	}
+}
+
+// a mono 16 bit wav of silence
+func makeWav(frames int) []byte {
+	var out bytes.Buffer
+	write := func(value any) {
+		binary.Write(&out, binary.LittleEndian, value)
+	}
+
+	out.WriteString("RIFF")
+	write(uint32(36 + frames*2))
+	out.WriteString("WAVEfmt ")
+	write(uint32(16))
+	write(uint16(1))
+	write(uint16(1))
+	write(uint32(22050))
+	write(uint32(22050 * 2))
+	write(uint16(2))
+	write(uint16(16))
+	out.WriteString("data")
+	write(uint32(frames * 2))
+	out.Write(make([]byte, frames*2))
+	return out.Bytes()
+}
+
+func TestOverrideDirectory(t *testing.T) {
+	directory := t.TempDir()
+	manifest := `{
+		"beep": {"file": "effects/beep.wav", "format": "wav", "bus": "ui", "volume": 0.5},
+		"custom": {"file": "custom.wav", "format": "wav", "bus": "voice", "loop_start": 0.1, "loop_end": 0.2}
+	}`
+	if err := os.Mkdir(filepath.Join(directory, "effects"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		ManifestFile:                         []byte(manifest),
+		filepath.Join("effects", "beep.wav"): makeWav(1000),
+		"custom.wav":                         makeWav(2000),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(directory, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	library, err := OpenLibrary(directory)
+	if err != nil {
+		t.Fatalf("unable to open sounds: %v", err)
+	}
+
+	if beep := library.Manifest[AudioBeep]; beep.Format != FormatWav || beep.Volume != 0.5 || beep.Limit != 10 {
+		t.Errorf("beep was not replaced: %+v", beep)
+	}
+	if start, end := library.Manifest["custom"].LoopFrames(48000); start != 4800 || end != 9600 {
+		t.Errorf("custom loops from %v to %v", start, end)
+	}
+
+	for _, name := range []AudioName{AudioBeep, "custom", AudioHit1} {
+		stream, err := library.Load(name, 48000)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		if data, err := io.ReadAll(stream); err != nil || len(data) == 0 {
+			t.Errorf("%v decoded %v bytes: %v", name, len(data), err)
+		}
+	}
+}
+
+func TestManifestErrors(t *testing.T) {
+	bad := []string{
+		`{}`,
+		`{"a": {"format": "vorbis", "bus": "ui"}}`,
+		`{"a": {"file": "a.flac", "format": "flac", "bus": "ui"}}`,
+		`{"a": {"file": "a.ogg", "format": "vorbis"}}`,
+		`{"a": {"file": "a.ogg", "format": "vorbis", "bus": "ui", "volume": 2}}`,
+		`{"a": {"file": "a.ogg", "format": "vorbis", "bus": "ui", "limit": -1}}`,
+		`{"a": {"file": "a.ogg", "format": "vorbis", "bus": "ui", "loop_start": 2, "loop_end": 1}}`,
+	}
+
+	for _, data := range bad {
+		if _, err := LoadManifest([]byte(data)); err == nil {
+			t.Errorf("no error for %v", data)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+
+	libAudio "github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// bytes in one frame of decoded 16 bit stereo pcm
+const frameSize = 4
+
+// what the vorbis, wav and mp3 decoders return
+type DecodedStream interface {
+	io.ReadSeeker
+	// in bytes of decoded pcm
+	Length() int64
+}
+
+// loops decoded pcm of the given length forever, playing from the start up to the loop end the first time
+// and between the loop points after that
+func (definition *SoundDefinition) Loop(source io.ReadSeeker, length int64, sampleRate int) *libAudio.InfiniteLoop {
+	loopStart, loopEnd := definition.LoopFrames(sampleRate)
+	intro := min(int64(loopStart*frameSize), length)
+	end := length
+	if loopEnd > 0 {
+		end = min(end, int64(loopEnd*frameSize))
+	}
+	return libAudio.NewInfiniteLoopWithIntro(source, intro, end-intro)
+}
+
+// a looping stream that decodes the sound as it is read, so only the compressed data stays in memory
+func (library *Library) LoadLoop(name AudioName, sampleRate int) (*libAudio.InfiniteLoop, error) {
+	stream, err := library.Load(name, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	if stream.Length() <= 0 {
+		return nil, fmt.Errorf("%v has no length to loop over", name)
+	}
+
+	return library.Manifest[name].Loop(stream, stream.Length(), sampleRate), nil
+}
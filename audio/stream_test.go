@@ -0,0 +1,67 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"testing"
+)
+
+// heap still in use after setup has made whatever it keeps for playing a track
+func retainedHeap(b *testing.B, setup func() any) {
+	var before, after runtime.MemStats
+	var total uint64
+	for range b.N {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		kept := setup()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		runtime.KeepAlive(kept)
+		if after.HeapAlloc > before.HeapAlloc {
+			total += after.HeapAlloc - before.HeapAlloc
+		}
+	}
+	b.ReportMetric(float64(total)/float64(b.N), "retained-bytes/op")
+}
+
+// the memory a song costs when it is decoded up front compared to when it is streamed, with one second of
+// playback read either way. run with
+//
+//	GOOS=js GOARCH=wasm go test -run none -bench MusicMemory ./audio/
+func BenchmarkMusicMemory(b *testing.B) {
+	const sampleRate = 48000
+	library, err := OpenLibrary("")
+	if err != nil {
+		b.Fatalf("unable to open sounds: %v", err)
+	}
+	definition := library.Manifest[AudioStellarPulseSong]
+	second := make([]byte, sampleRate*frameSize)
+
+	b.Run("decoded", func(b *testing.B) {
+		retainedHeap(b, func() any {
+			stream, err := library.Load(AudioStellarPulseSong, sampleRate)
+			if err != nil {
+				b.Fatal(err)
+			}
+			data, err := io.ReadAll(stream)
+			if err != nil {
+				b.Fatal(err)
+			}
+			loop := definition.Loop(bytes.NewReader(data), int64(len(data)), sampleRate)
+			io.ReadFull(loop, second)
+			return loop
+		})
+	})
+
+	b.Run("streamed", func(b *testing.B) {
+		retainedHeap(b, func() any {
+			loop, err := library.LoadLoop(AudioStellarPulseSong, sampleRate)
+			if err != nil {
+				b.Fatal(err)
+			}
+			io.ReadFull(loop, second)
+			return loop
+		})
+	})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+type ColorblindPalette int
+
+const (
+	PaletteNormal ColorblindPalette = iota
+	PaletteDeuteranopia
+	PaletteProtanopia
+	PaletteTritanopia
+)
+
+var colorblindPalettes = []ColorblindPalette{PaletteNormal, PaletteDeuteranopia, PaletteProtanopia, PaletteTritanopia}
+
+func (palette ColorblindPalette) String() string {
+	switch palette {
+	case PaletteNormal:
+		return "Normal"
+	case PaletteDeuteranopia:
+		return "Deuteranopia"
+	case PaletteProtanopia:
+		return "Protanopia"
+	case PaletteTritanopia:
+		return "Tritanopia"
+	}
+
+	return "Unknown"
+}
+
+type AccessibilitySettings struct {
+	// applied to the whole frame, see PostProcessor.Draw
+	Palette ColorblindPalette
+	// shapes on gun boxes and damage numbers that tell the elements apart without color
+	ElementShapes bool
+	// a dashed ring around the second player's ship, which is otherwise only told apart by its tint
+	PartnerOutline bool
+}
+
+func DefaultAccessibilitySettings() AccessibilitySettings {
+	return AccessibilitySettings{
+		Palette: PaletteNormal,
+	}
+}
+
+type matrix3 [3][3]float64
+
+func (a matrix3) multiply(b matrix3) matrix3 {
+	var out matrix3
+	for row := range 3 {
+		for column := range 3 {
+			for i := range 3 {
+				out[row][column] += a[row][i] * b[i][column]
+			}
+		}
+	}
+	return out
+}
+
+var identity3 = matrix3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+// how each kind of color blindness sees an rgb color, from Machado, Oliveira and Fernandes 2009 at full
+// severity
+var colorblindSimulations = map[ColorblindPalette]matrix3{
+	PaletteProtanopia: {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+	PaletteDeuteranopia: {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+	PaletteTritanopia: {
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	},
+}
+
+// the color matrix that moves the difference a palette cannot see into channels it can, known as
+// daltonization. the normal palette leaves colors alone
+func (palette ColorblindPalette) Matrix() matrix3 {
+	simulation, ok := colorblindSimulations[palette]
+	if !ok {
+		return identity3
+	}
+
+	// red and green confusion is shifted into green and blue, blue and yellow confusion into red and green
+	shift := matrix3{{0, 0, 0}, {0.7, 1, 0}, {0.7, 0, 1}}
+	if palette == PaletteTritanopia {
+		shift = matrix3{{1, 0, 0.7}, {0, 1, 0.7}, {0, 0, 0}}
+	}
+
+	var lost matrix3
+	for row := range 3 {
+		for column := range 3 {
+			lost[row][column] = identity3[row][column] - simulation[row][column]
+		}
+	}
+
+	shifted := shift.multiply(lost)
+	var out matrix3
+	for row := range 3 {
+		for column := range 3 {
+			out[row][column] = identity3[row][column] + shifted[row][column]
+		}
+	}
+	return out
+}
+
+func (palette ColorblindPalette) ColorM() colorm.ColorM {
+	var out colorm.ColorM
+	matrix := palette.Matrix()
+	for row := range 3 {
+		for column := range 3 {
+			out.SetElement(row, column, matrix[row][column])
+		}
+	}
+	return out
+}
+
+// a shape for each element so they can be told apart without color: a square for physical, a circle
+// for plasma and a triangle for lightning. x, y is the center
+func drawElementShape(screen *ebiten.Image, element ElementType, x float64, y float64, size float64, clr color.Color) {
+	half := float32(size / 2)
+	cx := float32(x)
+	cy := float32(y)
+
+	switch element {
+	case ElementPlasma:
+		vector.FillCircle(screen, cx, cy, half, clr, true)
+	case ElementLightning:
+		var path vector.Path
+		path.MoveTo(cx, cy-half)
+		path.LineTo(cx+half, cy+half)
+		path.LineTo(cx-half, cy+half)
+		path.Close()
+		options := &vector.DrawPathOptions{AntiAlias: true}
+		options.ColorScale.ScaleWithColor(clr)
+		vector.FillPath(screen, &path, &vector.FillOptions{}, options)
+	default:
+		vector.FillRect(screen, cx-half, cy-half, half*2, half*2, clr, true)
+	}
+}
+
+// a slowly turning dashed ring around the second player's ship
+func (player *Player) drawPartnerOutline(screen *ebiten.Image, camera *Camera, counter uint64) {
+	if player.RespawnBlink > 0 && (player.Counter/6)%2 == 0 {
+		return
+	}
+
+	x, y := camera.Apply(player.x, player.y)
+	radius := float32(math.Max(float64(player.pic.Bounds().Dx()), float64(player.pic.Bounds().Dy()))/2 + 6)
+	start := float64(counter) * math.Pi / 180
+
+	const dashes = 8
+	var path vector.Path
+	for i := range dashes {
+		angle := start + float64(i)*2*math.Pi/dashes
+		path.MoveTo(float32(x)+radius*float32(math.Cos(angle)), float32(y)+radius*float32(math.Sin(angle)))
+		path.Arc(float32(x), float32(y), radius, float32(angle), float32(angle+math.Pi/dashes), vector.Clockwise)
+	}
+
+	options := &vector.DrawPathOptions{AntiAlias: true}
+	options.ColorScale.ScaleWithColor(color.White)
+	vector.StrokePath(screen, &path, &vector.StrokeOptions{Width: 2}, options)
+}
+
+// the element of a gun in the corner of its box, and a slash through the box when the gun is off since
+// the box being red is easy to miss
+func drawGunBoxShapes(screen *ebiten.Image, gun Gun, x float64, y float64) {
+	drawElementShape(screen, gun.ElementType(), x+17, y+3, 6, color.White)
+
+	if !gun.IsEnabled() {
+		vector.StrokeLine(screen, float32(x), float32(y+20), float32(x+20), float32(y), 2, color.RGBA{R: 0xff, A: 0xff}, true)
+	}
+}
+
+func (game *Game) elementShapes() bool {
+	return game.Accessibility != nil && game.Accessibility.ElementShapes
+}
+
+func (game *Game) drawPartnerOutline(screen *ebiten.Image, camera *Camera, player *Player) {
+	if game.Accessibility == nil || !game.Accessibility.PartnerOutline {
+		return
+	}
+	player.drawPartnerOutline(screen, camera, game.Counter)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func (a matrix3) apply(clr [3]float64) [3]float64 {
+	var out [3]float64
+	for row := range 3 {
+		for i := range 3 {
+			out[row] += a[row][i] * clr[i]
+		}
+	}
+	return out
+}
+
+func colorDistance(a [3]float64, b [3]float64) float64 {
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2]))
+}
+
+func TestColorblindPalettes(t *testing.T) {
+	if PaletteNormal.Matrix() != identity3 {
+		t.Errorf("the normal palette should leave colors alone")
+	}
+
+	// the pairs each kind of color blindness has the most trouble with
+	pairs := map[ColorblindPalette][2][3]float64{
+		PaletteProtanopia:   {{0.8, 0.2, 0.2}, {0.3, 0.6, 0.2}},
+		PaletteDeuteranopia: {{0.8, 0.2, 0.2}, {0.3, 0.6, 0.2}},
+		PaletteTritanopia:   {{0.2, 0.4, 0.9}, {0.3, 0.7, 0.3}},
+	}
+
+	for palette, pair := range pairs {
+		matrix := palette.Matrix()
+		simulation := colorblindSimulations[palette]
+
+		gray := matrix.apply([3]float64{0.5, 0.5, 0.5})
+		if colorDistance(gray, [3]float64{0.5, 0.5, 0.5}) > 0.02 {
+			t.Errorf("%v turns gray into %v", palette, gray)
+		}
+
+		before := colorDistance(simulation.apply(pair[0]), simulation.apply(pair[1]))
+		after := colorDistance(simulation.apply(matrix.apply(pair[0])), simulation.apply(matrix.apply(pair[1])))
+		if after <= before {
+			t.Errorf("%v does not make %v and %v easier to tell apart: %v before and %v after", palette, pair[0], pair[1], before, after)
+		}
+	}
+}
@@ -107,3 +107,12 @@ func (animation *Animation) Draw(screen *ebiten.Image, x float64, y float64) {
     options.GeoM.Translate(x - float64(frame.Bounds().Dx()) / 2.0, y - float64(frame.Bounds().Dy()) / 2.0)
     screen.DrawImage(frame, &options)
 }
+
+// like Draw but the frame is turned clockwise around its middle, see Bullet.Angle
+func (animation *Animation) DrawRotated(screen *ebiten.Image, x float64, y float64, radians float64) {
+    if animation.CurrentFrame >= len(animation.Frames) {
+        return
+    }
+
+    drawRotatedImage(screen, animation.Frames[animation.CurrentFrame], x, y, radians)
+}
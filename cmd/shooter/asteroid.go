@@ -42,8 +42,9 @@ func MakeAsteroid(x float64, y float64) *Asteroid {
     }
 }
 
+// an asteroid that falls out of the world is dropped by Game.Update
 func (asteroid *Asteroid) IsAlive() bool {
-    return asteroid.health > 0 && asteroid.y < ScreenHeight + 100
+    return asteroid.health > 0
 }
 
 func (asteroid *Asteroid) Damage(amount float64){
@@ -56,6 +57,10 @@ func (asteroid *Asteroid) Move() {
     asteroid.rotation += 1
 }
 
+func (asteroid *Asteroid) Radians() float64 {
+    return float64(asteroid.rotation) * asteroid.rotationSpeed * math.Pi / 180
+}
+
 func (asteroid *Asteroid) Collision(x float64, y float64, imageManager *ImageManager) bool {
     _, raw, err := imageManager.LoadImage(asteroid.pic)
     if err != nil {
@@ -99,8 +104,7 @@ func (asteroid *Asteroid) Draw(screen *ebiten.Image, imageManager *ImageManager,
         x, y := camera.Apply(asteroid.x, asteroid.y)
         options := &ebiten.DrawImageOptions{}
         options.GeoM.Translate(-float64(pic.Bounds().Dx()) / 2, -float64(pic.Bounds().Dy()) / 2)
-        radians := float64(asteroid.rotation) * asteroid.rotationSpeed * math.Pi / 180
-        options.GeoM.Rotate(radians)
+        options.GeoM.Rotate(asteroid.Radians())
         options.GeoM.Translate(x, y)
         screen.DrawImage(pic, options)
     }
@@ -0,0 +1,516 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	gameImages "github.com/kazzmir/webgl-shooter/images"
+)
+
+// background definitions, keyed by the name sent to the peer when a level starts
+//
+//go:embed backgrounds/backgrounds.json
+var BackgroundData []byte
+
+const (
+	// sprites at random places that drift along and wrap around the view, such as stars and clouds
+	BackgroundLayerScatter = "scatter"
+	// one image repeated to cover the whole view
+	BackgroundLayerTiled = "tiled"
+	// spinning galaxies drawn with the galaxy shader
+	BackgroundLayerGalaxy = "galaxy"
+	// a single rotating planet drawn with the planet shader, replaced by another once it has passed
+	BackgroundLayerPlanet = "planet"
+)
+
+type BackgroundPlanetDefinition struct {
+	Image gameImages.Image `json:"image"`
+	// scattered over the planet and rotated a little slower than it
+	Clouds []gameImages.Image `json:"clouds"`
+}
+
+type BackgroundLayerDefinition struct {
+	Kind string `json:"kind"`
+	// how far the layer moves when the camera does, 1 moves with the world and 0 stays put
+	Parallax float64 `json:"parallax"`
+	// range of how many pixels per tick things in the layer move down
+	Speed [2]float64 `json:"speed"`
+	// range of how many pixels per tick scattered sprites move sideways
+	Drift  [2]float64         `json:"drift"`
+	Images []gameImages.Image `json:"images"`
+	// scattered sprites or galaxies in the layer
+	Count int        `json:"count"`
+	Scale [2]float64 `json:"scale"`
+	// 0 is the same as 1
+	Alpha float64 `json:"alpha"`
+	// how much the alpha of scattered sprites goes up and down
+	Twinkle float64 `json:"twinkle"`
+	// range of the tilt of galaxies
+	Tilt    [2]float64                   `json:"tilt"`
+	Planets []BackgroundPlanetDefinition `json:"planets"`
+	// the axis planets rotate around, random for each planet when not given
+	Axis *[3]float64 `json:"axis"`
+	// range of how slowly planets rotate, larger is slower
+	Rotation [2]float64 `json:"rotation"`
+}
+
+type BackgroundDefinition struct {
+	// stretched over the whole view behind the layers
+	Backdrop      gameImages.Image `json:"backdrop"`
+	BackdropAlpha float64          `json:"backdrop_alpha"`
+	// levels this background is meant for, counting from 0. backgrounds without levels are used for
+	// levels that no background claims
+	Levels []int                       `json:"levels"`
+	Layers []BackgroundLayerDefinition `json:"layers"`
+}
+
+func (definition *BackgroundLayerDefinition) alpha() float64 {
+	if definition.Alpha == 0 {
+		return 1
+	}
+	return definition.Alpha
+}
+
+func (definition *BackgroundLayerDefinition) scale() float64 {
+	if definition.Scale == [2]float64{} {
+		return 1
+	}
+	return randomFloat(definition.Scale[0], definition.Scale[1])
+}
+
+func (definition *BackgroundLayerDefinition) validate() error {
+	checkRange := func(name string, values [2]float64) error {
+		if values[1] < values[0] {
+			return fmt.Errorf("invalid %v %v", name, values)
+		}
+		return nil
+	}
+
+	for name, values := range map[string][2]float64{"speed": definition.Speed, "drift": definition.Drift, "scale": definition.Scale, "tilt": definition.Tilt, "rotation": definition.Rotation} {
+		if err := checkRange(name, values); err != nil {
+			return err
+		}
+	}
+
+	if definition.Parallax < 0 || definition.Parallax > 1 {
+		return fmt.Errorf("parallax %v is not between 0 and 1", definition.Parallax)
+	}
+
+	switch definition.Kind {
+	case BackgroundLayerScatter, BackgroundLayerGalaxy:
+		if len(definition.Images) == 0 || definition.Count <= 0 {
+			return fmt.Errorf("%v layer needs images and a count", definition.Kind)
+		}
+	case BackgroundLayerTiled:
+		if len(definition.Images) != 1 {
+			return fmt.Errorf("tiled layer needs exactly one image")
+		}
+	case BackgroundLayerPlanet:
+		if len(definition.Planets) == 0 {
+			return fmt.Errorf("planet layer needs planets")
+		}
+		if definition.Rotation[0] <= 0 {
+			return fmt.Errorf("invalid rotation %v", definition.Rotation)
+		}
+	default:
+		return fmt.Errorf("unknown layer kind %q", definition.Kind)
+	}
+
+	return nil
+}
+
+func LoadBackgroundDefinitions(data []byte) (map[string]*BackgroundDefinition, error) {
+	var definitions map[string]*BackgroundDefinition
+	err := json.Unmarshal(data, &definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(definitions) == 0 {
+		return nil, fmt.Errorf("no backgrounds defined")
+	}
+
+	for name, definition := range definitions {
+		if definition.Backdrop == "" {
+			return nil, fmt.Errorf("background %v: no backdrop", name)
+		}
+		for i := range definition.Layers {
+			err := definition.Layers[i].validate()
+			if err != nil {
+				return nil, fmt.Errorf("background %v layer %v: %w", name, i, err)
+			}
+		}
+	}
+
+	return definitions, nil
+}
+
+// a random background meant for the level with the given difficulty
+func chooseBackground(definitions map[string]*BackgroundDefinition, difficulty float64) string {
+	level := levelFor(difficulty)
+
+	var claimed []string
+	var general []string
+	for name, definition := range definitions {
+		if len(definition.Levels) == 0 {
+			general = append(general, name)
+		} else if slices.Contains(definition.Levels, level) {
+			claimed = append(claimed, name)
+		}
+	}
+
+	choices := claimed
+	if len(choices) == 0 {
+		choices = general
+	}
+	if len(choices) == 0 {
+		for name := range definitions {
+			choices = append(choices, name)
+		}
+	}
+
+	// map order is random, sort so the random choice is the only randomness
+	slices.Sort(choices)
+	return choices[rand.N(len(choices))]
+}
+
+type BackgroundSprite struct {
+	x, y   float64
+	dx, dy float64
+	scale  float64
+	// where the sprite starts in its twinkle
+	phase float64
+	Image *ebiten.Image
+}
+
+type GalaxyPosition struct {
+	x, y  float64
+	dy    float64
+	tilt  float64
+	scale float64
+}
+
+type PlanetAsset struct {
+	Image *ebiten.Image
+	Cloud *ebiten.Image
+}
+
+type PlanetPosition struct {
+	x, y          float64
+	scale         float64
+	rotationSpeed float64
+	axis          Vector3
+	asset         *PlanetAsset
+}
+
+type BackgroundLayer struct {
+	Definition *BackgroundLayerDefinition
+	Images     []*ebiten.Image
+
+	Sprites      []*BackgroundSprite
+	Galaxies     []*GalaxyPosition
+	PlanetAssets []*PlanetAsset
+	Planet       *PlanetPosition
+	// how far a tiled layer has scrolled
+	offset float64
+}
+
+type Background struct {
+	Name          string
+	Backdrop      *ebiten.Image
+	BackdropAlpha float64
+	GalaxyShader  *ebiten.Shader
+	PlanetShader  *ebiten.Shader
+	Layers        []*BackgroundLayer
+}
+
+func randomPlanetAxis() Vector3 {
+	axis := Vector3{
+		X: float32(randomFloat(-0.5, 0.5)),
+		Y: float32(randomFloat(-0.5, 0.5)),
+		Z: float32(randomFloat(-0.5, 0.5)),
+	}
+
+	if axis.X == 0 && axis.Y == 0 && axis.Z == 0 {
+		axis.Z = 1
+	}
+
+	return axis
+}
+
+func (layer *BackgroundLayer) makePlanetPosition() *PlanetPosition {
+	definition := layer.Definition
+
+	axis := randomPlanetAxis()
+	if definition.Axis != nil {
+		axis = Vector3{X: float32(definition.Axis[0]), Y: float32(definition.Axis[1]), Z: float32(definition.Axis[2])}
+	}
+
+	return &PlanetPosition{
+		x:             randomFloat(0, float64(LogicalWidth)),
+		y:             randomFloat(-float64(ScreenHeight), float64(ScreenHeight)),
+		scale:         definition.scale(),
+		rotationSpeed: randomFloat(definition.Rotation[0], definition.Rotation[1]),
+		axis:          axis,
+		asset:         layer.PlanetAssets[rand.N(len(layer.PlanetAssets))],
+	}
+}
+
+func (layer *BackgroundLayer) randomImage() *ebiten.Image {
+	return layer.Images[rand.N(len(layer.Images))]
+}
+
+func (layer *BackgroundLayer) makeGalaxyPosition() *GalaxyPosition {
+	definition := layer.Definition
+	return &GalaxyPosition{
+		x:     randomFloat(0, float64(LogicalWidth)),
+		y:     randomFloat(0-float64(ScreenHeight), float64(ScreenHeight)),
+		dy:    randomFloat(definition.Speed[0], definition.Speed[1]),
+		tilt:  randomFloat(definition.Tilt[0], definition.Tilt[1]),
+		scale: definition.scale(),
+	}
+}
+
+// loads each image once no matter how many layers use it
+type backgroundImageLoader map[gameImages.Image]*ebiten.Image
+
+func (loader backgroundImageLoader) load(name gameImages.Image) (*ebiten.Image, error) {
+	if pic, ok := loader[name]; ok {
+		return pic, nil
+	}
+
+	raw, err := gameImages.LoadImage(name)
+	if err != nil {
+		return nil, err
+	}
+
+	pic := ebiten.NewImageFromImage(raw)
+	loader[name] = pic
+	return pic, nil
+}
+
+func makeBackgroundLayer(definition *BackgroundLayerDefinition, loader backgroundImageLoader) (*BackgroundLayer, error) {
+	layer := &BackgroundLayer{
+		Definition: definition,
+	}
+
+	for _, name := range definition.Images {
+		pic, err := loader.load(name)
+		if err != nil {
+			return nil, err
+		}
+		layer.Images = append(layer.Images, pic)
+	}
+
+	switch definition.Kind {
+	case BackgroundLayerScatter:
+		for range definition.Count {
+			layer.Sprites = append(layer.Sprites, &BackgroundSprite{
+				x:     randomFloat(0, float64(LogicalWidth)),
+				y:     randomFloat(0, float64(ScreenHeight)),
+				dx:    randomFloat(definition.Drift[0], definition.Drift[1]),
+				dy:    randomFloat(definition.Speed[0], definition.Speed[1]),
+				scale: definition.scale(),
+				phase: randomFloat(0, math.Pi*2),
+				Image: layer.randomImage(),
+			})
+		}
+	case BackgroundLayerGalaxy:
+		for range definition.Count {
+			layer.Galaxies = append(layer.Galaxies, layer.makeGalaxyPosition())
+		}
+	case BackgroundLayerPlanet:
+		for _, planet := range definition.Planets {
+			pic, err := loader.load(planet.Image)
+			if err != nil {
+				return nil, err
+			}
+
+			asset := &PlanetAsset{Image: pic}
+
+			var clouds []*ebiten.Image
+			for _, name := range planet.Clouds {
+				cloud, err := loader.load(name)
+				if err != nil {
+					return nil, err
+				}
+				clouds = append(clouds, cloud)
+			}
+			if len(clouds) > 0 {
+				asset.Cloud = makeCloudImage(pic.Bounds(), clouds...)
+			}
+
+			layer.PlanetAssets = append(layer.PlanetAssets, asset)
+		}
+		layer.Planet = layer.makePlanetPosition()
+	}
+
+	return layer, nil
+}
+
+// make the named background, or one that suits the level when the name is empty or unknown
+func MakeBackground(name string, difficulty float64) (*Background, error) {
+	definitions, err := LoadBackgroundDefinitions(BackgroundData)
+	if err != nil {
+		return nil, err
+	}
+
+	definition, ok := definitions[name]
+	if !ok {
+		if name != "" {
+			log.Printf("Unknown background %v", name)
+		}
+		name = chooseBackground(definitions, difficulty)
+		definition = definitions[name]
+	}
+
+	galaxyShader, err := LoadGalaxyShader()
+	if err != nil {
+		return nil, err
+	}
+
+	planetShader, err := LoadPlanetShader()
+	if err != nil {
+		return nil, err
+	}
+
+	loader := make(backgroundImageLoader)
+
+	backdrop, err := loader.load(definition.Backdrop)
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []*BackgroundLayer
+	for i := range definition.Layers {
+		layer, err := makeBackgroundLayer(&definition.Layers[i], loader)
+		if err != nil {
+			return nil, fmt.Errorf("background %v layer %v: %w", name, i, err)
+		}
+		layers = append(layers, layer)
+	}
+
+	return &Background{
+		Name:          name,
+		Backdrop:      backdrop,
+		BackdropAlpha: definition.BackdropAlpha,
+		GalaxyShader:  galaxyShader,
+		PlanetShader:  planetShader,
+		Layers:        layers,
+	}, nil
+}
+
+// layers are compared against the view as seen through their parallax factor, so they keep coming
+// whichever way the camera moves
+func (layer *BackgroundLayer) Update(camera *Camera) {
+	definition := layer.Definition
+	top := camera.y * definition.Parallax
+
+	switch definition.Kind {
+	case BackgroundLayerScatter:
+		// scattered sprites are a repeating field, so they wrap around the view in every direction
+		for _, sprite := range layer.Sprites {
+			margin := math.Max(50, float64(max(sprite.Image.Bounds().Dx(), sprite.Image.Bounds().Dy()))*sprite.scale)
+			sprite.y = wrapRange(sprite.y+sprite.dy, top-margin, top+ScreenHeight+margin)
+			if sprite.dx != 0 {
+				sprite.x = wrapRange(sprite.x+sprite.dx, -margin, float64(LogicalWidth)+margin)
+			}
+		}
+	case BackgroundLayerTiled:
+		layer.offset += definition.Speed[0]
+	case BackgroundLayerGalaxy:
+		for i, galaxy := range layer.Galaxies {
+			galaxy.y += galaxy.dy
+			if galaxy.y > top+ScreenHeight+200 || galaxy.y < top-ScreenHeight*2 {
+				layer.Galaxies[i] = layer.makeGalaxyPosition()
+				layer.Galaxies[i].y = top + randomFloat(-float64(ScreenHeight)-200, -200)
+			}
+		}
+	case BackgroundLayerPlanet:
+		planet := layer.Planet
+		planet.y += definition.Speed[0]
+		size := float64(planet.asset.Image.Bounds().Dy()) * planet.scale
+		// also start over when the camera has gone so far down that the planet would take ages to come back
+		if planet.y > top+ScreenHeight+size || planet.y < top-ScreenHeight*2 {
+			layer.Planet = layer.makePlanetPosition()
+			layer.Planet.y = top + randomFloat(-float64(ScreenHeight)-250, -250)
+		}
+	}
+}
+
+func (background *Background) Update(camera *Camera) {
+	for _, layer := range background.Layers {
+		layer.Update(camera)
+	}
+}
+
+func (layer *BackgroundLayer) Draw(screen *ebiten.Image, background *Background, camera *Camera, counter uint64) {
+	definition := layer.Definition
+	alpha := definition.alpha()
+
+	switch definition.Kind {
+	case BackgroundLayerScatter:
+		for _, sprite := range layer.Sprites {
+			x, y := camera.ApplyParallax(sprite.x, sprite.y, definition.Parallax)
+			options := &ebiten.DrawImageOptions{}
+			options.GeoM.Translate(-float64(sprite.Image.Bounds().Dx())/2, -float64(sprite.Image.Bounds().Dy())/2)
+			options.GeoM.Scale(sprite.scale, sprite.scale)
+			options.GeoM.Translate(x, y)
+			twinkle := definition.Twinkle * math.Sin(float64(counter)/30+sprite.phase)
+			options.ColorScale.ScaleAlpha(float32(math.Max(0, alpha+twinkle)))
+			screen.DrawImage(sprite.Image, options)
+		}
+	case BackgroundLayerTiled:
+		pic := layer.Images[0]
+		scale := 1.0
+		if definition.Scale[0] > 0 {
+			scale = definition.Scale[0]
+		}
+		width := float64(pic.Bounds().Dx()) * scale
+		height := float64(pic.Bounds().Dy()) * scale
+
+		startX := wrapRange(-camera.x*definition.Parallax, -width, 0)
+		startY := wrapRange(layer.offset-camera.y*definition.Parallax, -height, 0)
+		screenWidth := float64(screen.Bounds().Dx())
+		screenHeight := float64(screen.Bounds().Dy())
+		for y := startY; y < screenHeight; y += height {
+			for x := startX; x < screenWidth; x += width {
+				options := &ebiten.DrawImageOptions{}
+				options.GeoM.Scale(scale, scale)
+				options.GeoM.Translate(x, y)
+				options.ColorScale.ScaleAlpha(float32(alpha))
+				screen.DrawImage(pic, options)
+			}
+		}
+	case BackgroundLayerGalaxy:
+		useTime := float32(counter) / 60.0
+		for i, galaxy := range layer.Galaxies {
+			x, y := camera.ApplyParallax(galaxy.x, galaxy.y, definition.Parallax)
+			pic := layer.Images[i%len(layer.Images)]
+			DrawGalaxy(screen, background.GalaxyShader, pic, useTime, float32(galaxy.tilt), float32(x), float32(y), float32(galaxy.scale))
+		}
+	case BackgroundLayerPlanet:
+		planet := layer.Planet
+		x, y := camera.ApplyParallax(planet.x, planet.y, definition.Parallax)
+		planetTime := float64(counter) / planet.rotationSpeed
+		DrawPlanet(screen, x, y, planet.scale, planet.axis, planet.asset.Image, planet.asset.Cloud, planetTime, background.PlanetShader)
+	}
+}
+
+func (background *Background) Draw(screen *ebiten.Image, camera *Camera, counter uint64) {
+	options := &ebiten.DrawImageOptions{}
+	bounds := background.Backdrop.Bounds()
+	options.GeoM.Scale(float64(screen.Bounds().Dx())/float64(bounds.Dx()), float64(screen.Bounds().Dy())/float64(bounds.Dy()))
+	options.ColorScale.ScaleAlpha(float32(background.BackdropAlpha))
+	screen.DrawImage(background.Backdrop, options)
+
+	for _, layer := range background.Layers {
+		layer.Draw(screen, background, camera, counter)
+	}
+}
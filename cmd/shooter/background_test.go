@@ -0,0 +1,64 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	gameImages "github.com/kazzmir/webgl-shooter/images"
+)
+
+func TestBackgroundDefinitions(t *testing.T) {
+	definitions, err := LoadBackgroundDefinitions(BackgroundData)
+	if err != nil {
+		t.Fatalf("Unable to load backgrounds: %v", err)
+	}
+
+	for name, definition := range definitions {
+		images := []gameImages.Image{definition.Backdrop}
+		for _, layer := range definition.Layers {
+			images = append(images, layer.Images...)
+			for _, planet := range layer.Planets {
+				images = append(images, planet.Image)
+				images = append(images, planet.Clouds...)
+			}
+		}
+
+		for _, image := range images {
+			if _, err := gameImages.LoadImage(image); err != nil {
+				t.Errorf("background %v uses image %v: %v", name, image, err)
+			}
+		}
+	}
+}
+
+func TestBackgroundDefinitionErrors(t *testing.T) {
+	for _, data := range []string{
+		`{}`,
+		`{"a": {"layers": []}}`,
+		`{"a": {"backdrop": "galaxy", "layers": [{"kind": "sparkles"}]}}`,
+		`{"a": {"backdrop": "galaxy", "layers": [{"kind": "scatter", "images": ["star1"]}]}}`,
+		`{"a": {"backdrop": "galaxy", "layers": [{"kind": "tiled", "images": ["star1"], "speed": [2, 1]}]}}`,
+		`{"a": {"backdrop": "galaxy", "layers": [{"kind": "planet", "parallax": 2, "rotation": [1, 2], "planets": [{"image": "mars"}]}]}}`,
+	} {
+		if _, err := LoadBackgroundDefinitions([]byte(data)); err == nil {
+			t.Errorf("expected an error for %v", data)
+		}
+	}
+}
+
+func TestChooseBackground(t *testing.T) {
+	definitions := map[string]*BackgroundDefinition{
+		"plain":  {},
+		"other":  {},
+		"second": {Levels: []int{1}},
+	}
+
+	for range 20 {
+		if name := chooseBackground(definitions, 1.5); name != "second" {
+			t.Fatalf("level 1 got %v", name)
+		}
+		if name := chooseBackground(definitions, 1); !slices.Contains([]string{"plain", "other"}, name) {
+			t.Fatalf("level 0 got %v", name)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"math"
+)
+
+// a movement that reacts to the player. Think is called every tick before Move
+type Behavior interface {
+	Movement
+	Think(enemy *NormalEnemy, target *Player)
+}
+
+type BehaviorKind string
+
+const (
+	BehaviorNone      BehaviorKind = ""
+	BehaviorKamikaze  BehaviorKind = "kamikaze"
+	BehaviorStrafer   BehaviorKind = "strafer"
+	BehaviorFlee      BehaviorKind = "flee"
+	BehaviorFormation BehaviorKind = "formation"
+	BehaviorCarrier   BehaviorKind = "carrier"
+)
+
+// behavior used by each enemy kind in Game.MakeEnemy, kinds that are not listed follow their movement path
+var enemyBehaviors = map[int]BehaviorKind{
+	2: BehaviorKamikaze,
+	3: BehaviorStrafer,
+	4: BehaviorFlee,
+	5: BehaviorFormation,
+	7: BehaviorFlee,
+	8: BehaviorCarrier,
+}
+
+// wrap or replace a movement path with a behavior
+func makeBehavior(kind BehaviorKind, move Movement) Movement {
+	switch kind {
+	case BehaviorKamikaze:
+		return &KamikazeBehavior{velocityY: 1.5}
+	case BehaviorStrafer:
+		return &StraferBehavior{velocityY: 2, distance: randomFloat(250, 350), direction: 1}
+	case BehaviorFlee:
+		return &FleeBehavior{inner: move}
+	case BehaviorFormation:
+		return &FormationBehavior{}
+	case BehaviorCarrier:
+		return &CarrierBehavior{velocityY: 1}
+	}
+
+	return move
+}
+
+// change velocity towards (wantX, wantY) by at most accel
+func steer(velocityX, velocityY, wantX, wantY, accel float64) (float64, float64) {
+	dx := wantX - velocityX
+	dy := wantY - velocityY
+	length := math.Hypot(dx, dy)
+	if length > accel {
+		dx = dx / length * accel
+		dy = dy / length * accel
+	}
+	return velocityX + dx, velocityY + dy
+}
+
+// a velocity of the given speed pointing from (x1, y1) to (x2, y2)
+func towards(x1, y1, x2, y2, speed float64) (float64, float64) {
+	length := math.Hypot(x2-x1, y2-y1)
+	if length == 0 {
+		return 0, 0
+	}
+	return (x2 - x1) / length * speed, (y2 - y1) / length * speed
+}
+
+// descends for a moment, then locks onto the player and dives at it
+type KamikazeBehavior struct {
+	velocityX, velocityY float64
+	counter              uint64
+	locked               bool
+}
+
+func (kamikaze *KamikazeBehavior) Copy() Movement {
+	return &KamikazeBehavior{
+		velocityX: kamikaze.velocityX,
+		velocityY: kamikaze.velocityY,
+		counter:   kamikaze.counter,
+		locked:    kamikaze.locked,
+	}
+}
+
+func (kamikaze *KamikazeBehavior) Think(enemy *NormalEnemy, target *Player) {
+	kamikaze.counter += 1
+
+	if !kamikaze.locked {
+		if kamikaze.counter > 90 && enemy.y > 0 {
+			kamikaze.locked = true
+		}
+		return
+	}
+
+	// home in for a while, after that keep heading down so the enemy leaves the screen
+	if kamikaze.counter < 90+100 && target != nil && target.IsAlive() {
+		wantX, wantY := towards(enemy.x, enemy.y, target.x, target.y, 6)
+		kamikaze.velocityX, kamikaze.velocityY = steer(kamikaze.velocityX, kamikaze.velocityY, wantX, wantY, 0.25)
+	} else {
+		kamikaze.velocityY = math.Max(kamikaze.velocityY, 2)
+	}
+}
+
+func (kamikaze *KamikazeBehavior) Move(x float64, y float64) (float64, float64) {
+	return x + kamikaze.velocityX, y + kamikaze.velocityY
+}
+
+func (kamikaze *KamikazeBehavior) Coords(x float64, y float64) (float64, float64) {
+	return x, y
+}
+
+// keeps a fixed distance from the player while sliding from side to side, then leaves
+type StraferBehavior struct {
+	velocityX, velocityY float64
+	counter              uint64
+	// distance to keep from the player
+	distance float64
+	// 1 or -1, which way to circle around the player
+	direction float64
+}
+
+func (strafer *StraferBehavior) Copy() Movement {
+	return &StraferBehavior{
+		velocityX: strafer.velocityX,
+		velocityY: strafer.velocityY,
+		counter:   strafer.counter,
+		distance:  strafer.distance,
+		direction: strafer.direction,
+	}
+}
+
+const straferLifetime = 60 * 20
+
+func (strafer *StraferBehavior) Think(enemy *NormalEnemy, target *Player) {
+	strafer.counter += 1
+
+	if strafer.counter > straferLifetime || target == nil || !target.IsAlive() {
+		strafer.velocityX, strafer.velocityY = steer(strafer.velocityX, strafer.velocityY, 0, 3, 0.1)
+		return
+	}
+
+	if strafer.counter%150 == 0 {
+		strafer.direction = -strafer.direction
+	}
+
+	// move along the line to the player to fix the distance, and sideways to strafe
+	toX, toY := towards(enemy.x, enemy.y, target.x, target.y, 1)
+	away := math.Hypot(target.x-enemy.x, target.y-enemy.y) - strafer.distance
+	closing := math.Max(-2, math.Min(2, away*0.02))
+
+	wantX := toX*closing - toY*strafer.direction*2
+	wantY := toY*closing + toX*strafer.direction*2
+
+	// stay well above the target and inside the level
+	if enemy.y > target.y-ScreenHeight/3 {
+		wantY = math.Min(wantY, -1)
+	}
+	if enemy.x < 50 {
+		wantX = math.Max(wantX, 1)
+	} else if enemy.x > LogicalWidth-50 {
+		wantX = math.Min(wantX, -1)
+	}
+
+	strafer.velocityX, strafer.velocityY = steer(strafer.velocityX, strafer.velocityY, wantX, wantY, 0.15)
+}
+
+func (strafer *StraferBehavior) Move(x float64, y float64) (float64, float64) {
+	return x + strafer.velocityX, y + strafer.velocityY
+}
+
+func (strafer *StraferBehavior) Coords(x float64, y float64) (float64, float64) {
+	return x, y
+}
+
+// follows another movement until the enemy loses half its life, then runs away from the player
+type FleeBehavior struct {
+	inner                Movement
+	fleeing              bool
+	velocityX, velocityY float64
+}
+
+func (flee *FleeBehavior) Copy() Movement {
+	var inner Movement
+	if flee.inner != nil {
+		inner = flee.inner.Copy()
+	}
+
+	return &FleeBehavior{
+		inner:     inner,
+		fleeing:   flee.fleeing,
+		velocityX: flee.velocityX,
+		velocityY: flee.velocityY,
+	}
+}
+
+func (flee *FleeBehavior) Think(enemy *NormalEnemy, target *Player) {
+	if !flee.fleeing {
+		if enemy.MaxLife <= 0 || enemy.Life > enemy.MaxLife/2 {
+			return
+		}
+
+		// the inner movement may offset the drawn position, so bake that in before taking over
+		enemy.x, enemy.y = flee.Coords(enemy.x, enemy.y)
+		flee.fleeing = true
+	}
+
+	awayX, awayY := 0.0, -4.0
+	if target != nil {
+		awayX, awayY = towards(target.x, target.y, enemy.x, enemy.y, 4)
+		// always make some progress upwards so the enemy leaves the screen
+		awayY = math.Min(awayY, -1)
+	}
+	flee.velocityX, flee.velocityY = steer(flee.velocityX, flee.velocityY, awayX, awayY, 0.3)
+
+	// enemies above the screen are normally still arriving, so mark this one as gone
+	if enemy.y < -150 {
+		enemy.gone = true
+	}
+}
+
+func (flee *FleeBehavior) Move(x float64, y float64) (float64, float64) {
+	if flee.fleeing || flee.inner == nil {
+		return x + flee.velocityX, y + flee.velocityY
+	}
+	return flee.inner.Move(x, y)
+}
+
+func (flee *FleeBehavior) Coords(x float64, y float64) (float64, float64) {
+	if flee.fleeing || flee.inner == nil {
+		return x, y
+	}
+	return flee.inner.Coords(x, y)
+}
+
+// the whole group descends, then holds its shape while swaying. every so often the group breaks
+// formation and dives at the player, then regroups. every member runs the same schedule so the
+// group stays together without sharing any state
+type FormationBehavior struct {
+	// where this member belongs in the formation
+	homeX, homeY float64
+	baseX        float64
+	velocityX    float64
+	velocityY    float64
+	counter      uint64
+	started      bool
+}
+
+func (formation *FormationBehavior) Copy() Movement {
+	return &FormationBehavior{
+		homeX:     formation.homeX,
+		homeY:     formation.homeY,
+		baseX:     formation.baseX,
+		velocityX: formation.velocityX,
+		velocityY: formation.velocityY,
+		counter:   formation.counter,
+		started:   formation.started,
+	}
+}
+
+const (
+	formationDescendTime = 250
+	formationDiveEvery   = 60 * 8
+	formationDiveTime    = 60
+	formationLifetime    = 60 * 30
+)
+
+func (formation *FormationBehavior) diving() bool {
+	return formation.counter > formationDescendTime && formation.counter < formationLifetime && (formation.counter-formationDescendTime)%formationDiveEvery >= formationDiveEvery-formationDiveTime
+}
+
+func (formation *FormationBehavior) Think(enemy *NormalEnemy, target *Player) {
+	if !formation.started {
+		formation.homeX = enemy.x
+		formation.homeY = enemy.y
+		formation.baseX = enemy.x
+		formation.started = true
+	}
+
+	formation.counter += 1
+
+	switch {
+	case formation.counter < formationDescendTime:
+		formation.homeY += 1.5
+	case formation.counter < formationLifetime:
+		sway := float64(formation.counter-formationDescendTime) * 0.01
+		formation.homeX = formation.baseX + 120*math.Sin(sway)
+	default:
+		formation.homeY += 2.5
+	}
+
+	wantX, wantY := 0.0, 0.0
+	if formation.diving() && target != nil && target.IsAlive() {
+		wantX, wantY = towards(enemy.x, enemy.y, target.x, target.y, 4.5)
+	} else {
+		// regroup, slowing down as the member reaches its spot
+		dx := formation.homeX - enemy.x
+		dy := formation.homeY - enemy.y
+		speed := math.Min(4, math.Hypot(dx, dy)*0.1)
+		wantX, wantY = towards(enemy.x, enemy.y, formation.homeX, formation.homeY, speed)
+	}
+
+	formation.velocityX, formation.velocityY = steer(formation.velocityX, formation.velocityY, wantX, wantY, 0.4)
+}
+
+func (formation *FormationBehavior) Move(x float64, y float64) (float64, float64) {
+	return x + formation.velocityX, y + formation.velocityY
+}
+
+func (formation *FormationBehavior) Coords(x float64, y float64) (float64, float64) {
+	return x, y
+}
+
+// a slow shielded enemy that parks near the top of the screen and launches kamikaze minions
+type CarrierBehavior struct {
+	velocityX, velocityY float64
+	counter              uint64
+	spawned              int
+}
+
+const (
+	carrierSpawnEvery = 60 * 4
+	carrierMaxSpawns  = 6
+	carrierLifetime   = 60 * 40
+	// the kind of enemy launched by the carrier, see Game.MakeEnemy
+	carrierMinionKind = 0
+)
+
+func (carrier *CarrierBehavior) Copy() Movement {
+	return &CarrierBehavior{
+		velocityX: carrier.velocityX,
+		velocityY: carrier.velocityY,
+		counter:   carrier.counter,
+		spawned:   carrier.spawned,
+	}
+}
+
+func (carrier *CarrierBehavior) Think(enemy *NormalEnemy, target *Player) {
+	carrier.counter += 1
+
+	wantX, wantY := 0.0, 0.0
+	switch {
+	case carrier.counter > carrierLifetime:
+		wantY = 1.5
+	case enemy.y < 150:
+		wantY = 1
+	default:
+		wantX = math.Sin(float64(carrier.counter)*0.01) * 0.8
+	}
+	carrier.velocityX, carrier.velocityY = steer(carrier.velocityX, carrier.velocityY, wantX, wantY, 0.05)
+
+	if enemy.y > 0 && carrier.counter < carrierLifetime && carrier.counter%carrierSpawnEvery == 0 && carrier.spawned < carrierMaxSpawns {
+		carrier.spawned += 1
+		enemy.spawns = append(enemy.spawns, Coordinate{x: enemy.x, y: enemy.y + float64(enemy.pic.Bounds().Dy())/2})
+	}
+}
+
+func (carrier *CarrierBehavior) Move(x float64, y float64) (float64, float64) {
+	return x + carrier.velocityX, y + carrier.velocityY
+}
+
+func (carrier *CarrierBehavior) Coords(x float64, y float64) (float64, float64) {
+	return x, y
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestShieldAbsorbsDamage(t *testing.T) {
+	enemy := &NormalEnemy{Life: 10, MaxLife: 10, Shield: 5, dead: make(chan struct{})}
+
+	enemy.Damage(3)
+	if enemy.Shield != 2 || enemy.Life != 10 {
+		t.Fatalf("shield %v life %v, want 2 and 10", enemy.Shield, enemy.Life)
+	}
+
+	enemy.Damage(4)
+	if enemy.Shield != 0 || enemy.Life != 8 {
+		t.Fatalf("shield %v life %v, want 0 and 8", enemy.Shield, enemy.Life)
+	}
+}
+
+func TestFleeAtHalfLife(t *testing.T) {
+	flee := &FleeBehavior{inner: &LinearMovement{}}
+	enemy := &NormalEnemy{x: 100, y: 200, Life: 10, MaxLife: 10}
+	target := &Player{x: 100, y: 400, Health: 100}
+
+	flee.Think(enemy, target)
+	if flee.fleeing {
+		t.Fatal("enemy at full life should not flee")
+	}
+
+	enemy.Life = 4
+	for range 200 {
+		flee.Think(enemy, target)
+		enemy.x, enemy.y = flee.Move(enemy.x, enemy.y)
+	}
+
+	if !flee.fleeing || !enemy.gone {
+		t.Fatalf("fleeing %v gone %v at y %v, want the enemy to leave the screen", flee.fleeing, enemy.gone, enemy.y)
+	}
+}
+
+func TestBehaviorStateRoundTrip(t *testing.T) {
+	original := &FleeBehavior{inner: &KamikazeBehavior{velocityY: 1.5, counter: 12, locked: true}, velocityX: 1}
+
+	restored, ok := makeMovementFromState(serializeMovement(original)).(*FleeBehavior)
+	if !ok {
+		t.Fatal("flee behavior was not restored")
+	}
+
+	inner, ok := restored.inner.(*KamikazeBehavior)
+	if !ok || *inner != *original.inner.(*KamikazeBehavior) || restored.velocityX != 1 {
+		t.Fatalf("restored %+v inner %+v", restored, restored.inner)
+	}
+}
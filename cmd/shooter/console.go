@@ -0,0 +1,553 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// lines of output kept in the scrollback
+const ConsoleScrollback = 200
+
+const ConsoleHeight = 300
+
+type ConsoleCommand struct {
+	Name  string
+	Usage string
+	Help  string
+	// the command changes the world, which only the master or a single player game simulates
+	World bool
+	// completion candidates for each argument
+	Arguments [][]string
+	Run       func(game *Game, args []string) (string, error)
+}
+
+func enemyKindNames() []string {
+	var names []string
+	for kind := range EnemyKinds {
+		names = append(names, strconv.Itoa(kind))
+	}
+	return names
+}
+
+func spawnMovementNames() []string {
+	names := slices.Clone(movementKinds)
+	for _, kind := range []BehaviorKind{BehaviorKamikaze, BehaviorStrafer, BehaviorFlee, BehaviorFormation, BehaviorCarrier} {
+		names = append(names, string(kind))
+	}
+	return names
+}
+
+var gunKinds = []string{"basic", "dual-basic", "beam", "missile", "lightning"}
+
+// help and clear are handled by the console itself since they work on the console rather than the game
+var consoleCommands = []ConsoleCommand{
+	{
+		Name:      "spawn",
+		Usage:     "spawn <kind> [movement] [x y]",
+		Help:      "spawn an enemy, in the middle of the view by default",
+		World:     true,
+		Arguments: [][]string{enemyKindNames(), spawnMovementNames()},
+		Run:       consoleSpawn,
+	},
+	{
+		Name:  "boss",
+		Usage: "boss",
+		Help:  "bring in the boss now",
+		World: true,
+		Run: func(game *Game, args []string) (string, error) {
+			if game.BossMode {
+				return "", fmt.Errorf("the boss has already appeared")
+			}
+			game.SpawnBoss()
+			return "here comes the boss", nil
+		},
+	},
+	{
+		Name:      "powerup",
+		Usage:     "powerup <kind>",
+		Help:      "drop a powerup in front of the player",
+		World:     true,
+		Arguments: [][]string{powerupKinds},
+		Run: func(game *Game, args []string) (string, error) {
+			if len(args) != 1 {
+				return "", errConsoleUsage
+			}
+			powerup, err := MakePowerupKind(args[0], game.Player.x, game.Player.y-150)
+			if err != nil {
+				return "", err
+			}
+			game.AddPowerup(powerup)
+			return fmt.Sprintf("dropped %v", args[0]), nil
+		},
+	},
+	{
+		Name:      "gunlevel",
+		Usage:     "gunlevel <gun> <level>",
+		Help:      "set the level of one of the player's guns",
+		World:     true,
+		Arguments: [][]string{gunKinds},
+		Run:       consoleGunLevel,
+	},
+	{
+		Name:  "difficulty",
+		Usage: "difficulty [value]",
+		Help:  "show or set the difficulty, which affects enemies made from now on",
+		World: true,
+		Run: func(game *Game, args []string) (string, error) {
+			if len(args) == 0 {
+				return fmt.Sprintf("difficulty is %v", game.Difficulty), nil
+			}
+			value, err := strconv.ParseFloat(args[0], 64)
+			if err != nil || value <= 0 {
+				return "", fmt.Errorf("invalid difficulty %q", args[0])
+			}
+			game.Difficulty = value
+			return fmt.Sprintf("difficulty is now %v", value), nil
+		},
+	},
+	{
+		Name:  "god",
+		Usage: "god",
+		Help:  "toggle taking no damage",
+		World: true,
+		Run: func(game *Game, args []string) (string, error) {
+			game.Player.God = !game.Player.God
+			if game.Player.God {
+				return "god mode on", nil
+			}
+			return "god mode off", nil
+		},
+	},
+	{
+		Name:      "camera",
+		Usage:     "camera <x|left|center|right> [y|top|bottom]",
+		Help:      "move the view, and the player along with it, to a position in the world",
+		Arguments: [][]string{{"left", "center", "right"}, {"top", "bottom"}},
+		Run:       consoleCamera,
+	},
+	{
+		Name:  "dump",
+		Usage: "dump",
+		Help:  "write the state of the world to a json file",
+		Run:   consoleDump,
+	},
+	{
+		Name:  "timescale",
+		Usage: "timescale <scale>",
+		Help:  "run the game faster or slower, 1 is normal speed",
+		Run: func(game *Game, args []string) (string, error) {
+			if len(args) != 1 {
+				return "", errConsoleUsage
+			}
+			// both peers have to tick at the same rate
+			if game.Multiplayer != nil {
+				return "", fmt.Errorf("the time scale can not be changed in multiplayer")
+			}
+			scale, err := strconv.ParseFloat(args[0], 64)
+			if err != nil || scale < 0.1 || scale > 8 {
+				return "", fmt.Errorf("the time scale must be between 0.1 and 8")
+			}
+			game.TimeScale = scale
+			game.tickCarry = 0
+			return fmt.Sprintf("time scale is now %v", scale), nil
+		},
+	},
+}
+
+var errConsoleUsage = errors.New("wrong arguments")
+
+func findConsoleCommand(name string) (ConsoleCommand, bool) {
+	for _, command := range consoleCommands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+	return ConsoleCommand{}, false
+}
+
+func consoleCommandNames() []string {
+	names := []string{"clear", "help"}
+	for _, command := range consoleCommands {
+		names = append(names, command.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func consoleSpawn(game *Game, args []string) (string, error) {
+	if len(args) != 1 && len(args) != 2 && len(args) != 4 {
+		return "", errConsoleUsage
+	}
+
+	kind, err := strconv.Atoi(args[0])
+	if err != nil || kind < 0 || kind >= EnemyKinds {
+		return "", fmt.Errorf("enemy kind must be from 0 to %v", EnemyKinds-1)
+	}
+
+	move := makeMovement()
+	if len(args) >= 2 {
+		name := args[1]
+		if slices.Contains(movementKinds, name) {
+			move = makeMovementKind(name)
+		} else if slices.Contains(spawnMovementNames(), name) {
+			move = makeBehavior(BehaviorKind(name), makeMovement())
+		} else {
+			return "", fmt.Errorf("unknown movement %q", name)
+		}
+	}
+
+	x := game.Camera.x + game.Camera.Width()/2
+	y := 100.0
+	if len(args) == 4 {
+		x, err = strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid x %q", args[2])
+		}
+		y, err = strconv.ParseFloat(args[3], 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid y %q", args[3])
+		}
+	}
+
+	err = game.MakeEnemy(x, y, kind, move)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("spawned enemy %v at %.0f, %.0f", kind, x, y), nil
+}
+
+func consoleGunLevel(game *Game, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", errConsoleUsage
+	}
+
+	level, err := strconv.Atoi(args[1])
+	if err != nil || level < 0 {
+		return "", fmt.Errorf("invalid level %q", args[1])
+	}
+
+	// go through the network representation rather than knowing about every type of gun
+	states := serializeGuns(game.Player.Guns)
+	for i, state := range states {
+		if state.Kind == args[0] {
+			states[i].Level = level
+			states[i].Experience = 0
+			game.Player.Guns = makeGunsFromState(states)
+			return fmt.Sprintf("%v gun is now level %v", args[0], level), nil
+		}
+	}
+
+	return "", fmt.Errorf("the player has no %v gun", args[0])
+}
+
+func consoleCamera(game *Game, args []string) (string, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return "", errConsoleUsage
+	}
+
+	oldX := game.Camera.x
+	oldY := game.Camera.y
+	switch args[0] {
+	case "left":
+		game.Camera.x = 0
+	case "center":
+		game.Camera.x = (LogicalWidth - game.Camera.Width()) / 2
+	case "right":
+		game.Camera.x = LogicalWidth - game.Camera.Width()
+	default:
+		x, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid position %q", args[0])
+		}
+		game.Camera.x = x
+	}
+
+	if len(args) == 2 {
+		switch args[1] {
+		case "top":
+			game.Camera.y = 0
+		case "bottom":
+			game.Camera.y = game.Camera.WorldHeight() - ScreenHeight
+		default:
+			y, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid position %q", args[1])
+			}
+			game.Camera.y = y
+		}
+	}
+	game.Camera.Clamp()
+
+	// the camera follows the player, so the player has to come along or the camera would drift back
+	game.Player.x += game.Camera.x - oldX
+	game.Player.y += game.Camera.y - oldY
+
+	return fmt.Sprintf("camera is at %.0f, %.0f", game.Camera.x, game.Camera.y), nil
+}
+
+func consoleDump(game *Game, args []string) (string, error) {
+	data, err := json.MarshalIndent(game.makeSnapshot(), "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	filename := fmt.Sprintf("shooter-state-%s.json", time.Now().Format("2006-01-02-150405"))
+	err = os.WriteFile(filename, data, 0644)
+	if err != nil {
+		// there is no file system in the browser, so the log is the next best place
+		log.Printf("Game state: %s", data)
+		return "wrote the state to the log", nil
+	}
+
+	return fmt.Sprintf("wrote the state to %v", filename), nil
+}
+
+// complete the last word of line. returns the new line and, when the word is ambiguous, the candidates
+func completeConsoleLine(line string) (string, []string) {
+	fields := strings.Fields(line)
+	// a trailing space means a new, empty word is being started
+	if len(fields) == 0 || strings.HasSuffix(line, " ") {
+		fields = append(fields, "")
+	}
+
+	last := len(fields) - 1
+	var candidates []string
+	if last == 0 {
+		candidates = consoleCommandNames()
+	} else if command, ok := findConsoleCommand(fields[0]); ok && last-1 < len(command.Arguments) {
+		candidates = command.Arguments[last-1]
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, fields[last]) {
+			matches = append(matches, candidate)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return line, nil
+	case 1:
+		fields[last] = matches[0]
+		return strings.Join(fields, " ") + " ", nil
+	}
+
+	// fill in as much as all the matches have in common
+	common := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	fields[last] = common
+
+	return strings.Join(fields, " "), matches
+}
+
+// drop down console toggled with the backquote key
+type Console struct {
+	Open    bool
+	Input   string
+	Output  []string
+	History []string
+	// position in History while browsing with the arrow keys, len(History) is the line being typed
+	historyIndex int
+	// set for the frame the console closed in as well, so the key that closed it is not seen by the game
+	capturing bool
+	counter   uint64
+}
+
+func MakeConsole() *Console {
+	return &Console{}
+}
+
+// whether the console is taking the keyboard this frame
+func (console *Console) IsCapturing() bool {
+	return console != nil && console.capturing
+}
+
+func (console *Console) Print(format string, args ...any) {
+	console.Output = append(console.Output, strings.Split(fmt.Sprintf(format, args...), "\n")...)
+	if len(console.Output) > ConsoleScrollback {
+		console.Output = slices.Clone(console.Output[len(console.Output)-ConsoleScrollback:])
+	}
+}
+
+// run a line as a command
+func (console *Console) Execute(game *Game, line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	if len(console.History) == 0 || console.History[len(console.History)-1] != line {
+		console.History = append(console.History, line)
+	}
+	console.historyIndex = len(console.History)
+
+	console.Print("> %v", line)
+
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "help":
+		for _, command := range consoleCommands {
+			console.Print("%v - %v", command.Usage, command.Help)
+		}
+		console.Print("clear - empty the console")
+		return
+	case "clear":
+		console.Output = nil
+		return
+	}
+
+	command, ok := findConsoleCommand(fields[0])
+	if !ok {
+		console.Print("unknown command %q, try help", fields[0])
+		return
+	}
+
+	if command.World && game.isSlave() {
+		console.Print("%v can only be used by the host", command.Name)
+		return
+	}
+
+	result, err := command.Run(game, fields[1:])
+	if errors.Is(err, errConsoleUsage) {
+		console.Print("usage: %v", command.Usage)
+	} else if err != nil {
+		console.Print("%v: %v", command.Name, err)
+	} else if result != "" {
+		console.Print("%v", result)
+	}
+}
+
+func (console *Console) browseHistory(direction int) {
+	index := console.historyIndex + direction
+	if index < 0 || index > len(console.History) {
+		return
+	}
+
+	console.historyIndex = index
+	if index == len(console.History) {
+		console.Input = ""
+	} else {
+		console.Input = console.History[index]
+	}
+}
+
+func (console *Console) Update(game *Game) {
+	if console == nil {
+		return
+	}
+
+	console.counter += 1
+	wasOpen := console.Open
+
+	keys := inpututil.AppendJustPressedKeys(nil)
+	for _, key := range keys {
+		if key == ebiten.KeyBackquote {
+			console.Open = !console.Open
+			console.capturing = true
+			return
+		}
+	}
+
+	if !console.Open {
+		console.capturing = false
+		return
+	}
+
+	for _, key := range keys {
+		switch key {
+		case ebiten.KeyEscape:
+			console.Open = false
+		case ebiten.KeyEnter:
+			console.Execute(game, console.Input)
+			console.Input = ""
+		case ebiten.KeyTab:
+			line, matches := completeConsoleLine(console.Input)
+			console.Input = line
+			if len(matches) > 0 {
+				console.Print("%v", strings.Join(matches, "  "))
+			}
+		case ebiten.KeyArrowUp:
+			console.browseHistory(-1)
+		case ebiten.KeyArrowDown:
+			console.browseHistory(1)
+		}
+	}
+
+	for _, char := range ebiten.AppendInputChars(nil) {
+		if char < 32 || char == 127 || char == '`' {
+			continue
+		}
+		console.Input += string(char)
+	}
+
+	backspaceDuration := inpututil.KeyPressDuration(ebiten.KeyBackspace)
+	if backspaceDuration == 1 || (backspaceDuration >= 20 && backspaceDuration%3 == 0) {
+		if console.Input != "" {
+			_, size := utf8.DecodeLastRuneInString(console.Input)
+			console.Input = console.Input[:len(console.Input)-size]
+		}
+	}
+
+	console.capturing = console.Open || wasOpen
+}
+
+func (console *Console) Draw(screen *ebiten.Image, font *text.GoTextFaceSource) {
+	if console == nil || !console.Open {
+		return
+	}
+
+	face := text.GoTextFace{Source: font, Size: 14}
+	const lineHeight = 18
+
+	width := float32(screen.Bounds().Dx())
+	vector.FillRect(screen, 0, 0, width, ConsoleHeight, color.RGBA{R: 0x08, G: 0x0c, B: 0x12, A: 0xe0}, false)
+	vector.StrokeLine(screen, 0, ConsoleHeight, width, ConsoleHeight, 1, color.RGBA{R: 0x52, G: 0xc8, B: 0xff, A: 0xff}, false)
+
+	y := float64(ConsoleHeight - lineHeight - 8)
+	cursor := ""
+	if console.counter/30%2 == 0 {
+		cursor = "_"
+	}
+	drawText(screen, face, 10, y, "> "+console.Input+cursor, color.RGBA{R: 0x52, G: 0xff, B: 0x8c, A: 0xff})
+
+	for i := len(console.Output) - 1; i >= 0; i-- {
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+		drawText(screen, face, 10, y, console.Output[i], color.White)
+	}
+}
+
+// how many times to run Game.Update this frame. fractions of a tick are carried over so slow motion
+// still moves
+func (game *Game) TicksThisFrame() int {
+	if game.TimeScale == 1 || game.TimeScale <= 0 {
+		return 1
+	}
+
+	game.tickCarry += game.TimeScale
+	ticks := int(game.tickCarry)
+	game.tickCarry -= float64(ticks)
+	return ticks
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConsoleCompletion(t *testing.T) {
+	line, matches := completeConsoleLine("time")
+	if line != "timescale " || matches != nil {
+		t.Errorf("completed to %q with %v, want a single match", line, matches)
+	}
+
+	line, matches = completeConsoleLine("d")
+	if line != "d" || !slices.Equal(matches, []string{"difficulty", "dump"}) {
+		t.Errorf("completed to %q with %v, want both d commands", line, matches)
+	}
+
+	line, _ = completeConsoleLine("powerup energy")
+	if line != "powerup energy" {
+		t.Errorf("completed to %q, the common prefix of energy and energy-increase is energy", line)
+	}
+
+	line, _ = completeConsoleLine("spawn 3 kam")
+	if line != "spawn 3 kamikaze " {
+		t.Errorf("completed to %q, want the movement", line)
+	}
+
+	line, matches = completeConsoleLine("boss x")
+	if line != "boss x" || matches != nil {
+		t.Errorf("boss has no arguments to complete, got %q %v", line, matches)
+	}
+}
+
+func TestConsoleHistory(t *testing.T) {
+	console := MakeConsole()
+	game := &Game{}
+
+	console.Execute(game, "help")
+	console.Execute(game, "help")
+	console.Execute(game, "nonsense")
+
+	if !slices.Equal(console.History, []string{"help", "nonsense"}) {
+		t.Fatalf("history %v, repeated lines should only be kept once", console.History)
+	}
+
+	console.browseHistory(-1)
+	console.browseHistory(-1)
+	console.browseHistory(-1)
+	if console.Input != "help" {
+		t.Errorf("input %q, want the oldest line", console.Input)
+	}
+
+	console.browseHistory(1)
+	console.browseHistory(1)
+	if console.Input != "" {
+		t.Errorf("input %q, going past the newest line should clear the input", console.Input)
+	}
+
+	if last := console.Output[len(console.Output)-1]; !strings.Contains(last, "unknown command") {
+		t.Errorf("last output %q, want an unknown command message", last)
+	}
+}
+
+func TestConsoleTimeScale(t *testing.T) {
+	console := MakeConsole()
+	game := &Game{TimeScale: 1}
+
+	console.Execute(game, "timescale 0.5")
+	ticks := 0
+	for range 10 {
+		ticks += game.TicksThisFrame()
+	}
+	if ticks != 5 {
+		t.Errorf("ran %v ticks in 10 frames at half speed", ticks)
+	}
+
+	console.Execute(game, "timescale 100")
+	if game.TimeScale != 0.5 {
+		t.Errorf("time scale %v, out of range values should be rejected", game.TimeScale)
+	}
+}
+
+func TestPressesCountOnce(t *testing.T) {
+	game := &Game{TimeScale: 2}
+
+	game.frameInput[0] = keepPresses(game.frameInput[0], playerInputState{OpenMenu: true, Shoot: true})
+	first := game.takeInput(0)
+	second := game.takeInput(0)
+	if !first.OpenMenu || second.OpenMenu || !second.Shoot {
+		t.Errorf("two ticks of one frame got %+v and %+v", first, second)
+	}
+
+	// a press in a frame that runs no ticks waits for the next one
+	game.frameInput[0] = keepPresses(game.frameInput[0], playerInputState{CycleGun: 1})
+	game.frameInput[0] = keepPresses(game.frameInput[0], playerInputState{})
+	if input := game.takeInput(0); input.CycleGun != 1 {
+		t.Errorf("the press was lost, cycled %v", input.CycleGun)
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// something the player can do, each bound to a key and a controller button from the controls menu
+type InputAction int
+
+const (
+	ActionUp InputAction = iota
+	ActionDown
+	ActionLeft
+	ActionRight
+	ActionShoot
+	ActionJump
+	ActionBomb
+	ActionWeapon1
+	ActionWeapon2
+	ActionWeapon3
+	ActionWeapon4
+	ActionWeapon5
+	ActionNextGun
+	ActionPreviousGun
+	// escape always opens the menu as well, so there is a way out whatever this is bound to
+	ActionMenu
+	InputActions
+)
+
+func (action InputAction) String() string {
+	switch action {
+	case ActionUp:
+		return "Up"
+	case ActionDown:
+		return "Down"
+	case ActionLeft:
+		return "Left"
+	case ActionRight:
+		return "Right"
+	case ActionShoot:
+		return "Shoot"
+	case ActionJump:
+		return "Speed boost"
+	case ActionBomb:
+		return "Bomb"
+	case ActionWeapon1, ActionWeapon2, ActionWeapon3, ActionWeapon4, ActionWeapon5:
+		return fmt.Sprintf("Weapon %v", int(action-ActionWeapon1)+1)
+	case ActionNextGun:
+		return "Next weapon"
+	case ActionPreviousGun:
+		return "Previous weapon"
+	case ActionMenu:
+		return "Menu"
+	}
+
+	return "Unknown"
+}
+
+// the name the action is saved under, which stays the same when the label shown in the menu changes
+func (action InputAction) settingName() string {
+	switch action {
+	case ActionUp:
+		return "up"
+	case ActionDown:
+		return "down"
+	case ActionLeft:
+		return "left"
+	case ActionRight:
+		return "right"
+	case ActionShoot:
+		return "shoot"
+	case ActionJump:
+		return "jump"
+	case ActionBomb:
+		return "bomb"
+	case ActionWeapon1, ActionWeapon2, ActionWeapon3, ActionWeapon4, ActionWeapon5:
+		return fmt.Sprintf("weapon%v", int(action-ActionWeapon1)+1)
+	case ActionNextGun:
+		return "next_gun"
+	case ActionPreviousGun:
+		return "previous_gun"
+	case ActionMenu:
+		return "menu"
+	}
+
+	return "unknown"
+}
+
+// the name the controls of a local player are saved under, see loadSetting
+func controlsSetting(player int) string {
+	if player == 0 {
+		return "controls.json"
+	}
+	return fmt.Sprintf("controls%v.json", player+1)
+}
+
+// the key and controller button of every action. -1 means the action has no key or button
+type Controls struct {
+	Keys    [InputActions]ebiten.Key
+	Buttons [InputActions]ebiten.StandardGamepadButton
+}
+
+func DefaultControls() Controls {
+	var controls Controls
+	for action := range InputActions {
+		controls.Keys[action] = -1
+		controls.Buttons[action] = -1
+	}
+
+	controls.Keys[ActionUp] = ebiten.KeyArrowUp
+	controls.Keys[ActionDown] = ebiten.KeyArrowDown
+	controls.Keys[ActionLeft] = ebiten.KeyArrowLeft
+	controls.Keys[ActionRight] = ebiten.KeyArrowRight
+	controls.Keys[ActionShoot] = ebiten.KeySpace
+	controls.Keys[ActionJump] = ebiten.KeyShift
+	controls.Keys[ActionBomb] = ebiten.KeyB
+	controls.Keys[ActionWeapon1] = ebiten.KeyDigit1
+	controls.Keys[ActionWeapon2] = ebiten.KeyDigit2
+	controls.Keys[ActionWeapon3] = ebiten.KeyDigit3
+	controls.Keys[ActionWeapon4] = ebiten.KeyDigit4
+	controls.Keys[ActionWeapon5] = ebiten.KeyDigit5
+	controls.Keys[ActionMenu] = ebiten.KeyCapsLock
+
+	controls.Buttons[ActionUp] = ebiten.StandardGamepadButtonLeftTop
+	controls.Buttons[ActionDown] = ebiten.StandardGamepadButtonLeftBottom
+	controls.Buttons[ActionLeft] = ebiten.StandardGamepadButtonLeftLeft
+	controls.Buttons[ActionRight] = ebiten.StandardGamepadButtonLeftRight
+	controls.Buttons[ActionShoot] = ebiten.StandardGamepadButtonFrontBottomRight
+	controls.Buttons[ActionJump] = ebiten.StandardGamepadButtonRightBottom
+	controls.Buttons[ActionBomb] = ebiten.StandardGamepadButtonRightLeft
+	controls.Buttons[ActionNextGun] = ebiten.StandardGamepadButtonFrontTopRight
+	controls.Buttons[ActionPreviousGun] = ebiten.StandardGamepadButtonFrontTopLeft
+	controls.Buttons[ActionMenu] = ebiten.StandardGamepadButtonCenterRight
+
+	return controls
+}
+
+// the second player on the same keyboard moves with WASD and shoots with the keys around them, the
+// weapons are on the row below and T
+func DefaultPartnerControls() Controls {
+	controls := DefaultControls()
+	for action := range InputActions {
+		controls.Keys[action] = -1
+	}
+
+	controls.Keys[ActionUp] = ebiten.KeyW
+	controls.Keys[ActionDown] = ebiten.KeyS
+	controls.Keys[ActionLeft] = ebiten.KeyA
+	controls.Keys[ActionRight] = ebiten.KeyD
+	controls.Keys[ActionShoot] = ebiten.KeyF
+	controls.Keys[ActionJump] = ebiten.KeyR
+	controls.Keys[ActionBomb] = ebiten.KeyG
+	controls.Keys[ActionWeapon1] = ebiten.KeyZ
+	controls.Keys[ActionWeapon2] = ebiten.KeyX
+	controls.Keys[ActionWeapon3] = ebiten.KeyC
+	controls.Keys[ActionWeapon4] = ebiten.KeyV
+	controls.Keys[ActionWeapon5] = ebiten.KeyT
+	controls.Keys[ActionNextGun] = ebiten.KeyE
+	controls.Keys[ActionPreviousGun] = ebiten.KeyQ
+
+	return controls
+}
+
+func defaultControlsFor(player int) Controls {
+	if player == 0 {
+		return DefaultControls()
+	}
+	return DefaultPartnerControls()
+}
+
+// names of the buttons of the standard layout, as printed on an xbox style controller
+var gamepadButtonNames = map[ebiten.StandardGamepadButton]string{
+	ebiten.StandardGamepadButtonRightBottom:      "A",
+	ebiten.StandardGamepadButtonRightRight:       "B",
+	ebiten.StandardGamepadButtonRightLeft:        "X",
+	ebiten.StandardGamepadButtonRightTop:         "Y",
+	ebiten.StandardGamepadButtonFrontTopLeft:     "LB",
+	ebiten.StandardGamepadButtonFrontTopRight:    "RB",
+	ebiten.StandardGamepadButtonFrontBottomLeft:  "LT",
+	ebiten.StandardGamepadButtonFrontBottomRight: "RT",
+	ebiten.StandardGamepadButtonCenterLeft:       "Back",
+	ebiten.StandardGamepadButtonCenterRight:      "Start",
+	ebiten.StandardGamepadButtonLeftStick:        "Left stick",
+	ebiten.StandardGamepadButtonRightStick:       "Right stick",
+	ebiten.StandardGamepadButtonLeftTop:          "D-pad up",
+	ebiten.StandardGamepadButtonLeftBottom:       "D-pad down",
+	ebiten.StandardGamepadButtonLeftLeft:         "D-pad left",
+	ebiten.StandardGamepadButtonLeftRight:        "D-pad right",
+	ebiten.StandardGamepadButtonCenterCenter:     "Home",
+}
+
+func keyName(key ebiten.Key) string {
+	if key == -1 {
+		return "none"
+	}
+	return key.String()
+}
+
+func buttonName(button ebiten.StandardGamepadButton) string {
+	if name, ok := gamepadButtonNames[button]; ok {
+		return name
+	}
+	return "none"
+}
+
+func parseButtonName(name string) (ebiten.StandardGamepadButton, error) {
+	if name == "none" {
+		return -1, nil
+	}
+	for button, buttonName := range gamepadButtonNames {
+		if buttonName == name {
+			return button, nil
+		}
+	}
+	return -1, fmt.Errorf("unknown button %q", name)
+}
+
+type inputCode interface {
+	ebiten.Key | ebiten.StandardGamepadButton
+}
+
+// the other action bound to code, or -1
+func findConflict[Code inputCode](codes *[InputActions]Code, action InputAction, code Code) InputAction {
+	if code == -1 {
+		return -1
+	}
+	for other := range InputActions {
+		if other != action && codes[other] == code {
+			return other
+		}
+	}
+	return -1
+}
+
+// bind code to action. an action that had code already is given action's old code in exchange, so two
+// actions never end up on the same key. returns the action that was swapped with, or -1
+func rebind[Code inputCode](codes *[InputActions]Code, action InputAction, code Code) InputAction {
+	other := findConflict(codes, action, code)
+	if other != -1 {
+		codes[other] = codes[action]
+	}
+	codes[action] = code
+	return other
+}
+
+func (controls *Controls) BindKey(action InputAction, key ebiten.Key) InputAction {
+	return rebind(&controls.Keys, action, key)
+}
+
+func (controls *Controls) BindButton(action InputAction, button ebiten.StandardGamepadButton) InputAction {
+	return rebind(&controls.Buttons, action, button)
+}
+
+// true if the action shares its key or button with another action, which can only happen when the saved
+// controls were edited by hand
+func (controls *Controls) HasConflict(action InputAction) bool {
+	return findConflict(&controls.Keys, action, controls.Keys[action]) != -1 ||
+		findConflict(&controls.Buttons, action, controls.Buttons[action]) != -1
+}
+
+// the action the key is bound to, or -1
+func (controls *Controls) KeyAction(key ebiten.Key) InputAction {
+	for action := range InputActions {
+		if controls.Keys[action] == key {
+			return action
+		}
+	}
+	return -1
+}
+
+func (controls *Controls) KeyPressed(action InputAction) bool {
+	key := controls.Keys[action]
+	return key != -1 && ebiten.IsKeyPressed(key)
+}
+
+func (controls *Controls) KeyJustPressed(action InputAction) bool {
+	key := controls.Keys[action]
+	return key != -1 && inpututil.IsKeyJustPressed(key)
+}
+
+// how one action is saved, with keys and buttons by name so the file can be edited by hand
+type savedBinding struct {
+	Key    string `json:"key"`
+	Button string `json:"button"`
+}
+
+func (controls Controls) MarshalJSON() ([]byte, error) {
+	saved := make(map[string]savedBinding)
+	for action := range InputActions {
+		saved[action.settingName()] = savedBinding{
+			Key:    keyName(controls.Keys[action]),
+			Button: buttonName(controls.Buttons[action]),
+		}
+	}
+	return json.Marshal(saved)
+}
+
+// only replaces the actions that are in the data, the rest keep what they had. an action with a key or
+// button that is not understood is an error
+func (controls *Controls) UnmarshalJSON(data []byte) error {
+	var saved map[string]savedBinding
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	for action := range InputActions {
+		binding, ok := saved[action.settingName()]
+		if !ok {
+			continue
+		}
+
+		key := ebiten.Key(-1)
+		if binding.Key != "none" {
+			if err := key.UnmarshalText([]byte(binding.Key)); err != nil {
+				return fmt.Errorf("%v: %w", action, err)
+			}
+		}
+
+		button, err := parseButtonName(binding.Button)
+		if err != nil {
+			return fmt.Errorf("%v: %w", action, err)
+		}
+
+		controls.Keys[action] = key
+		controls.Buttons[action] = button
+	}
+
+	return nil
+}
+
+// the default controls of a local player with whatever was saved on top
+func LoadControls(player int) Controls {
+	controls := defaultControlsFor(player)
+
+	data, err := loadSetting(controlsSetting(player))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Unable to load controls: %v", err)
+		}
+		return controls
+	}
+
+	if err := json.Unmarshal(data, &controls); err != nil {
+		log.Printf("Unable to read saved controls: %v", err)
+		return defaultControlsFor(player)
+	}
+
+	return controls
+}
+
+func (controls *Controls) Save(player int) {
+	data, err := json.MarshalIndent(controls, "", "  ")
+	if err != nil {
+		log.Printf("Unable to save controls: %v", err)
+		return
+	}
+	if err := saveSetting(controlsSetting(player), data); err != nil {
+		log.Printf("Unable to save controls: %v", err)
+	}
+}
+
+// the key the menus already know about for a key bound to moving or the menu, so a player who moved
+// up to W can also go up through the menus with W. the arrows, enter and escape keep their meaning
+func (controls *Controls) menuKey(key ebiten.Key) (ebiten.Key, bool) {
+	switch key {
+	case ebiten.KeyArrowUp, ebiten.KeyArrowDown, ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter, ebiten.KeyEscape:
+		return key, false
+	}
+
+	switch controls.KeyAction(key) {
+	case ActionUp:
+		return ebiten.KeyArrowUp, true
+	case ActionDown:
+		return ebiten.KeyArrowDown, true
+	case ActionLeft:
+		return ebiten.KeyArrowLeft, true
+	case ActionRight:
+		return ebiten.KeyArrowRight, true
+	case ActionMenu:
+		return ebiten.KeyEscape, true
+	}
+	return key, false
+}
+
+// start waiting for the key or button to bind to the action
+func (menu *Menu) startCapture(action InputAction) {
+	menu.Capturing = true
+	menu.CaptureAction = action
+	menu.ControlsNotice = ""
+}
+
+// bind the first key or button pressed to the action being captured, escape gives up
+func (menu *Menu) updateCapture(run *Run) {
+	controls := menu.Controls[menu.ControlsPlayer]
+	action := menu.CaptureAction
+
+	finish := func(other InputAction, name string, otherName string) {
+		menu.Capturing = false
+		if other != -1 {
+			menu.ControlsNotice = fmt.Sprintf("%v moved from %v, %v is now on %v", name, other, other, otherName)
+		}
+		controls.Save(menu.ControlsPlayer)
+	}
+
+	// a touch screen has nothing to bind, so a tap gives up like escape does
+	if run.Touch != nil && run.Touch.Tapped() {
+		menu.Capturing = false
+		return
+	}
+
+	for _, key := range inpututil.AppendJustPressedKeys(nil) {
+		if key == ebiten.KeyEscape {
+			menu.Capturing = false
+			return
+		}
+
+		old := controls.Keys[action]
+		finish(controls.BindKey(action, key), keyName(key), keyName(old))
+		return
+	}
+
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for button := ebiten.StandardGamepadButton(0); button <= ebiten.StandardGamepadButtonMax; button++ {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+				old := controls.Buttons[action]
+				finish(controls.BindButton(action, button), buttonName(button), buttonName(old))
+				return
+			}
+		}
+	}
+}
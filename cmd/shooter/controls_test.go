@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestDefaultControlsHaveNoConflicts(t *testing.T) {
+	controls := DefaultControls()
+	for action := range InputActions {
+		if controls.HasConflict(action) {
+			t.Errorf("%v shares a key or button", action)
+		}
+	}
+}
+
+func TestRebindSwaps(t *testing.T) {
+	controls := DefaultControls()
+
+	if other := controls.BindKey(ActionShoot, ebiten.KeyB); other != ActionBomb {
+		t.Fatalf("binding the bomb key swapped with %v", other)
+	}
+	if controls.Keys[ActionShoot] != ebiten.KeyB || controls.Keys[ActionBomb] != ebiten.KeySpace {
+		t.Errorf("shoot is on %v and bomb on %v", controls.Keys[ActionShoot], controls.Keys[ActionBomb])
+	}
+
+	if other := controls.BindButton(ActionWeapon1, ebiten.StandardGamepadButtonRightTop); other != -1 {
+		t.Errorf("a free button swapped with %v", other)
+	}
+
+	// a weapon with no button takes the menu's, the menu is left with none
+	if other := controls.BindButton(ActionWeapon2, ebiten.StandardGamepadButtonCenterRight); other != ActionMenu || controls.Buttons[ActionMenu] != -1 {
+		t.Errorf("swapped with %v, menu is on %v", other, controls.Buttons[ActionMenu])
+	}
+
+	for action := range InputActions {
+		if controls.HasConflict(action) {
+			t.Errorf("%v conflicts after rebinding", action)
+		}
+	}
+}
+
+func TestControlsSaveAndLoad(t *testing.T) {
+	controls := DefaultControls()
+	controls.BindKey(ActionUp, ebiten.KeyW)
+	controls.BindKey(ActionMenu, ebiten.KeyP)
+	controls.Buttons[ActionBomb] = -1
+
+	data, err := json.Marshal(controls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := DefaultControls()
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded != controls {
+		t.Errorf("loaded %+v, saved %+v", loaded, controls)
+	}
+
+	// actions missing from the file keep their defaults, and a hand edit can leave a conflict
+	partial := DefaultControls()
+	if err := json.Unmarshal([]byte(`{"shoot": {"key": "ArrowUp", "button": "none"}}`), &partial); err != nil {
+		t.Fatal(err)
+	}
+	if partial.Keys[ActionBomb] != ebiten.KeyB || !partial.HasConflict(ActionShoot) || !partial.HasConflict(ActionUp) {
+		t.Errorf("partial controls %+v", partial)
+	}
+
+	if err := json.Unmarshal([]byte(`{"shoot": {"key": "NotAKey", "button": "A"}}`), &partial); err == nil {
+		t.Errorf("an unknown key was accepted")
+	}
+}
+
+func TestMenuKey(t *testing.T) {
+	controls := DefaultControls()
+	controls.BindKey(ActionUp, ebiten.KeyW)
+
+	if key, ok := controls.menuKey(ebiten.KeyW); !ok || key != ebiten.KeyArrowUp {
+		t.Errorf("W went to %v", key)
+	}
+	// the arrow up now belongs to no action but still moves up through the menus
+	if _, ok := controls.menuKey(ebiten.KeyArrowUp); ok {
+		t.Errorf("arrow up was translated")
+	}
+	if key, ok := controls.menuKey(ebiten.KeyCapsLock); !ok || key != ebiten.KeyEscape {
+		t.Errorf("caps lock went to %v", key)
+	}
+	if _, ok := controls.menuKey(ebiten.KeySpace); ok {
+		t.Errorf("shoot was translated")
+	}
+}
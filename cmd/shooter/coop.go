@@ -0,0 +1,194 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// how close the shared camera lets a player get to the edge of the view before the screen splits
+const CoopEdgePadding = 100
+
+// once split, the players have to come back within this fraction of the distance that split the
+// screen before it joins again, so the screen does not flicker between the two at the limit
+const CoopJoinFraction = 0.8
+
+// how much of the way to the middle of the players the shared camera moves each tick
+const CoopCameraEase = 0.15
+
+// the second player's HUD is drawn this far in from the right of the view
+const CoopHudWidth = 420
+
+// two players sharing one screen, player 2 is the game's RemotePlayer and is flown from this machine
+// with the second keyboard layout or the second controller
+type LocalCoop struct {
+	// player 2's keyboard layout
+	Controls *Controls
+	Split    bool
+	// the cameras of the left and right half of a split screen
+	Views [2]*Camera
+	// the player each half follows, whoever was on the left when the screen split is on the left
+	Followed [2]*Player
+
+	// each half is drawn here before it goes on the screen
+	images [2]*ebiten.Image
+}
+
+func MakeLocalCoop(controls *Controls) *LocalCoop {
+	return &LocalCoop{
+		Controls: controls,
+		Views:    [2]*Camera{&Camera{}, &Camera{}},
+	}
+}
+
+// whether players this far apart need a split screen. a shared view of the given width fits them as
+// long as neither has to be closer than CoopEdgePadding to its edge
+func coopSplit(dx float64, dy float64, split bool, width float64) bool {
+	fitX := width - 2*CoopEdgePadding
+	fitY := float64(ScreenHeight - 2*CoopEdgePadding)
+	if split {
+		fitX *= CoopJoinFraction
+		fitY *= CoopJoinFraction
+	}
+	return dx > fitX || dy > fitY
+}
+
+// both players on this machine, the loadout picked is player 1's and player 2 flies the default
+func (run *Run) StartLocalCoop() error {
+	err := run.StartGame(multiplayerRoleMaster, false, "", DefaultLoadout())
+	if err != nil {
+		return err
+	}
+
+	run.Game.startLocalCoop(run)
+	return nil
+}
+
+// this game decides everything about the world like a master does, it just has nobody to tell
+func (game *Game) startLocalCoop(run *Run) {
+	game.Multiplayer.Peer = nil
+	// there is no copy of the partner to wait for, it is right here
+	game.Multiplayer.PartnerHeard = true
+	game.Coop = MakeLocalCoop(run.Controls[1])
+}
+
+func (game *Game) resolvePartnerInput() playerInputState {
+	if game.Console.IsCapturing() {
+		return playerInputState{}
+	}
+	input := gatherLocalPlayerInput(game.Coop.Controls)
+	if game.Gamepads != nil {
+		input = mergeInput(input, game.Gamepads.PlayerInput(1))
+	}
+	return input
+}
+
+// fly player 2 and move the cameras to keep both players in view
+func (game *Game) updateLocalCoop(run *Run) error {
+	partner := game.RemotePlayer
+	if game.Coop == nil || partner == nil {
+		return nil
+	}
+
+	if partner.HurtTime > 0 {
+		partner.HurtTime -= 1
+	}
+
+	input := game.takeInput(1)
+	if partner.IsAlive() {
+		err := partner.ApplyInput(game, run, input, true)
+		if err != nil {
+			return err
+		}
+
+		partner.Move(game.Camera.WorldHeight())
+		game.Camera.Contain(partner)
+	}
+
+	game.Coop.UpdateCameras(game.Camera, game.Player, partner)
+	return nil
+}
+
+// the shared camera always sits between the players, even while the screen is split effects are heard
+// from there. the halves of a split screen each follow one player
+func (coop *LocalCoop) UpdateCameras(camera *Camera, first *Player, second *Player) {
+	dx := math.Abs(first.x - second.x)
+	dy := math.Abs(first.y - second.y)
+	// a scrolling camera keeps both players inside the same band of the world already
+	if camera.IsScrolling() {
+		dy = 0
+	}
+
+	wasSplit := coop.Split
+	coop.Split = coopSplit(dx, dy, coop.Split, camera.Width())
+
+	camera.x += ((first.x+second.x)/2 - camera.Width()/2 - camera.x) * CoopCameraEase
+	if !camera.IsScrolling() {
+		camera.y += ((first.y+second.y)/2 - ScreenHeight/2 - camera.y) * CoopCameraEase
+	}
+	camera.Clamp()
+
+	if !coop.Split {
+		return
+	}
+
+	half := camera.Width() / 2
+	if !wasSplit {
+		coop.Followed = [2]*Player{first, second}
+		if second.x < first.x {
+			coop.Followed = [2]*Player{second, first}
+		}
+
+		// start the halves where the shared view showed them so the split does not jump
+		for i, view := range coop.Views {
+			view.x = camera.x + float64(i)*half
+			view.y = camera.y
+		}
+	}
+
+	for i, view := range coop.Views {
+		view.width = half
+		view.scrollSpeed = camera.scrollSpeed
+		view.worldHeight = camera.worldHeight
+		if camera.IsScrolling() {
+			view.y = camera.y
+		}
+		view.TrackPlayer(coop.Followed[i])
+	}
+}
+
+// draw the world once for each half of a split screen, each half through its own camera onto an image as
+// wide as its view
+func (game *Game) drawSplit(screen *ebiten.Image, timer *DebugTimer) {
+	left := 0.0
+	for i, view := range game.Coop.Views {
+		game.Coop.images[i] = ensureImage(game.Coop.images[i], int(view.Width()), ScreenHeight)
+		game.Coop.images[i].Clear()
+		game.drawWorld(game.Coop.images[i], view, timer)
+
+		var options ebiten.DrawImageOptions
+		options.GeoM.Translate(left, 0)
+		screen.DrawImage(game.Coop.images[i], &options)
+		left += view.Width()
+	}
+
+	divider := float32(game.Coop.Views[0].Width())
+	vector.FillRect(screen, divider-2, 0, 4, ScreenHeight, color.RGBA{R: 0x10, G: 0x10, B: 0x18, A: 0xff}, false)
+}
+
+// player 1's HUD on the left and player 2's on the right, each with their own lives
+func (game *Game) drawCoopHud(screen *ebiten.Image, area HudArea) {
+	for i, player := range game.players() {
+		left := area.Left
+		if i == 1 {
+			left = area.Right - CoopHudWidth
+		}
+
+		if player.IsAlive() || game.Arcade {
+			player.DrawHud(screen, game.ImageManager, game.Font, game.elementShapes(), left, area.Top)
+			game.drawLives(screen, player, left+150, area.Top+45)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCoopSplit(t *testing.T) {
+	width := 1000.0
+	fit := width - 2*CoopEdgePadding
+
+	if coopSplit(fit-10, 0, false, width) {
+		t.Errorf("players that fit split the screen")
+	}
+	if !coopSplit(fit+10, 0, false, width) {
+		t.Errorf("players too far apart did not split the screen")
+	}
+	if !coopSplit(0, ScreenHeight, false, width) {
+		t.Errorf("players too far apart vertically did not split the screen")
+	}
+
+	// coming back just inside the limit stays split until the players are well within it
+	if !coopSplit(fit-10, 0, true, width) {
+		t.Errorf("split screen joined at the limit")
+	}
+	if coopSplit(fit*CoopJoinFraction-10, 0, true, width) {
+		t.Errorf("split screen did not join once the players were close")
+	}
+}
+
+func TestCoopCameras(t *testing.T) {
+	coop := MakeLocalCoop(nil)
+	camera := &Camera{}
+	left := &Player{x: 100, y: 300}
+	right := &Player{x: 150, y: 300}
+
+	coop.UpdateCameras(camera, right, left)
+	if coop.Split {
+		t.Fatalf("players side by side split the screen")
+	}
+
+	right.x = 100 + camera.Width()
+	coop.UpdateCameras(camera, right, left)
+	if !coop.Split {
+		t.Fatalf("players a screen apart did not split the screen")
+	}
+
+	// the left half follows whoever is on the left no matter which player is which
+	if coop.Followed[0] != left || coop.Followed[1] != right {
+		t.Errorf("halves follow %v and %v", coop.Followed[0].x, coop.Followed[1].x)
+	}
+	for i, view := range coop.Views {
+		if view.Width() != camera.Width()/2 {
+			t.Errorf("view %v is %v wide", i, view.Width())
+		}
+		x := coop.Followed[i].x
+		if x < view.x || x > view.x+view.Width() {
+			t.Errorf("view %v at %v does not show its player at %v", i, view.x, x)
+		}
+	}
+}
+
+func TestCoopShopTurns(t *testing.T) {
+	first := &Player{MaxHealth: 100, BombCapacity: 4, Guns: DefaultLoadout().Guns()}
+	second := &Player{MaxHealth: 100, BombCapacity: 4, Guns: DefaultLoadout().Guns()}
+	shop := MakeShop(nil, nil, []*Player{first, second}, 1)
+
+	last := shop.List.Options[len(shop.List.Options)-1]
+	if shop.Player() != first || last.Label() != "Player 2's turn" {
+		t.Fatalf("the shop opened for the wrong player, leaving says %q", last.Label())
+	}
+
+	if !shop.NextTurn() || shop.Player() != second {
+		t.Fatalf("player 2 did not get a turn")
+	}
+	if label := shop.List.Options[len(shop.List.Options)-1].Label(); label != "Next level" {
+		t.Errorf("leaving player 2's turn says %q", label)
+	}
+	if shop.NextTurn() {
+		t.Errorf("a third turn was given")
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// number of frames of timings kept for the graphs
+const DebugHistory = 120
+
+const (
+	DebugUpdate = "update"
+	DebugDraw   = "draw"
+)
+
+// time spent in each section of one phase (update or draw) over the last DebugHistory frames
+type debugTimings struct {
+	// section names in the order they were first seen
+	Names   []string
+	History [][]time.Duration
+	// index of the next frame to write in History
+	next    int
+	current []time.Duration
+}
+
+func (timings *debugTimings) add(name string, duration time.Duration) {
+	index := slices.Index(timings.Names, name)
+	if index == -1 {
+		timings.Names = append(timings.Names, name)
+		index = len(timings.Names) - 1
+	}
+
+	for len(timings.current) <= index {
+		timings.current = append(timings.current, 0)
+	}
+	timings.current[index] += duration
+}
+
+func (timings *debugTimings) finishFrame() {
+	if len(timings.History) < DebugHistory {
+		timings.History = append(timings.History, timings.current)
+	} else {
+		timings.History[timings.next] = timings.current
+	}
+	timings.next = (timings.next + 1) % DebugHistory
+	timings.current = nil
+}
+
+// average time of a section over the recorded frames
+func (timings *debugTimings) Average(index int) time.Duration {
+	if len(timings.History) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, frame := range timings.History {
+		if index < len(frame) {
+			total += frame[index]
+		}
+	}
+
+	return total / time.Duration(len(timings.History))
+}
+
+// frames from oldest to newest
+func (timings *debugTimings) Frames() [][]time.Duration {
+	if len(timings.History) < DebugHistory {
+		return timings.History
+	}
+	return append(slices.Clone(timings.History[timings.next:]), timings.History[:timings.next]...)
+}
+
+// toggled with F3. shows hitboxes, how many of each kind of object exist and where the time goes
+type DebugOverlay struct {
+	Enabled bool
+	Timings map[string]*debugTimings
+}
+
+func MakeDebugOverlay() *DebugOverlay {
+	return &DebugOverlay{
+		Timings: make(map[string]*debugTimings),
+	}
+}
+
+func (overlay *DebugOverlay) Toggle() {
+	if overlay != nil {
+		overlay.Enabled = !overlay.Enabled
+	}
+}
+
+func (overlay *DebugOverlay) IsEnabled() bool {
+	return overlay != nil && overlay.Enabled
+}
+
+// measures consecutive sections of a phase. a nil timer does nothing, so callers don't need to check
+// whether the overlay is enabled
+type DebugTimer struct {
+	timings *debugTimings
+	last    time.Time
+}
+
+func (overlay *DebugOverlay) Timer(phase string) *DebugTimer {
+	if !overlay.IsEnabled() {
+		return nil
+	}
+
+	timings, ok := overlay.Timings[phase]
+	if !ok {
+		timings = &debugTimings{}
+		overlay.Timings[phase] = timings
+	}
+
+	return &DebugTimer{timings: timings, last: time.Now()}
+}
+
+// record the time since the previous lap under name
+func (timer *DebugTimer) Lap(name string) {
+	if timer == nil {
+		return
+	}
+
+	now := time.Now()
+	timer.timings.add(name, now.Sub(timer.last))
+	timer.last = now
+}
+
+func (timer *DebugTimer) Finish() {
+	if timer == nil {
+		return
+	}
+
+	timer.timings.finishFrame()
+}
+
+var debugSectionColors = []color.RGBA{
+	{R: 0xff, G: 0x52, B: 0x52, A: 0xff},
+	{R: 0x52, G: 0xff, B: 0x8c, A: 0xff},
+	{R: 0x52, G: 0xc8, B: 0xff, A: 0xff},
+	{R: 0xff, G: 0xd8, B: 0x3a, A: 0xff},
+	{R: 0xd0, G: 0x6c, B: 0xff, A: 0xff},
+	{R: 0xff, G: 0x9a, B: 0x3c, A: 0xff},
+	{R: 0x3c, G: 0xff, B: 0xf0, A: 0xff},
+	{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+}
+
+func strokeDebugRect(screen *ebiten.Image, camera *Camera, bounds image.Rectangle, clr color.Color) {
+	x, y := camera.Apply(float64(bounds.Min.X), float64(bounds.Min.Y))
+	vector.StrokeRect(screen, float32(x), float32(y), float32(bounds.Dx()), float32(bounds.Dy()), 1, clr, false)
+}
+
+// draw the opaque pixels of pic in a flat color, which is what pixel perfect collisions test against
+func drawDebugMask(screen *ebiten.Image, camera *Camera, pic *ebiten.Image, bounds image.Rectangle, clr color.RGBA) {
+	x, y := camera.Apply(float64(bounds.Min.X), float64(bounds.Min.Y))
+
+	var mask colorm.ColorM
+	mask.Scale(0, 0, 0, 0.4)
+	mask.Translate(float64(clr.R)/255, float64(clr.G)/255, float64(clr.B)/255, 0)
+
+	options := &colorm.DrawImageOptions{}
+	options.GeoM.Translate(x, y)
+	colorm.DrawImage(screen, pic, mask, options)
+}
+
+func (game *Game) drawDebugHitboxes(screen *ebiten.Image) {
+	playerColor := color.RGBA{R: 0x52, G: 0xff, B: 0x8c, A: 0xff}
+	enemyColor := color.RGBA{R: 0xff, G: 0x52, B: 0x52, A: 0xff}
+	asteroidColor := color.RGBA{R: 0xff, G: 0x9a, B: 0x3c, A: 0xff}
+	powerupColor := color.RGBA{R: 0xd0, G: 0x6c, B: 0xff, A: 0xff}
+
+	for _, player := range game.players() {
+		if !player.IsAlive() {
+			continue
+		}
+		bounds := player.Bounds()
+		drawDebugMask(screen, game.Camera, player.pic, bounds, playerColor)
+		strokeDebugRect(screen, game.Camera, bounds, playerColor)
+	}
+
+	for _, enemy := range game.Enemies {
+		bounds := enemy.Bounds()
+		if normal, ok := enemy.(*NormalEnemy); ok {
+			drawDebugMask(screen, game.Camera, normal.pic, bounds, enemyColor)
+		}
+		strokeDebugRect(screen, game.Camera, bounds, enemyColor)
+	}
+
+	for _, asteroid := range game.Asteroids {
+		pic, raw, err := game.ImageManager.LoadImage(asteroid.pic)
+		if err != nil {
+			continue
+		}
+		// collisions ignore the rotation, so the mask is drawn unrotated
+		bounds := raw.Bounds().Add(image.Point{
+			X: int(asteroid.x - float64(raw.Bounds().Dx())/2),
+			Y: int(asteroid.y - float64(raw.Bounds().Dy())/2),
+		})
+		drawDebugMask(screen, game.Camera, pic, bounds, asteroidColor)
+		strokeDebugRect(screen, game.Camera, bounds, asteroidColor)
+	}
+
+	for _, powerup := range game.Powerups {
+		strokeDebugRect(screen, game.Camera, powerup.Bounds(game.ImageManager), powerupColor)
+	}
+
+	// bullets collide as points
+	for _, bullets := range [][]*Bullet{game.Bullets, game.EnemyBullets} {
+		for _, bullet := range bullets {
+			x, y := game.Camera.Apply(bullet.x, bullet.y)
+			vector.StrokeLine(screen, float32(x-3), float32(y), float32(x+3), float32(y), 1, color.White, false)
+			vector.StrokeLine(screen, float32(x), float32(y-3), float32(x), float32(y+3), 1, color.White, false)
+		}
+	}
+}
+
+// a stacked bar per frame, one color per section
+func drawDebugGraph(screen *ebiten.Image, face *text.GoTextFace, x float64, y float64, phase string, timings *debugTimings) float64 {
+	const graphHeight = 40.0
+	// a full bar is one frame at 60fps
+	const frameBudget = time.Second / 60
+
+	drawText(screen, *face, x, y, phase, color.White)
+	y += 16
+
+	vector.FillRect(screen, float32(x), float32(y), DebugHistory*2, graphHeight, color.RGBA{A: 0xa0}, false)
+	for column, frame := range timings.Frames() {
+		bottom := y + graphHeight
+		for index, duration := range frame {
+			height := graphHeight * float64(duration) / float64(frameBudget)
+			clr := debugSectionColors[index%len(debugSectionColors)]
+			vector.FillRect(screen, float32(x+float64(column)*2), float32(bottom-height), 2, float32(height), clr, false)
+			bottom -= height
+		}
+	}
+	y += graphHeight + 4
+
+	for index, name := range timings.Names {
+		clr := debugSectionColors[index%len(debugSectionColors)]
+		drawText(screen, *face, x, y, fmt.Sprintf("%v %.2fms", name, float64(timings.Average(index).Microseconds())/1000), clr)
+		y += 14
+	}
+
+	return y + 6
+}
+
+func (game *Game) drawDebugOverlay(screen *ebiten.Image) {
+	if !game.Debug.IsEnabled() {
+		return
+	}
+
+	game.drawDebugHitboxes(screen)
+
+	area := game.Display.HudArea(screen.Bounds())
+	face := &text.GoTextFace{Source: game.Font, Size: 12}
+	x := area.Right - 260
+	y := area.Top + 60
+
+	vector.FillRect(screen, float32(x-6), float32(y-6), 262, float32(area.Bottom-y), color.RGBA{A: 0x80}, false)
+
+	line := func(format string, args ...any) {
+		drawText(screen, *face, x, y, fmt.Sprintf(format, args...), color.White)
+		y += 14
+	}
+
+	line("FPS %.1f  TPS %.1f  tick %v", ebiten.ActualFPS(), ebiten.ActualTPS(), game.Counter)
+	line("bullets %v  enemy bullets %v", len(game.Bullets), len(game.EnemyBullets))
+	line("enemies %v  asteroids %v  powerups %v", len(game.Enemies), len(game.Asteroids), len(game.Powerups))
+	line("explosions %v  bombs %v  particles %v", len(game.Explosions), len(game.Bombs), len(game.Particles.Particles))
+	line("camera %.0f, %.0f  world height %.0f", game.Camera.x, game.Camera.y, game.Camera.WorldHeight())
+
+	if game.Multiplayer != nil {
+		latency := "unknown"
+		if game.Multiplayer.Peer != nil && game.Multiplayer.Peer.HasLatency() {
+			latency = fmt.Sprintf("%vms", game.Multiplayer.Peer.LatencyMS())
+		}
+		line("role %v  latency %v", game.Multiplayer.Role, latency)
+		line("snapshot %v bytes", game.Multiplayer.SnapshotBytes)
+	} else {
+		line("single player")
+	}
+	y += 6
+
+	names := make([]string, 0, len(game.Counters))
+	for name := range game.Counters {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, cmp.Compare)
+	for _, name := range names {
+		counter := game.Counters[name]
+		line("%v %v/%v", name, counter.Counter, counter.Limit)
+	}
+	y += 6
+
+	for _, phase := range []string{DebugUpdate, DebugDraw} {
+		if timings, ok := game.Debug.Timings[phase]; ok {
+			y = drawDebugGraph(screen, face, x, y, phase, timings)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDebugTimerDisabled(t *testing.T) {
+	var overlay *DebugOverlay
+	timer := overlay.Timer(DebugUpdate)
+	timer.Lap("player")
+	timer.Finish()
+
+	overlay = MakeDebugOverlay()
+	if overlay.Timer(DebugUpdate) != nil {
+		t.Fatal("a closed overlay should not time anything")
+	}
+}
+
+func TestDebugTimingsHistory(t *testing.T) {
+	timings := &debugTimings{}
+
+	for frame := range DebugHistory + 10 {
+		timings.add("enemies", time.Duration(frame))
+		timings.add("bullets", time.Millisecond)
+		timings.finishFrame()
+	}
+
+	if len(timings.History) != DebugHistory {
+		t.Fatalf("kept %v frames, want %v", len(timings.History), DebugHistory)
+	}
+
+	frames := timings.Frames()
+	if frames[0][0] != 10 || frames[DebugHistory-1][0] != DebugHistory+9 {
+		t.Errorf("frames should run from oldest to newest, got %v to %v", frames[0][0], frames[DebugHistory-1][0])
+	}
+
+	if average := timings.Average(1); average != time.Millisecond {
+		t.Errorf("average %v, want 1ms", average)
+	}
+}
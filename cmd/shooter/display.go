@@ -0,0 +1,173 @@
+package main
+
+import (
+	"image"
+	"math"
+	"runtime"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// narrowest view, anything narrower than 4:3 is letterboxed instead
+const MinViewWidth = ScreenHeight * 4 / 3
+
+type Resolution struct {
+	Name string
+	// 0 follows the shape of the window
+	Width int
+}
+
+var resolutions = []Resolution{
+	{Name: "Fit window", Width: 0},
+	{Name: "1200x800 (3:2)", Width: 1200},
+	{Name: "1066x800 (4:3)", Width: 1066},
+	{Name: "1280x800 (16:10)", Width: 1280},
+	{Name: "1422x800 (16:9)", Width: 1422},
+	{Name: "1866x800 (21:9)", Width: 1866},
+}
+
+type ScalingMode int
+
+const (
+	// scale smoothly to fill as much of the window as possible
+	ScaleFit ScalingMode = iota
+	// only scale by whole numbers, keeping every pixel the same size
+	ScaleInteger
+	// fill the window but without smoothing pixels
+	ScalePixelPerfect
+)
+
+func (mode ScalingMode) String() string {
+	switch mode {
+	case ScaleFit:
+		return "Smooth"
+	case ScaleInteger:
+		return "Integer"
+	case ScalePixelPerfect:
+		return "Pixel perfect"
+	}
+
+	return "Unknown"
+}
+
+type DisplaySettings struct {
+	// index into resolutions
+	Resolution int
+	Scaling    ScalingMode
+	Borderless bool
+	// fraction of the view kept clear of the HUD along every edge, see HudArea
+	SafeArea float64
+
+	// the width of the visible part of the world, set by Run.Layout. the view is always ScreenHeight tall
+	// since the height of the playfield is part of the game rules, so aspect ratios only change the width
+	Width int
+
+	// moves a pointer from where ebiten puts it to where it is on the view, see PointerPosition
+	pointer ebiten.GeoM
+}
+
+// the web build usually runs in an iframe of whatever size the page gives it, so it follows the window
+func DefaultDisplaySettings() DisplaySettings {
+	resolution := 1
+	if runtime.GOOS == "js" {
+		resolution = 0
+	}
+
+	return DisplaySettings{
+		Resolution: resolution,
+		Scaling:    ScaleFit,
+		Width:      ScreenWidth,
+	}
+}
+
+// the width of the view for a window of the given size
+func (settings *DisplaySettings) ViewWidth(outsideWidth int, outsideHeight int) int {
+	width := ScreenWidth
+	if settings.Resolution >= 0 && settings.Resolution < len(resolutions) {
+		width = resolutions[settings.Resolution].Width
+	}
+
+	if width == 0 {
+		if outsideWidth <= 0 || outsideHeight <= 0 {
+			return ScreenWidth
+		}
+		width = outsideWidth * ScreenHeight / outsideHeight
+	}
+
+	return max(MinViewWidth, min(LogicalWidth, width))
+}
+
+// the transform and filter used to draw the finished view onto a window of the given size. fit is the
+// transform ebiten would use, which keeps the aspect ratio and centers the view
+func (settings *DisplaySettings) ScaleGeoM(window image.Rectangle, view image.Rectangle, fit ebiten.GeoM) (ebiten.GeoM, ebiten.Filter) {
+	switch settings.Scaling {
+	case ScaleInteger:
+		scale := math.Floor(math.Min(float64(window.Dx())/float64(view.Dx()), float64(window.Dy())/float64(view.Dy())))
+		// the window is smaller than the view, so scaling down smoothly is all that can be done
+		if scale < 1 {
+			return fit, ebiten.FilterLinear
+		}
+
+		var geoM ebiten.GeoM
+		geoM.Scale(scale, scale)
+		geoM.Translate(math.Floor((float64(window.Dx())-float64(view.Dx())*scale)/2), math.Floor((float64(window.Dy())-float64(view.Dy())*scale)/2))
+		return geoM, ebiten.FilterNearest
+	case ScalePixelPerfect:
+		return fit, ebiten.FilterNearest
+	}
+
+	return fit, ebiten.FilterLinear
+}
+
+// remember how the view was put on the window this frame. ebiten moves the cursor and touches into the
+// view as if it was drawn with fit, so pointers are moved back out through fit and into the view through
+// whatever ScaleGeoM drew it with
+func (settings *DisplaySettings) Placed(fit ebiten.GeoM, scaled ebiten.GeoM) {
+	scaled.Invert()
+	fit.Concat(scaled)
+	settings.pointer = fit
+}
+
+// where a pointer that ebiten reports at x, y is on the view, for the cursor and touches
+func (settings *DisplaySettings) PointerPosition(x int, y int) (float64, float64) {
+	return settings.pointer.Apply(float64(x), float64(y))
+}
+
+// a borderless window covers the whole monitor without switching the display mode
+func (settings *DisplaySettings) SetBorderless(borderless bool) {
+	settings.Borderless = borderless
+
+	ebiten.SetWindowDecorated(!borderless)
+	if borderless {
+		width, height := ebiten.Monitor().Size()
+		ebiten.SetWindowPosition(0, 0)
+		ebiten.SetWindowSize(width, height)
+	} else {
+		ebiten.SetWindowSize(ScreenWidth, ScreenHeight)
+	}
+}
+
+// the part of the view the HUD is drawn in
+type HudArea struct {
+	Left, Top, Right, Bottom float64
+}
+
+// the whole view, as it was laid out last
+func (settings *DisplaySettings) View() image.Rectangle {
+	return image.Rect(0, 0, settings.Width, ScreenHeight)
+}
+
+// the HUD area of a view, which is usually the screen being drawn on
+func (settings *DisplaySettings) HudArea(view image.Rectangle) HudArea {
+	margin := math.Round(settings.SafeArea * ScreenHeight)
+	return HudArea{
+		Left:   float64(view.Min.X) + margin,
+		Top:    float64(view.Min.Y) + margin,
+		Right:  float64(view.Max.X) - margin,
+		Bottom: float64(view.Max.Y) - margin,
+	}
+}
+
+func (area HudArea) CenterX() float64 {
+	return (area.Left + area.Right) / 2
+}
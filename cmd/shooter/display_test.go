@@ -0,0 +1,85 @@
+package main
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestViewWidthFollowsWindow(t *testing.T) {
+	settings := DisplaySettings{Resolution: 0}
+
+	if width := settings.ViewWidth(1920, 1080); width != 1422 {
+		t.Errorf("16:9 window gave a view %v wide, want 1422", width)
+	}
+
+	if width := settings.ViewWidth(400, 800); width != MinViewWidth {
+		t.Errorf("narrow window gave a view %v wide, want %v", width, MinViewWidth)
+	}
+
+	if width := settings.ViewWidth(5000, 800); width != LogicalWidth {
+		t.Errorf("very wide window gave a view %v wide, want %v", width, LogicalWidth)
+	}
+
+	settings.Resolution = 1
+	if width := settings.ViewWidth(1920, 1080); width != 1200 {
+		t.Errorf("fixed resolution gave a view %v wide, want 1200", width)
+	}
+}
+
+func TestIntegerScaling(t *testing.T) {
+	settings := DisplaySettings{Scaling: ScaleInteger}
+	var fit ebiten.GeoM
+	fit.Scale(1.35, 1.35)
+
+	geoM, filter := settings.ScaleGeoM(image.Rect(0, 0, 2560, 1440), image.Rect(0, 0, 1200, 800), fit)
+	if filter != ebiten.FilterNearest {
+		t.Errorf("integer scaling should not smooth pixels")
+	}
+
+	// 1440 is not tall enough for 2x, so the view is drawn at 1x in the middle
+	x, y := geoM.Apply(0, 0)
+	right, bottom := geoM.Apply(1200, 800)
+	if right-x != 1200 || bottom-y != 800 || x != 680 || y != 320 {
+		t.Errorf("view drawn from %v,%v to %v,%v, want 1x centered", x, y, right, bottom)
+	}
+
+	// smaller than the view, so fall back to the smooth fit
+	geoM, filter = settings.ScaleGeoM(image.Rect(0, 0, 600, 400), image.Rect(0, 0, 1200, 800), fit)
+	if geoM != fit || filter != ebiten.FilterLinear {
+		t.Errorf("small windows should use the fit transform")
+	}
+}
+
+func TestPointerFollowsIntegerScaling(t *testing.T) {
+	settings := DisplaySettings{Scaling: ScaleInteger}
+	window := image.Rect(0, 0, 2560, 1440)
+	view := image.Rect(0, 0, 1200, 800)
+	// what ebiten would do: scale by 1.8 to fill the height and center the width
+	var fit ebiten.GeoM
+	fit.Scale(1.8, 1.8)
+	fit.Translate(200, 0)
+
+	geoM, _ := settings.ScaleGeoM(window, view, fit)
+	settings.Placed(fit, geoM)
+
+	// ebiten reports the cursor over the middle of the fit view, which is also the middle of the 1x view
+	if x, y := settings.PointerPosition(600, 400); math.Abs(x-600) > 0.001 || math.Abs(y-400) > 0.001 {
+		t.Errorf("middle of the window is at %v,%v in the view, want 600,400", x, y)
+	}
+
+	// the top left of the fit view is at 200,0 on the window, which is left of and above the 1x view
+	if x, y := settings.PointerPosition(0, 0); math.Abs(x+480) > 0.001 || math.Abs(y+320) > 0.001 {
+		t.Errorf("top left of the fit view is at %v,%v in the view, want -480,-320", x, y)
+	}
+
+	// with the fit transform the pointer is left alone
+	settings.Scaling = ScaleFit
+	geoM, _ = settings.ScaleGeoM(window, view, fit)
+	settings.Placed(fit, geoM)
+	if x, y := settings.PointerPosition(300, 200); math.Abs(x-300) > 0.001 || math.Abs(y-200) > 0.001 {
+		t.Errorf("fit scaling moved the pointer to %v,%v", x, y)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"log"
+	"math/rand/v2"
+)
+
+// if nothing has dropped for this many ticks then the next kill is guaranteed to drop something
+const DropPityTime = 60 * 40
+
+type DropEntry struct {
+	// a powerup kind, as understood by MakePowerupKind
+	Kind   string
+	Weight int
+}
+
+type DropTable struct {
+	// probability from 0.0 to 1.0 that a kill drops a powerup
+	Chance float64
+	// number of drops that always happen, used for bosses
+	Guaranteed int
+	Entries    []DropEntry
+}
+
+func (table *DropTable) totalWeight() int {
+	total := 0
+	for _, entry := range table.Entries {
+		if entry.Weight > 0 {
+			total += entry.Weight
+		}
+	}
+	return total
+}
+
+// choose one entry according to its weight, or "" if the table is empty
+func (table *DropTable) Pick(rng *rand.Rand) string {
+	total := table.totalWeight()
+	if total == 0 {
+		return ""
+	}
+
+	choice := rng.IntN(total)
+	for _, entry := range table.Entries {
+		if entry.Weight <= 0 {
+			continue
+		}
+		if choice < entry.Weight {
+			return entry.Kind
+		}
+		choice -= entry.Weight
+	}
+
+	return ""
+}
+
+// returns the powerup kinds dropped by a single kill. if forced is true then at least one powerup drops
+func (table *DropTable) Roll(rng *rand.Rand, forced bool) []string {
+	var out []string
+
+	for range table.Guaranteed {
+		if kind := table.Pick(rng); kind != "" {
+			out = append(out, kind)
+		}
+	}
+
+	if len(out) == 0 && (forced || rng.Float64() < table.Chance) {
+		if kind := table.Pick(rng); kind != "" {
+			out = append(out, kind)
+		}
+	}
+
+	return out
+}
+
+var defaultDropTable = &DropTable{
+	Chance: 0.06,
+	Entries: []DropEntry{
+		{Kind: "energy", Weight: 3},
+		{Kind: "health", Weight: 3},
+		{Kind: "weapon", Weight: 2},
+		{Kind: "bomb", Weight: 2},
+		{Kind: "energy-increase", Weight: 1},
+	},
+}
+
+// keyed by NormalEnemy.Kind
+var enemyDropTables = map[string]*DropTable{
+	// the aiming enemy is weak to everything, so it mostly gives back health
+	"enemy-0": {
+		Chance: 0.05,
+		Entries: []DropEntry{
+			{Kind: "health", Weight: 5},
+			{Kind: "energy", Weight: 2},
+			{Kind: "bomb", Weight: 1},
+		},
+	},
+	"enemy-1": defaultDropTable,
+	"enemy-2": {
+		Chance: 0.07,
+		Entries: []DropEntry{
+			{Kind: "energy", Weight: 4},
+			{Kind: "energy-increase", Weight: 2},
+			{Kind: "health", Weight: 2},
+		},
+	},
+	"enemy-3": {
+		Chance: 0.08,
+		Entries: []DropEntry{
+			{Kind: "weapon", Weight: 3},
+			{Kind: "energy", Weight: 2},
+			{Kind: "health", Weight: 2},
+		},
+	},
+	"enemy-4": defaultDropTable,
+	"enemy-5": {
+		Chance: 0.07,
+		Entries: []DropEntry{
+			{Kind: "bomb", Weight: 4},
+			{Kind: "health", Weight: 2},
+			{Kind: "energy", Weight: 1},
+		},
+	},
+	"enemy-6": defaultDropTable,
+	"enemy-7": {
+		Chance: 0.07,
+		Entries: []DropEntry{
+			{Kind: "energy-increase", Weight: 2},
+			{Kind: "weapon", Weight: 2},
+			{Kind: "energy", Weight: 3},
+		},
+	},
+	// resists two elements so it is harder to kill, and pays out more often
+	"enemy-8": {
+		Chance: 0.12,
+		Entries: []DropEntry{
+			{Kind: "weapon", Weight: 3},
+			{Kind: "energy-increase", Weight: 2},
+			{Kind: "bomb", Weight: 2},
+			{Kind: "health", Weight: 2},
+		},
+	},
+	"boss1": {
+		Chance:     1,
+		Guaranteed: 3,
+		Entries: []DropEntry{
+			{Kind: "weapon", Weight: 3},
+			{Kind: "energy-increase", Weight: 3},
+			{Kind: "bomb", Weight: 2},
+			{Kind: "health", Weight: 2},
+		},
+	},
+}
+
+func dropTableFor(enemy Enemy) *DropTable {
+	if normal, ok := enemy.(*NormalEnemy); ok {
+		if table, ok := enemyDropTables[normal.Kind]; ok {
+			return table
+		}
+	}
+
+	return defaultDropTable
+}
+
+// roll the enemy's drop table and create powerups where the enemy died
+func (game *Game) dropEnemyLoot(enemy Enemy) {
+	if game.isSlave() {
+		return
+	}
+
+	forced := game.Counter-game.LastDrop > DropPityTime
+	kinds := dropTableFor(enemy).Roll(game.DropRand, forced)
+	if len(kinds) == 0 {
+		return
+	}
+
+	x, y := enemy.Coords()
+	for i, kind := range kinds {
+		// spread out multiple drops so they don't overlap
+		offset := float64(i) - float64(len(kinds)-1)/2
+		powerup, err := MakePowerupKind(kind, x+offset*40, y)
+		if err != nil {
+			log.Printf("Unable to create drop: %v", err)
+			continue
+		}
+		game.AddPowerup(powerup)
+	}
+
+	game.LastDrop = game.Counter
+}
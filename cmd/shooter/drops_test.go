@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestDropTablesMakeKnownPowerups(t *testing.T) {
+	tables := map[string]*DropTable{"default": defaultDropTable}
+	for kind, table := range enemyDropTables {
+		tables[kind] = table
+	}
+
+	for name, table := range tables {
+		for _, entry := range table.Entries {
+			if _, err := MakePowerupKind(entry.Kind, 0, 0); err != nil {
+				t.Fatalf("table %s has bad entry %q: %v", name, entry.Kind, err)
+			}
+		}
+	}
+}
+
+func TestDropTableBossGuaranteed(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	boss := enemyDropTables["boss1"]
+
+	for range 100 {
+		drops := boss.Roll(rng, false)
+		if len(drops) != boss.Guaranteed {
+			t.Fatalf("boss dropped %d powerups, want %d", len(drops), boss.Guaranteed)
+		}
+	}
+}
+
+func TestDropTableForcedDrop(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	table := &DropTable{
+		Chance:  0,
+		Entries: []DropEntry{{Kind: "health", Weight: 1}},
+	}
+
+	if drops := table.Roll(rng, false); len(drops) != 0 {
+		t.Fatalf("zero chance table dropped %v", drops)
+	}
+
+	drops := table.Roll(rng, true)
+	if len(drops) != 1 || drops[0] != "health" {
+		t.Fatalf("forced drop returned %v", drops)
+	}
+}
+
+func TestDropTableSkipsZeroWeight(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	table := &DropTable{
+		Chance: 1,
+		Entries: []DropEntry{
+			{Kind: "weapon", Weight: 0},
+			{Kind: "bomb", Weight: 4},
+		},
+	}
+
+	for range 100 {
+		if kind := table.Pick(rng); kind != "bomb" {
+			t.Fatalf("picked %q from a table where only bomb has weight", kind)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	gameImages "github.com/kazzmir/webgl-shooter/images"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 func ptr[T any](obj T) *T { return &obj }
@@ -96,14 +97,22 @@ func (circular *CircularMovement) Coords(x float64, y float64) (float64, float64
 	return x + circular.radius*math.Cos(radians), y + circular.radius*math.Sin(radians)
 }
 
+// the movement paths enemies are spawned with, by the names used in movementState
+var movementKinds = []string{"linear", "circular", "sine"}
+
 func makeMovement() Movement {
-	switch rand.N(3) {
-	case 0:
+	return makeMovementKind(movementKinds[rand.N(len(movementKinds))])
+}
+
+// a randomized movement path of the given kind, or nil if there is no such kind
+func makeMovementKind(kind string) Movement {
+	switch kind {
+	case "linear":
 		return &LinearMovement{
 			velocityX: randomFloat(-1, 1),
 			velocityY: 2,
 		}
-	case 1:
+	case "circular":
 		return &CircularMovement{
 			radius:    75,
 			angle:     0,
@@ -111,7 +120,7 @@ func makeMovement() Movement {
 			velocityX: 0,
 			velocityY: 2,
 		}
-	case 2:
+	case "sine":
 		return &SineMovement{
 			amplitude: randomFloat(50, 100),
 			velocityX: 0,
@@ -190,6 +199,8 @@ type Enemy interface {
 	Move(player *Player, imageManager *ImageManager) []*Bullet
 	// maybe dont need this method since we can just call Damage()
 	Hit(bullet *Bullet)
+	// how the element of a bullet works against the enemy, for the hit feedback
+	DescribeHit(bullet *Bullet) HitResult
 	Damage(amount float64)
 	Coords() (float64, float64)
 	IsAlive() bool
@@ -219,6 +230,15 @@ type NormalEnemy struct {
 	dead       chan struct{}
 	Strengths  []ElementType
 	Weaknesses []ElementType
+
+	// life the enemy started with, used by behaviors that react to damage
+	MaxLife float64
+	// absorbs damage before Life does
+	Shield float64
+	// minions requested by the behavior, created by the game after Move
+	spawns []Coordinate
+	// set by a behavior when the enemy has left the level
+	gone bool
 }
 
 func (enemy *NormalEnemy) Experience() float64 {
@@ -228,6 +248,14 @@ func (enemy *NormalEnemy) Experience() float64 {
 
 func (enemy *NormalEnemy) Damage(amount float64) {
 	enemy.hurt = 10
+	if enemy.Shield > 0 {
+		enemy.Shield -= amount
+		if enemy.Shield >= 0 {
+			return
+		}
+		amount = -enemy.Shield
+		enemy.Shield = 0
+	}
 	enemy.Life -= amount
 	if enemy.Life <= 0 {
 		close(enemy.dead)
@@ -278,12 +306,17 @@ func (enemy *NormalEnemy) CollidePlayer(player *Player) (float64, float64, bool)
 	return 0, 0, false
 }
 
+// an enemy that leaves the world is dropped by Game.Update
 func (enemy *NormalEnemy) IsAlive() bool {
-	x, y := enemy.Coords()
-	return enemy.Life > 0 && (y < 0 || onLogicalScreen(x, y, 100))
+	return enemy.Life > 0 && !enemy.gone
 }
 
 func (enemy *NormalEnemy) Hit(bullet *Bullet) {
+	enemy.Damage(enemy.hitDamage(bullet))
+}
+
+// the damage the bullet does once the enemy's strengths and weaknesses are taken into account
+func (enemy *NormalEnemy) hitDamage(bullet *Bullet) float64 {
 	damage := bullet.Strength
 	if enemy.hasElementStrength(bullet.ElementType) {
 		damage *= 0.9
@@ -291,7 +324,16 @@ func (enemy *NormalEnemy) Hit(bullet *Bullet) {
 	if enemy.hasElementWeakness(bullet.ElementType) {
 		damage *= 1.1
 	}
-	enemy.Damage(damage)
+	return damage
+}
+
+func (enemy *NormalEnemy) DescribeHit(bullet *Bullet) HitResult {
+	return HitResult{
+		Damage:   enemy.hitDamage(bullet),
+		Element:  bullet.ElementType,
+		Weak:     enemy.hasElementWeakness(bullet.ElementType),
+		Resisted: enemy.hasElementStrength(bullet.ElementType),
+	}
 }
 
 func (enemy *NormalEnemy) hasElementStrength(element ElementType) bool {
@@ -315,6 +357,10 @@ func (enemy *NormalEnemy) hasElementWeakness(element ElementType) bool {
 }
 
 func (enemy *NormalEnemy) Move(player *Player, imageManager *ImageManager) []*Bullet {
+	if behavior, ok := enemy.move.(Behavior); ok {
+		behavior.Think(enemy, player)
+	}
+
 	enemy.x, enemy.y = enemy.move.Move(enemy.x, enemy.y)
 
 	if enemy.hurt > 0 {
@@ -329,6 +375,13 @@ func (enemy *NormalEnemy) Move(player *Player, imageManager *ImageManager) []*Bu
 	return bullets
 }
 
+// returns the minions the behavior wants to launch since the last call
+func (enemy *NormalEnemy) TakeSpawns() []Coordinate {
+	spawns := enemy.spawns
+	enemy.spawns = nil
+	return spawns
+}
+
 func (enemy *NormalEnemy) Collision(x float64, y float64) bool {
 	bounds := enemy.pic.Bounds()
 
@@ -402,6 +455,12 @@ func (enemy *NormalEnemy) Draw(screen *ebiten.Image, shaders *ShaderManager, cam
 		screen.DrawImage(enemy.pic, options)
 	}
 
+	if enemy.Shield > 0 {
+		radius := float32(math.Max(float64(enemy.pic.Bounds().Dx()), float64(enemy.pic.Bounds().Dy()))/2 + 6)
+		alpha := uint8(math.Min(255, 90+enemy.Shield*8))
+		vector.StrokeCircle(screen, float32(useX), float32(useY), radius, 3, premultiplyAlpha(color.RGBA{R: 0x60, G: 0xc0, B: 0xff, A: alpha}), true)
+	}
+
 	/*
 	   vector.StrokeRect(
 	       screen,
@@ -422,6 +481,7 @@ func MakeEnemy1(x float64, y float64, rawImage image.Image, image *ebiten.Image,
 		y:          y,
 		move:       move,
 		Life:       5 * difficulty,
+		MaxLife:    5 * difficulty,
 		rawImage:   rawImage,
 		pic:        image,
 		gun:        &EnemyGun2{},
@@ -440,6 +500,7 @@ func MakeEnemy2(x float64, y float64, rawImage image.Image, pic *ebiten.Image, m
 		y:          y,
 		move:       move,
 		Life:       5 * difficulty,
+		MaxLife:    5 * difficulty,
 		rawImage:   rawImage,
 		pic:        pic,
 		gun:        &EnemyGun1{},
@@ -565,11 +626,16 @@ func MakeBossGun1() EnemyGun {
 	}
 }
 
+// how far above the view the boss starts
+const Boss1Entry = 150
+
 type Boss1Movement struct {
 	// location we want to move to
 	moveX, moveY float64
 	// count how long we are at one position
 	counter uint64
+	// the boss stays in the screen tall area below this, which is the top of the view when it arrived
+	top float64
 }
 
 func distance(x1, y1, x2, y2 float64) float64 {
@@ -581,6 +647,7 @@ func (boss *Boss1Movement) Copy() Movement {
 		moveX:   boss.moveX,
 		moveY:   boss.moveY,
 		counter: boss.counter,
+		top:     boss.top,
 	}
 }
 
@@ -595,7 +662,7 @@ func (boss *Boss1Movement) Move(x float64, y float64) (float64, float64) {
 	if distance(x, y, boss.moveX, boss.moveY) < speed*2 {
 		if boss.counter == 0 {
 			boss.moveX = randomFloat(100, LogicalWidth-100)
-			boss.moveY = randomFloat(100, ScreenHeight-100)
+			boss.moveY = boss.top + randomFloat(100, ScreenHeight-100)
 			boss.counter = uint64(rand.N(200) + 200)
 		} else {
 			boss.counter -= 1
@@ -619,8 +686,9 @@ func MakeBoss1(x float64, y float64, rawImage image.Image, pic *ebiten.Image, di
 		y:    y,
 		move: &Boss1Movement{
 			moveX:   LogicalWidth / 2,
-			moveY:   100,
+			moveY:   y + Boss1Entry + 100,
 			counter: 100,
+			top:     y + Boss1Entry,
 		},
 		Life:     500 * difficulty,
 		rawImage: rawImage,
@@ -9,6 +9,8 @@ type Explosion interface {
     Move()
     IsAlive() bool
     Draw(screen *ebiten.Image, shaderManager *ShaderManager, camera *Camera)
+    // the light the explosion gives off right now
+    Light() PointLight
 }
 
 type SimpleExplosion struct {
@@ -69,6 +71,16 @@ func (explosion *SimpleExplosion) Draw(screen *ebiten.Image, shaderManager *Shad
     screen.DrawRectShader(bounds.Dx(), bounds.Dy(), shaderManager.ExplosionShader, options)
 }
 
+func (explosion *SimpleExplosion) Light() PointLight {
+    return PointLight{
+        X: explosion.x,
+        Y: explosion.y,
+        Radius: 120,
+        Color: [3]float64{1, 0.7, 0.4},
+        Intensity: float64(explosion.life) / 10,
+    }
+}
+
 type AnimatedExplosion struct {
     x, y float64
     velocityX, velocityY float64
@@ -90,6 +102,17 @@ func (explosion *AnimatedExplosion) Draw(screen *ebiten.Image, shaderManager *Sh
     explosion.animation.Draw(screen, x, y)
 }
 
+// bright at first and dimmer as the animation plays out
+func (explosion *AnimatedExplosion) Light() PointLight {
+    frames := len(explosion.animation.Frames)
+    return PointLight{
+        X: explosion.x,
+        Y: explosion.y,
+        Radius: 200,
+        Color: [3]float64{1, 0.65, 0.3},
+        Intensity: 1.5 * float64(frames - explosion.animation.CurrentFrame) / float64(max(1, frames)),
+    }
+}
 
 func MakeAnimatedExplosion(x float64, y float64, animation *Animation) Explosion {
     return &AnimatedExplosion{
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"log"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// how many people can play on one machine, each with their own controller
+const MaxLocalPlayers = 2
+
+// stick movement inside this radius is ignored, so a stick resting slightly off center does not drift
+const GamepadDeadzone = 0.2
+
+// how far a trigger has to be pulled to count as pressed
+const GamepadTriggerThreshold = 0.5
+
+// how far the stick has to be pushed to move through a menu, and how far back it has to come before it
+// moves again
+const GamepadMenuPush = 0.6
+const GamepadMenuRelease = 0.3
+
+// menus are driven by keys, so these buttons act as the keys the menus already know about
+var gamepadMenuButtons = map[ebiten.StandardGamepadButton]ebiten.Key{
+	ebiten.StandardGamepadButtonLeftTop:     ebiten.KeyArrowUp,
+	ebiten.StandardGamepadButtonLeftBottom:  ebiten.KeyArrowDown,
+	ebiten.StandardGamepadButtonLeftLeft:    ebiten.KeyArrowLeft,
+	ebiten.StandardGamepadButtonLeftRight:   ebiten.KeyArrowRight,
+	ebiten.StandardGamepadButtonRightBottom: ebiten.KeyEnter,
+	ebiten.StandardGamepadButtonRightRight:  ebiten.KeyEscape,
+	ebiten.StandardGamepadButtonCenterRight: ebiten.KeyEscape,
+}
+
+// scale a stick position so the deadzone reads as 0 and the edge of the stick as 1
+func applyDeadzone(x float64, y float64, deadzone float64) (float64, float64) {
+	magnitude := math.Hypot(x, y)
+	if magnitude <= deadzone {
+		return 0, 0
+	}
+
+	scaled := math.Min(1, (magnitude-deadzone)/(1-deadzone))
+	return x / magnitude * scaled, y / magnitude * scaled
+}
+
+// the arrow key a stick position points at, or -1 if it is not pushed far enough. previous is the
+// direction from the last update, which holds until the stick comes back towards the center
+func stickMenuKey(x float64, y float64, previous ebiten.Key) ebiten.Key {
+	if math.Hypot(x, y) < GamepadMenuRelease {
+		return -1
+	}
+	if math.Max(math.Abs(x), math.Abs(y)) < GamepadMenuPush {
+		return previous
+	}
+
+	if math.Abs(x) > math.Abs(y) {
+		if x < 0 {
+			return ebiten.KeyArrowLeft
+		}
+		return ebiten.KeyArrowRight
+	}
+	if y < 0 {
+		return ebiten.KeyArrowUp
+	}
+	return ebiten.KeyArrowDown
+}
+
+// switch to only the gun after (or before) the first gun that is enabled
+func cycleGun(guns []Gun, direction int) {
+	if len(guns) == 0 || direction == 0 {
+		return
+	}
+
+	current := slices.IndexFunc(guns, func(gun Gun) bool {
+		return gun.IsEnabled()
+	})
+	if current == -1 {
+		current = 0
+		if direction > 0 {
+			current = len(guns) - 1
+		}
+	}
+
+	next := ((current+direction)%len(guns) + len(guns)) % len(guns)
+	for i, gun := range guns {
+		gun.SetEnabled(i == next)
+	}
+}
+
+// tracks which controllers are plugged in and which player each one belongs to
+type GamepadManager struct {
+	// the buttons of each local player, shared with the run and changed from the controls menu
+	Controls [MaxLocalPlayers]*Controls
+	// the controller of each local player, -1 if the player has none
+	Assigned [MaxLocalPlayers]ebiten.GamepadID
+	// the direction each controller's stick pointed last update, for menu navigation
+	stickKeys map[ebiten.GamepadID]ebiten.Key
+	menuKeys  []ebiten.Key
+}
+
+func MakeGamepadManager(controls [MaxLocalPlayers]*Controls) *GamepadManager {
+	manager := &GamepadManager{
+		Controls:  controls,
+		stickKeys: make(map[ebiten.GamepadID]ebiten.Key),
+	}
+	for i := range manager.Assigned {
+		manager.Assigned[i] = -1
+	}
+	return manager
+}
+
+// the player the controller belongs to, or -1
+func (manager *GamepadManager) PlayerOf(id ebiten.GamepadID) int {
+	return slices.Index(manager.Assigned[:], id)
+}
+
+// give the controller to the first player without one. returns false if every player has one already
+func (manager *GamepadManager) assign(id ebiten.GamepadID) bool {
+	if manager.PlayerOf(id) != -1 {
+		return true
+	}
+
+	free := manager.PlayerOf(-1)
+	if free == -1 {
+		return false
+	}
+	manager.Assigned[free] = id
+	log.Printf("Controller %v (%v) is player %v", id, ebiten.GamepadName(id), free+1)
+	return true
+}
+
+func (manager *GamepadManager) unassign(id ebiten.GamepadID) {
+	if player := manager.PlayerOf(id); player != -1 {
+		manager.Assigned[player] = -1
+		log.Printf("Controller %v for player %v disconnected", id, player+1)
+	}
+	delete(manager.stickKeys, id)
+}
+
+// called once per frame to pick up controllers being plugged in and out and to work out the menu keys
+func (manager *GamepadManager) Update() {
+	for id := range manager.stickKeys {
+		if inpututil.IsGamepadJustDisconnected(id) {
+			manager.unassign(id)
+		}
+	}
+
+	manager.menuKeys = manager.menuKeys[:0]
+
+	// also catches controllers that were plugged in before the game started
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+
+		if _, known := manager.stickKeys[id]; !known {
+			manager.stickKeys[id] = -1
+		}
+		// a controller plugged in while every player had one gets the first player that frees up
+		manager.assign(id)
+
+		for button, key := range gamepadMenuButtons {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+				manager.menuKeys = append(manager.menuKeys, key)
+			}
+		}
+
+		x := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+		y := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
+		key := stickMenuKey(x, y, manager.stickKeys[id])
+		if key != -1 && key != manager.stickKeys[id] {
+			manager.menuKeys = append(manager.menuKeys, key)
+		}
+		manager.stickKeys[id] = key
+	}
+}
+
+// keys that controllers pressed this frame, see gamepadMenuButtons
+func (manager *GamepadManager) MenuKeys() []ebiten.Key {
+	return manager.menuKeys
+}
+
+// what the controller of the given local player is doing
+func (manager *GamepadManager) PlayerInput(player int) playerInputState {
+	var input playerInputState
+	if player < 0 || player >= len(manager.Assigned) || manager.Assigned[player] == -1 {
+		return input
+	}
+	id := manager.Assigned[player]
+	controls := manager.Controls[player]
+
+	pressed := func(action InputAction) bool {
+		button := controls.Buttons[action]
+		return button != -1 && ebiten.StandardGamepadButtonValue(id, button) > GamepadTriggerThreshold
+	}
+	justPressed := func(action InputAction) bool {
+		button := controls.Buttons[action]
+		return button != -1 && inpututil.IsStandardGamepadButtonJustPressed(id, button)
+	}
+
+	input.MoveX, input.MoveY = applyDeadzone(
+		ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal),
+		ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical),
+		GamepadDeadzone)
+
+	input.Up = pressed(ActionUp)
+	input.Down = pressed(ActionDown)
+	input.Left = pressed(ActionLeft)
+	input.Right = pressed(ActionRight)
+	input.Shoot = pressed(ActionShoot)
+	input.Jump = pressed(ActionJump)
+	input.Bomb = pressed(ActionBomb)
+	input.OpenMenu = justPressed(ActionMenu)
+	for i := range input.ToggleGun {
+		input.ToggleGun[i] = justPressed(ActionWeapon1 + InputAction(i))
+	}
+	if justPressed(ActionNextGun) {
+		input.CycleGun += 1
+	}
+	if justPressed(ActionPreviousGun) {
+		input.CycleGun -= 1
+	}
+
+	return input
+}
+
+// input from two sources at once, such as the keyboard and a controller
+func mergeInput(a playerInputState, b playerInputState) playerInputState {
+	out := playerInputState{
+		Up:       a.Up || b.Up,
+		Down:     a.Down || b.Down,
+		Left:     a.Left || b.Left,
+		Right:    a.Right || b.Right,
+		Jump:     a.Jump || b.Jump,
+		Bomb:     a.Bomb || b.Bomb,
+		Shoot:    a.Shoot || b.Shoot,
+		OpenMenu: a.OpenMenu || b.OpenMenu,
+		MoveX:    math.Max(-1, math.Min(1, a.MoveX+b.MoveX)),
+		MoveY:    math.Max(-1, math.Min(1, a.MoveY+b.MoveY)),
+		CycleGun: a.CycleGun + b.CycleGun,
+	}
+	for i := range out.ToggleGun {
+		out.ToggleGun[i] = a.ToggleGun[i] || b.ToggleGun[i]
+	}
+	// a direction can't be added up, so the first source aiming wins
+	out.AimX, out.AimY = a.AimX, a.AimY
+	if out.AimX == 0 && out.AimY == 0 {
+		out.AimX, out.AimY = b.AimX, b.AimY
+	}
+	return out
+}
+
+// keys pressed this frame on the keyboard and on any controller, as they are typed
+func (run *Run) typedKeys() []ebiten.Key {
+	keys := inpututil.AppendJustPressedKeys(nil)
+	if run.Gamepads != nil {
+		keys = append(keys, run.Gamepads.MenuKeys()...)
+	}
+	return keys
+}
+
+// keys pressed this frame on the keyboard and on any controller, with keys bound to moving and to the
+// menu also pressing the keys they stand for, see Controls.menuKey
+func (run *Run) menuKeys() []ebiten.Key {
+	keys := run.typedKeys()
+	if run.Controls[0] != nil {
+		for _, key := range keys {
+			if menuKey, ok := run.Controls[0].menuKey(key); ok {
+				keys = append(keys, menuKey)
+			}
+		}
+	}
+	return keys
+}
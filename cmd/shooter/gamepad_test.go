@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestApplyDeadzone(t *testing.T) {
+	if x, y := applyDeadzone(0.1, -0.1, GamepadDeadzone); x != 0 || y != 0 {
+		t.Errorf("resting stick moved %v, %v", x, y)
+	}
+
+	// just past the deadzone is barely moving, the edge is full speed and the direction is kept
+	if x, _ := applyDeadzone(GamepadDeadzone+0.01, 0, GamepadDeadzone); x <= 0 || x > 0.05 {
+		t.Errorf("just past the deadzone moved %v", x)
+	}
+	x, y := applyDeadzone(-0.8, 0.6, GamepadDeadzone)
+	if math.Abs(math.Hypot(x, y)-1) > 1e-9 || math.Abs(x/y-(-0.8/0.6)) > 1e-9 {
+		t.Errorf("full stick moved %v, %v", x, y)
+	}
+}
+
+func TestStickMenuKey(t *testing.T) {
+	if key := stickMenuKey(0.1, 0, -1); key != -1 {
+		t.Errorf("resting stick pressed %v", key)
+	}
+	if key := stickMenuKey(0, -0.9, -1); key != ebiten.KeyArrowUp {
+		t.Errorf("stick up pressed %v", key)
+	}
+	if key := stickMenuKey(0.7, 0.2, -1); key != ebiten.KeyArrowRight {
+		t.Errorf("stick right pressed %v", key)
+	}
+	// easing off holds the direction instead of pressing it again, and only the center lets it go
+	if key := stickMenuKey(0, -0.4, ebiten.KeyArrowUp); key != ebiten.KeyArrowUp {
+		t.Errorf("easing off changed to %v", key)
+	}
+	if key := stickMenuKey(0, -0.4, -1); key != -1 {
+		t.Errorf("a half push pressed %v", key)
+	}
+}
+
+func TestCycleGun(t *testing.T) {
+	guns := []Gun{&BasicGun{}, &BeamGun{}, &MissleGun{}}
+	enabled := func() []bool {
+		var out []bool
+		for _, gun := range guns {
+			out = append(out, gun.IsEnabled())
+		}
+		return out
+	}
+
+	cycleGun(guns, 1)
+	if got := enabled(); !got[0] || got[1] || got[2] {
+		t.Fatalf("with no gun enabled next picked %v", got)
+	}
+
+	guns[2].SetEnabled(true)
+	cycleGun(guns, 1)
+	if got := enabled(); got[0] || !got[1] || got[2] {
+		t.Fatalf("next after the first enabled gun picked %v", got)
+	}
+
+	cycleGun(guns, -1)
+	cycleGun(guns, -1)
+	if got := enabled(); got[0] || got[1] || !got[2] {
+		t.Fatalf("previous did not wrap around: %v", got)
+	}
+}
+
+func TestMergeInput(t *testing.T) {
+	keyboard := playerInputState{Up: true, MoveX: 0.5}
+	keyboard.ToggleGun[1] = true
+	pad := playerInputState{Shoot: true, MoveX: 0.8, MoveY: -0.3, CycleGun: 1}
+
+	merged := mergeInput(keyboard, pad)
+	if !merged.Up || !merged.Shoot || !merged.ToggleGun[1] || merged.CycleGun != 1 {
+		t.Errorf("buttons lost in %+v", merged)
+	}
+	if merged.MoveX != 1 || merged.MoveY != -0.3 {
+		t.Errorf("movement %v, %v", merged.MoveX, merged.MoveY)
+	}
+}
@@ -18,10 +18,38 @@ import (
 	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
+// the direction a player's guns fire in as a unit vector, straight up the screen is 0, -1
+type Aim struct {
+	X float64
+	Y float64
+}
+
+var AimUp = Aim{X: 0, Y: -1}
+
+// the direction of x, y. the guns fire straight up if there is no direction
+func MakeAim(x float64, y float64) Aim {
+	length := math.Hypot(x, y)
+	if length == 0 {
+		return AimUp
+	}
+	return Aim{X: x / length, Y: y / length}
+}
+
+// turn a distance to the right of the gun and a distance in front of it so they point along the aim,
+// with AimUp this is just side, -forward
+func (aim Aim) Apply(side float64, forward float64) (float64, float64) {
+	return -side*aim.Y + forward*aim.X, side*aim.X + forward*aim.Y
+}
+
+// radians turned clockwise from straight up, for drawing
+func (aim Aim) Angle() float64 {
+	return math.Atan2(aim.X, -aim.Y)
+}
+
 type Gun interface {
-	Shoot(imageManager *ImageManager, x float64, y float64) ([]*Bullet, error)
+	Shoot(imageManager *ImageManager, x float64, y float64, aim Aim) ([]*Bullet, error)
 	Rate() float64
-	DoSound(soundManager *SoundManager)
+	DoSound(soundManager *SoundManager, x float64, y float64)
 	DrawIcon(screen *ebiten.Image, imageManager *ImageManager, x float64, y float64, textFace *text.GoTextFace)
 	IsEnabled() bool
 	SetEnabled(bool)
@@ -34,6 +62,9 @@ type Gun interface {
 
 	// a value from 0.0 to 1.0 indicating how close the gun is to leveling up
 	LevelPercent() float64
+
+	// upgrades bought in the shop, see upgrades.go
+	Upgrades() *GunUpgrades
 }
 
 type ElementType string
@@ -57,6 +88,7 @@ type BasicGun struct {
 	level       int
 	experience  float64
 	elementType ElementType
+	upgrades    GunUpgrades
 
 	// for tracking fire rate
 	counter int
@@ -129,7 +161,11 @@ func (basic *BasicGun) ElementType() ElementType {
 }
 
 func (basic *BasicGun) Rate() float64 {
-	return 10 + float64(basic.level)
+	return (10 + float64(basic.level)) * basic.upgrades.RateFactor()
+}
+
+func (basic *BasicGun) Upgrades() *GunUpgrades {
+	return &basic.upgrades
 }
 
 func drawGunBox(screen *ebiten.Image, x float64, y float64, color_ color.Color, icon *ebiten.Image) {
@@ -187,11 +223,11 @@ func (basic *BasicGun) DrawIcon(screen *ebiten.Image, imageManager *ImageManager
 	drawGunLevel(screen, basic, x, y, textFace)
 }
 
-func (basic *BasicGun) DoSound(soundManager *SoundManager) {
-	soundManager.PlayEffect(audioFiles.AudioShoot1)
+func (basic *BasicGun) DoSound(soundManager *SoundManager, x float64, y float64) {
+	soundManager.PlayEffectAt(audioFiles.AudioShoot1, x, y)
 }
 
-func (basic *BasicGun) Shoot(imageManager *ImageManager, x float64, y float64) ([]*Bullet, error) {
+func (basic *BasicGun) Shoot(imageManager *ImageManager, x float64, y float64, aim Aim) ([]*Bullet, error) {
 	if basic.enabled && basic.counter == 0 {
 		pic, _, err := imageManager.LoadImage(gameImages.ImageBullet)
 		if err != nil {
@@ -200,16 +236,17 @@ func (basic *BasicGun) Shoot(imageManager *ImageManager, x float64, y float64) (
 
 		basic.counter = int(60.0 / basic.Rate())
 
-		if basic.level <= 2 {
-			velocityY := -2.5
+		velocityX, velocityY := aim.Apply(0, 2.5)
 
+		if basic.level <= 2 {
 			bullet := Bullet{
 				x:           x,
 				y:           y,
 				Strength:    1 + float64(basic.level)*0.05,
 				health:      1,
-				velocityX:   0,
+				velocityX:   velocityX,
 				velocityY:   velocityY,
+				Angle:       aim.Angle(),
 				pic:         pic,
 				ElementType: basic.ElementType(),
 				Gun:         basic,
@@ -217,16 +254,16 @@ func (basic *BasicGun) Shoot(imageManager *ImageManager, x float64, y float64) (
 
 			return []*Bullet{&bullet}, nil
 		} else if basic.level <= 5 {
-			velocityY := -2.5
-
-			makeBullet := func(offsetX float64) *Bullet {
+			makeBullet := func(side float64) *Bullet {
+				offsetX, offsetY := aim.Apply(side, 0)
 				return &Bullet{
 					x:           x + offsetX,
-					y:           y,
+					y:           y + offsetY,
 					Strength:    1.1 + float64(basic.level)*0.05,
 					health:      1,
-					velocityX:   0,
+					velocityX:   velocityX,
 					velocityY:   velocityY,
+					Angle:       aim.Angle(),
 					pic:         pic,
 					ElementType: basic.ElementType(),
 					Gun:         basic,
@@ -235,23 +272,23 @@ func (basic *BasicGun) Shoot(imageManager *ImageManager, x float64, y float64) (
 
 			return []*Bullet{makeBullet(-6), makeBullet(6)}, nil
 		} else {
-			velocityY := -2.5
-
-			makeBullet := func(offsetX float64, offsetY float64) *Bullet {
+			makeBullet := func(side float64, forward float64) *Bullet {
+				offsetX, offsetY := aim.Apply(side, forward)
 				return &Bullet{
 					x:           x + offsetX,
 					y:           y + offsetY,
 					Strength:    1.1 + float64(basic.level)*0.05,
 					health:      1,
-					velocityX:   0,
+					velocityX:   velocityX,
 					velocityY:   velocityY,
+					Angle:       aim.Angle(),
 					pic:         pic,
 					ElementType: basic.ElementType(),
 					Gun:         basic,
 				}
 			}
 
-			return []*Bullet{makeBullet(-10, 3), makeBullet(10, 3), makeBullet(0, 0)}, nil
+			return []*Bullet{makeBullet(-10, -3), makeBullet(10, -3), makeBullet(0, 0)}, nil
 		}
 	} else {
 		return nil, nil
@@ -265,6 +302,7 @@ type DualBasicGun struct {
 	level       int
 	experience  float64
 	elementType ElementType
+	upgrades    GunUpgrades
 }
 
 func (dual *DualBasicGun) GetLevel() int {
@@ -309,7 +347,11 @@ func (dual *DualBasicGun) ElementType() ElementType {
 }
 
 func (dual *DualBasicGun) Rate() float64 {
-	return 7
+	return 7 * dual.upgrades.RateFactor()
+}
+
+func (dual *DualBasicGun) Upgrades() *GunUpgrades {
+	return &dual.upgrades
 }
 
 func (dual *DualBasicGun) DrawIcon(screen *ebiten.Image, imageManager *ImageManager, x float64, y float64, textFace *text.GoTextFace) {
@@ -345,34 +387,37 @@ func (dual *DualBasicGun) DrawIcon(screen *ebiten.Image, imageManager *ImageMana
 	drawGunBox(screen, x, y, iconColor(dual.enabled), dual.icon)
 }
 
-func (dual *DualBasicGun) DoSound(soundManager *SoundManager) {
-	soundManager.PlayEffect(audioFiles.AudioShoot1)
+func (dual *DualBasicGun) DoSound(soundManager *SoundManager, x float64, y float64) {
+	soundManager.PlayEffectAt(audioFiles.AudioShoot1, x, y)
 }
 
-func (dual *DualBasicGun) Shoot(imageManager *ImageManager, x float64, y float64) ([]*Bullet, error) {
+func (dual *DualBasicGun) Shoot(imageManager *ImageManager, x float64, y float64, aim Aim) ([]*Bullet, error) {
 	if dual.enabled && dual.counter == 0 {
 		dual.counter = int(60.0 / dual.Rate())
-		velocityY := -2.5
+		velocityX, velocityY := aim.Apply(0, 2.5)
 
 		pic, _, err := imageManager.LoadImage(gameImages.ImageBullet)
 		if err != nil {
 			return nil, err
 		}
 
+		offsetX, offsetY := aim.Apply(10, 0)
 		bullet1 := Bullet{
-			x:           x - 10,
-			y:           y,
+			x:           x - offsetX,
+			y:           y - offsetY,
 			Strength:    1,
 			health:      1,
-			velocityX:   0,
+			velocityX:   velocityX,
 			velocityY:   velocityY,
+			Angle:       aim.Angle(),
 			pic:         pic,
 			ElementType: dual.ElementType(),
 			Gun:         dual,
 		}
 
 		bullet2 := bullet1
-		bullet2.x += 20
+		bullet2.x = x + offsetX
+		bullet2.y = y + offsetY
 
 		return []*Bullet{&bullet1, &bullet2}, nil
 	} else {
@@ -386,6 +431,7 @@ type BeamGun struct {
 	level       int
 	experience  float64
 	elementType ElementType
+	upgrades    GunUpgrades
 }
 
 func (beam *BeamGun) LevelPercent() float64 {
@@ -436,11 +482,15 @@ func (beam *BeamGun) ElementType() ElementType {
 }
 
 func (beam *BeamGun) Rate() float64 {
-	return 3.5 + float64(beam.level)/3
+	return (3.5 + float64(beam.level)/3) * beam.upgrades.RateFactor()
+}
+
+func (beam *BeamGun) Upgrades() *GunUpgrades {
+	return &beam.upgrades
 }
 
-func (beam *BeamGun) DoSound(soundManager *SoundManager) {
-	soundManager.PlayEffect(audioFiles.AudioShoot1)
+func (beam *BeamGun) DoSound(soundManager *SoundManager, x float64, y float64) {
+	soundManager.PlayEffectAt(audioFiles.AudioShoot1, x, y)
 }
 
 func (beam *BeamGun) DrawIcon(screen *ebiten.Image, imageManager *ImageManager, x float64, y float64, textFace *text.GoTextFace) {
@@ -479,10 +529,10 @@ func drawBlendedLight(screen *ebiten.Image, x float64, y float64, radius float64
 	screen.DrawRectShader(int(radius*2), int(radius*2), shaderManager.AlphaCircleShader, options)
 }
 
-func (beam *BeamGun) Shoot(imageManager *ImageManager, x float64, y float64) ([]*Bullet, error) {
+func (beam *BeamGun) Shoot(imageManager *ImageManager, x float64, y float64, aim Aim) ([]*Bullet, error) {
 	if beam.enabled && beam.counter == 0 {
 		beam.counter = int(60.0 / beam.Rate())
-		velocityY := -2.3
+		velocityX, velocityY := aim.Apply(0, 2.3)
 
 		animation, err := imageManager.LoadAnimation(gameImages.ImageBeam1)
 		if err != nil {
@@ -491,20 +541,22 @@ func (beam *BeamGun) Shoot(imageManager *ImageManager, x float64, y float64) ([]
 
 		var bullets []*Bullet
 
-		makeBullet := func(offsetX float64) *Bullet {
+		makeBullet := func(side float64) *Bullet {
+			offsetX, offsetY := aim.Apply(side, 0)
 			return &Bullet{
 				x:           x + offsetX,
-				y:           y,
+				y:           y + offsetY,
 				Strength:    2 + float64(beam.level)*0.1,
 				health:      3,
-				velocityX:   0,
+				velocityX:   velocityX,
 				velocityY:   velocityY,
 				ElementType: beam.ElementType(),
 				Gun:         beam,
+				Angle:       aim.Angle(),
 				CustomDraw: func(bullet *Bullet, screen *ebiten.Image, shaderManager *ShaderManager, camera *Camera) {
 					x, y := camera.Apply(bullet.x, bullet.y)
 					drawBlendedLight(screen, x, y, 12, color.RGBA{R: 255, A: 255}, shaderManager)
-					animation.Draw(screen, x, y)
+					animation.DrawRotated(screen, x, y, bullet.Angle)
 				},
 				// pic: pic,
 			}
@@ -531,6 +583,7 @@ type MissleGun struct {
 	level       int
 	experience  float64
 	elementType ElementType
+	upgrades    GunUpgrades
 }
 
 func (missle *MissleGun) GetLevel() int {
@@ -581,11 +634,15 @@ func (missle *MissleGun) ElementType() ElementType {
 }
 
 func (missle *MissleGun) Rate() float64 {
-	return 2 + float64(missle.level)/4
+	return (2 + float64(missle.level)/4) * missle.upgrades.RateFactor()
+}
+
+func (missle *MissleGun) Upgrades() *GunUpgrades {
+	return &missle.upgrades
 }
 
-func (missle *MissleGun) DoSound(soundManager *SoundManager) {
-	soundManager.PlayEffect(audioFiles.AudioShoot1)
+func (missle *MissleGun) DoSound(soundManager *SoundManager, x float64, y float64) {
+	soundManager.PlayEffectAt(audioFiles.AudioShoot1, x, y)
 }
 
 func (missle *MissleGun) DrawIcon(screen *ebiten.Image, imageManager *ImageManager, x float64, y float64, textFace *text.GoTextFace) {
@@ -597,10 +654,10 @@ func (missle *MissleGun) DrawIcon(screen *ebiten.Image, imageManager *ImageManag
 	drawGunLevel(screen, missle, x, y, textFace)
 }
 
-func (missle *MissleGun) Shoot(imageManager *ImageManager, x float64, y float64) ([]*Bullet, error) {
+func (missle *MissleGun) Shoot(imageManager *ImageManager, x float64, y float64, aim Aim) ([]*Bullet, error) {
 	if missle.enabled && missle.counter == 0 {
 		missle.counter = int(60.0 / missle.Rate())
-		velocityY := -2.1 - float64(missle.level)*0.1
+		velocityX, velocityY := aim.Apply(0, 2.1+float64(missle.level)*0.1)
 
 		pic, _, err := imageManager.LoadImage(gameImages.ImageMissle1)
 		if err != nil {
@@ -612,16 +669,21 @@ func (missle *MissleGun) Shoot(imageManager *ImageManager, x float64, y float64)
 			y:           y,
 			Strength:    10 + float64(missle.level)*2,
 			health:      1,
-			velocityX:   0,
+			velocityX:   velocityX,
 			velocityY:   velocityY,
+			Angle:       aim.Angle(),
 			pic:         pic,
 			ElementType: missle.ElementType(),
 			Gun:         missle,
 			CustomDraw: func(bullet *Bullet, screen *ebiten.Image, shaderManager *ShaderManager, camera *Camera) {
 				x, y := camera.Apply(bullet.x, bullet.y)
-				drawBlendedLight(screen, x, y+float64(pic.Bounds().Dy())/2, 12, color.NRGBA{R: 255, G: 255, B: 128, A: 210}, shaderManager)
-				drawBlendedLight(screen, x, y+float64(pic.Bounds().Dy())/2+10, 8, color.NRGBA{R: 255, G: 255, B: 0, A: 180}, shaderManager)
-				drawCenteredImage(screen, pic, x, y)
+				// the exhaust glows behind the missile
+				tail := float64(pic.Bounds().Dy()) / 2
+				glowX, glowY := aim.Apply(0, -tail)
+				drawBlendedLight(screen, x+glowX, y+glowY, 12, color.NRGBA{R: 255, G: 255, B: 128, A: 210}, shaderManager)
+				glowX, glowY = aim.Apply(0, -tail-10)
+				drawBlendedLight(screen, x+glowX, y+glowY, 8, color.NRGBA{R: 255, G: 255, B: 0, A: 180}, shaderManager)
+				drawRotatedImage(screen, pic, x, y, bullet.Angle)
 			},
 		}
 
@@ -637,6 +699,7 @@ type LightningGun struct {
 	counter     int
 	experience  float64
 	elementType ElementType
+	upgrades    GunUpgrades
 
 	bulletImage *ebiten.Image
 }
@@ -670,6 +733,9 @@ type LineSegment struct {
 	life int
 }
 
+// how many ticks the trunk of a lightning bolt lasts, branches fade sooner
+const LightningLife = 100
+
 func newLightningRand(seed int64) *rand.Rand {
 	seed1 := uint64(seed)
 	seed2 := seed1 ^ 0x9e3779b97f4a7c15
@@ -720,7 +786,7 @@ func makeLightningSegments(rng *rand.Rand, x1 float64, y1 float64, x2 float64, y
 	return output
 }
 
-func (lightning *LightningGun) ShootWithSeed(imageManager *ImageManager, x float64, y float64, seed int64) ([]*Bullet, error) {
+func (lightning *LightningGun) ShootWithSeed(imageManager *ImageManager, x float64, y float64, aim Aim, seed int64) ([]*Bullet, error) {
 	rng := newLightningRand(seed)
 
 	var lowColor color.RGBA
@@ -736,10 +802,13 @@ func (lightning *LightningGun) ShootWithSeed(imageManager *ImageManager, x float
 	var bullets []*Bullet
 	var lastBullet *Bullet
 
-	endX := x + (rng.Float64()-0.5)*80
+	side := (rng.Float64() - 0.5) * 80
 	length := float64(600 + lightning.level*15)
-	endY := y - length + (rng.Float64()-0.5)*20
-	segments := makeLightningSegments(rng, x, y, endX, endY, 0.8, 20.0, 100)
+	forward := length - (rng.Float64()-0.5)*20
+	endX, endY := aim.Apply(side, forward)
+	endX += x
+	endY += y
+	segments := makeLightningSegments(rng, x, y, endX, endY, 0.8, 20.0, LightningLife)
 
 	for _, segment := range segments {
 		sx := segment.x1
@@ -773,6 +842,7 @@ func (lightning *LightningGun) ShootWithSeed(imageManager *ImageManager, x float
 				LightningSeed:    seed,
 				LightningOriginX: x,
 				LightningOriginY: y,
+				LightningAim:     aim,
 				LightningLevel:   lightning.level,
 				Gun:              lightning,
 			}
@@ -837,22 +907,26 @@ func (lightning *LightningGun) ShootWithSeed(imageManager *ImageManager, x float
 	return bullets, nil
 }
 
-func (lightning *LightningGun) Shoot(imageManager *ImageManager, x float64, y float64) ([]*Bullet, error) {
+func (lightning *LightningGun) Shoot(imageManager *ImageManager, x float64, y float64, aim Aim) ([]*Bullet, error) {
 	if lightning.enabled && lightning.counter == 0 {
 		lightning.counter = int(60.0 / lightning.Rate())
 		seed := int64(rand.Uint64())
-		return lightning.ShootWithSeed(imageManager, x, y, seed)
+		return lightning.ShootWithSeed(imageManager, x, y, aim, seed)
 	}
 
 	return nil, nil
 }
 
 func (lightning *LightningGun) Rate() float64 {
-	return 0.8 + float64(lightning.level)/10
+	return (0.8 + float64(lightning.level)/10) * lightning.upgrades.RateFactor()
+}
+
+func (lightning *LightningGun) Upgrades() *GunUpgrades {
+	return &lightning.upgrades
 }
 
-func (lightning *LightningGun) DoSound(soundManager *SoundManager) {
-	soundManager.PlayEffect(audioFiles.AudioLightning)
+func (lightning *LightningGun) DoSound(soundManager *SoundManager, x float64, y float64) {
+	soundManager.PlayEffectAt(audioFiles.AudioLightning, x, y)
 }
 
 func (lightning *LightningGun) DrawIcon(screen *ebiten.Image, imageManager *ImageManager, x float64, y float64, textFace *text.GoTextFace) {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math"
 	"testing"
 )
 
@@ -45,11 +46,11 @@ func TestLightningGunShootWithSeedIsDeterministic(t *testing.T) {
 		elementType: ElementLightning,
 	}
 
-	left, err := gun.ShootWithSeed(nil, 100, 200, 12345)
+	left, err := gun.ShootWithSeed(nil, 100, 200, AimUp, 12345)
 	if err != nil {
 		t.Fatalf("first ShootWithSeed failed: %v", err)
 	}
-	right, err := gun.ShootWithSeed(nil, 100, 200, 12345)
+	right, err := gun.ShootWithSeed(nil, 100, 200, AimUp, 12345)
 	if err != nil {
 		t.Fatalf("second ShootWithSeed failed: %v", err)
 	}
@@ -86,7 +87,86 @@ func BenchmarkLightningGun(bench *testing.B) {
 	}
 
 	for bench.Loop() {
-		gun.Shoot(nil, 0, 0)
+		gun.Shoot(nil, 0, 0, AimUp)
 		gun.counter = 0
 	}
 }
+
+func TestGunUpgradesLockOtherBranch(t *testing.T) {
+	gun := &BasicGun{enabled: true}
+	upgrades := gun.Upgrades()
+
+	if !upgrades.Buy(GunBranchRate) {
+		t.Fatal("expected to buy the first rate upgrade")
+	}
+	if upgrades.CanBuy(GunBranchSpread) {
+		t.Fatal("spread should be locked after choosing rate")
+	}
+
+	for upgrades.Buy(GunBranchRate) {
+	}
+	if upgrades.Tier != MaxGunBranchTier {
+		t.Fatalf("tier %d, want %d", upgrades.Tier, MaxGunBranchTier)
+	}
+
+	if gun.Rate() <= (&BasicGun{}).Rate() {
+		t.Fatalf("rate upgrade did not increase rate: %v", gun.Rate())
+	}
+}
+
+func TestApplyGunUpgradesSpread(t *testing.T) {
+	gun := &BasicGun{enabled: true, upgrades: GunUpgrades{Branch: GunBranchSpread, Tier: 1}}
+	bullets := []*Bullet{{Strength: 1, health: 1, velocityY: -2.5, Gun: gun, Angle: AimUp.Angle()}}
+
+	out := applyGunUpgrades(gun, bullets)
+	if len(out) != 3 {
+		t.Fatalf("got %d bullets, want 3", len(out))
+	}
+	if out[1].velocityX >= 0 || out[2].velocityX <= 0 {
+		t.Fatalf("spread bullets should angle left and right: %v %v", out[1].velocityX, out[2].velocityX)
+	}
+	for _, bullet := range out {
+		if want := MakeAim(bullet.velocityX, bullet.velocityY).Angle(); math.Abs(bullet.Angle-want) > 1e-9 {
+			t.Fatalf("bullet heading %v, %v is drawn at %v, want %v", bullet.velocityX, bullet.velocityY, bullet.Angle, want)
+		}
+	}
+}
+
+func TestAim(t *testing.T) {
+	if aim := MakeAim(0, 0); aim != AimUp {
+		t.Fatalf("no direction aimed %v", aim)
+	}
+
+	// with AimUp the side is x and forward is up the screen
+	if x, y := AimUp.Apply(10, 3); x != 10 || y != -3 {
+		t.Fatalf("up moved %v, %v", x, y)
+	}
+
+	right := MakeAim(5, 0)
+	if x, y := right.Apply(10, 3); math.Abs(x-3) > 1e-9 || math.Abs(y-10) > 1e-9 {
+		t.Fatalf("right moved %v, %v", x, y)
+	}
+	if math.Abs(right.Angle()-math.Pi/2) > 1e-9 || AimUp.Angle() != 0 {
+		t.Fatalf("angles %v and %v", right.Angle(), AimUp.Angle())
+	}
+}
+
+func TestLightningGunAims(t *testing.T) {
+	gun := &LightningGun{level: 2, enabled: true}
+
+	bullets, err := gun.ShootWithSeed(nil, 100, 200, MakeAim(1, 0), 12345)
+	if err != nil {
+		t.Fatalf("ShootWithSeed failed: %v", err)
+	}
+
+	furthest := 0.0
+	for _, bullet := range bullets {
+		furthest = math.Max(furthest, bullet.x-100)
+		if bullet.LightningAim.X != 1 {
+			t.Fatalf("bullet lost its aim: %v", bullet.LightningAim)
+		}
+	}
+	if furthest < 500 {
+		t.Fatalf("bolt aimed right only reached %v to the right", furthest)
+	}
+}
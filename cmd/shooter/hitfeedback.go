@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+// a bullet that does at least this much damage is a heavy hit, such as a missile
+const HeavyHitDamage = 5.0
+
+// ticks the world freezes for on a heavy hit
+const HitStopTicks = 4
+const HitFlashTicks = 8
+
+const DamageNumberLife = 45
+
+// what happened when a bullet hit an enemy, used to show the player how well the element worked
+type HitResult struct {
+	Damage  float64
+	Element ElementType
+	// the enemy is weak or resistant to the element of the bullet
+	Weak     bool
+	Resisted bool
+}
+
+// heavy hits get the hit stop and flash
+func (result HitResult) Heavy() bool {
+	return result.Damage >= HeavyHitDamage
+}
+
+type HitFeedbackSettings struct {
+	DamageNumbers bool
+	// freeze the world for a few ticks on heavy hits
+	HitStop  bool
+	HitFlash bool
+}
+
+func DefaultHitFeedbackSettings() HitFeedbackSettings {
+	return HitFeedbackSettings{
+		DamageNumbers: true,
+		HitStop:       true,
+		HitFlash:      true,
+	}
+}
+
+func elementColor(element ElementType) color.RGBA {
+	switch element {
+	case ElementPlasma:
+		return color.RGBA{R: 0x6c, G: 0xd8, B: 0xff, A: 0xff}
+	case ElementLightning:
+		return color.RGBA{R: 0xff, G: 0xe8, B: 0x4a, A: 0xff}
+	default:
+		return color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	}
+}
+
+// a number that floats up from where a bullet hit. purely cosmetic so it is never sent over the network
+type DamageNumber struct {
+	x, y      float64
+	velocityY float64
+	age       int
+	result    HitResult
+}
+
+func MakeDamageNumber(x float64, y float64, result HitResult) *DamageNumber {
+	return &DamageNumber{
+		x:         x + randomFloat(-8, 8),
+		y:         y,
+		velocityY: -2.5,
+		result:    result,
+	}
+}
+
+func (number *DamageNumber) Update() {
+	number.age += 1
+	number.y += number.velocityY
+	number.velocityY *= 0.92
+}
+
+func (number *DamageNumber) IsAlive() bool {
+	return number.age < DamageNumberLife
+}
+
+// the text of the number and the small label under it
+func (number *DamageNumber) Labels() (string, string) {
+	amount := fmt.Sprintf("%.0f", math.Max(1, math.Round(number.result.Damage)))
+
+	if number.result.Heavy() {
+		amount += "!"
+	}
+
+	switch {
+	case number.result.Weak:
+		return amount, "WEAK"
+	case number.result.Resisted:
+		return amount, "RESIST"
+	}
+
+	return amount, ""
+}
+
+func (number *DamageNumber) Draw(screen *ebiten.Image, font *text.GoTextFaceSource, camera *Camera, elementShapes bool) {
+	size := 16.0
+	if number.result.Weak {
+		size = 20
+	}
+	if number.result.Resisted {
+		size = 13
+	}
+	if number.result.Heavy() {
+		size *= 1.5
+	}
+
+	// pop in at a larger size then settle
+	if number.age < 6 {
+		size *= 1 + float64(6-number.age)*0.08
+	}
+
+	clr := elementColor(number.result.Element)
+	if number.result.Resisted {
+		clr = color.RGBA{R: clr.R / 2, G: clr.G / 2, B: clr.B / 2, A: 0xff}
+	}
+
+	alpha := 1.0
+	if fade := DamageNumberLife - number.age; fade < 15 {
+		alpha = float64(fade) / 15
+	}
+
+	x, y := camera.Apply(number.x, number.y)
+	amount, label := number.Labels()
+
+	face := &text.GoTextFace{Source: font, Size: size}
+	width, height := text.Measure(amount, face, 0)
+
+	// a dark copy underneath keeps the number readable over explosions
+	for _, layer := range []struct {
+		offset float64
+		clr    color.Color
+	}{{2, color.Black}, {0, clr}} {
+		options := &text.DrawOptions{}
+		options.GeoM.Translate(x-width/2+layer.offset, y-height/2+layer.offset)
+		options.ColorScale.ScaleWithColor(layer.clr)
+		options.ColorScale.ScaleAlpha(float32(alpha))
+		text.Draw(screen, amount, face, options)
+	}
+
+	if elementShapes {
+		shapeSize := size / 2
+		drawElementShape(screen, number.result.Element, x-width/2-shapeSize, y, shapeSize, clr)
+	}
+
+	if label != "" {
+		labelFace := &text.GoTextFace{Source: font, Size: 10}
+		labelWidth, _ := text.Measure(label, labelFace, 0)
+		options := &text.DrawOptions{}
+		options.GeoM.Translate(x-labelWidth/2, y+height/2)
+		options.ColorScale.ScaleWithColor(clr)
+		options.ColorScale.ScaleAlpha(float32(alpha))
+		text.Draw(screen, label, labelFace, options)
+	}
+}
+
+// show the result of a bullet hitting an enemy at x, y
+func (game *Game) showHit(x float64, y float64, result HitResult) {
+	settings := game.HitFeedback
+	if settings == nil {
+		return
+	}
+
+	if settings.DamageNumbers {
+		game.DamageNumbers = append(game.DamageNumbers, MakeDamageNumber(x, y, result))
+	}
+
+	if result.Heavy() {
+		// both peers have to run the same ticks, so the world is only ever frozen in single player
+		if settings.HitStop && game.Multiplayer == nil {
+			game.HitStop = HitStopTicks
+		}
+		if settings.HitFlash {
+			game.HitFlash = HitFlashTicks
+		}
+	}
+}
+
+func (game *Game) updateDamageNumbers() {
+	alive := game.DamageNumbers[:0]
+	for _, number := range game.DamageNumbers {
+		number.Update()
+		if number.IsAlive() {
+			alive = append(alive, number)
+		}
+	}
+	game.DamageNumbers = alive
+
+	if game.HitFlash > 0 {
+		game.HitFlash -= 1
+	}
+}
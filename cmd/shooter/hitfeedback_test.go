@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEnemyHitElements(t *testing.T) {
+	enemy := &NormalEnemy{
+		Life:       1000,
+		Strengths:  []ElementType{ElementPlasma},
+		Weaknesses: []ElementType{ElementLightning},
+		dead:       make(chan struct{}),
+	}
+
+	check := func(element ElementType, damage float64, weak bool, resisted bool) {
+		result := enemy.DescribeHit(&Bullet{Strength: 10, ElementType: element})
+		if math.Abs(result.Damage-damage) > 1e-9 || result.Weak != weak || result.Resisted != resisted {
+			t.Errorf("%v hit %+v, want %v damage weak %v resisted %v", element, result, damage, weak, resisted)
+		}
+	}
+
+	check(ElementPhysical, 10, false, false)
+	check(ElementPlasma, 9, false, true)
+	check(ElementLightning, 11, true, false)
+
+	// describing a hit does not damage the enemy
+	if enemy.Life != 1000 {
+		t.Errorf("enemy life went down to %v", enemy.Life)
+	}
+
+	// the hit takes off what was described
+	bullet := &Bullet{Strength: 10, ElementType: ElementLightning}
+	described := enemy.DescribeHit(bullet)
+	enemy.Hit(bullet)
+	if math.Abs(1000-enemy.Life-described.Damage) > 1e-9 {
+		t.Errorf("a hit described as %v damage left the enemy at %v", described.Damage, enemy.Life)
+	}
+}
+
+func TestHitStopSinglePlayerOnly(t *testing.T) {
+	settings := DefaultHitFeedbackSettings()
+	game := &Game{HitFeedback: &settings}
+
+	game.showHit(0, 0, HitResult{Damage: 5})
+	if len(game.DamageNumbers) != 1 || game.HitStop != 0 {
+		t.Fatalf("a normal hit should only show a number, got %v numbers and hit stop %v", len(game.DamageNumbers), game.HitStop)
+	}
+
+	game.showHit(0, 0, HitResult{Damage: 20})
+	if game.HitStop != HitStopTicks || game.HitFlash != HitFlashTicks {
+		t.Errorf("a heavy hit should freeze and flash, got %v and %v", game.HitStop, game.HitFlash)
+	}
+
+	game = &Game{HitFeedback: &settings, Multiplayer: &gameMultiplayer{Role: multiplayerRoleMaster}}
+	game.showHit(0, 0, HitResult{Damage: 20})
+	if game.HitStop != 0 {
+		t.Errorf("multiplayer games should never freeze")
+	}
+}
+
+func TestDamageNumberLabels(t *testing.T) {
+	number := MakeDamageNumber(0, 0, HitResult{Damage: 21.6, Weak: true})
+	amount, label := number.Labels()
+	if amount != "22!" || label != "WEAK" {
+		t.Errorf("labels %q %q", amount, label)
+	}
+
+	for number.IsAlive() {
+		number.Update()
+	}
+	if number.age != DamageNumberLife {
+		t.Errorf("number lived %v ticks", number.age)
+	}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+)
+
+// must match MaxLights in shaders/lighting.kage
+const MaxLights = 64
+
+// brightness of the scene where no light reaches
+const LightAmbient = 0.7
+
+// how many bullets of a lightning bolt there are between each of its lights
+const LightningLightSpacing = 40
+
+// how long a new lightning bolt flashes the scene
+const LightningFlashTicks = 4
+
+// how steep the normal maps made from sprites are
+const NormalMapStrength = 3.0
+
+type LightingSettings struct {
+	Enabled bool
+	// shade enemies and asteroids with normal maps made from their sprites
+	NormalMaps bool
+}
+
+// normal maps are an extra full screen pass, so browsers start without them
+func DefaultLightingSettings() LightingSettings {
+	return LightingSettings{
+		Enabled:    true,
+		NormalMaps: runtime.GOOS != "js",
+	}
+}
+
+// a light in world coordinates
+type PointLight struct {
+	X, Y   float64
+	Radius float64
+	// r, g, b from 0 to 1
+	Color     [3]float64
+	Intensity float64
+}
+
+func (light PointLight) visible(camera *Camera, width float64, height float64) bool {
+	x, y := camera.Apply(light.X, light.Y)
+	return x+light.Radius > 0 && y+light.Radius > 0 && x-light.Radius < width && y-light.Radius < height
+}
+
+// the background, enemies and asteroids are drawn to a buffer which is then drawn to the screen lit by
+// the lights registered that frame
+type LightingSystem struct {
+	Settings *LightingSettings
+	Lights   []PointLight
+	// from 0 to 1, lights up the whole scene after a lightning strike
+	Flash float64
+
+	scene   *ebiten.Image
+	normals *ebiten.Image
+	// normal maps made from sprites, keyed by the sprite
+	normalMaps map[*ebiten.Image]*ebiten.Image
+
+	// reused between frames
+	lightUniforms []float32
+	colorUniforms []float32
+}
+
+func MakeLightingSystem(settings *LightingSettings) *LightingSystem {
+	return &LightingSystem{
+		Settings:      settings,
+		normalMaps:    make(map[*ebiten.Image]*ebiten.Image),
+		lightUniforms: make([]float32, MaxLights*4),
+		colorUniforms: make([]float32, MaxLights*4),
+	}
+}
+
+func (lighting *LightingSystem) IsEnabled() bool {
+	return lighting != nil && lighting.Settings != nil && lighting.Settings.Enabled
+}
+
+func (lighting *LightingSystem) Add(light PointLight) {
+	lighting.Lights = append(lighting.Lights, light)
+}
+
+// light up the whole scene for a moment
+func (lighting *LightingSystem) Strike(amount float64) {
+	lighting.Flash = math.Max(lighting.Flash, amount)
+}
+
+func (lighting *LightingSystem) Update() {
+	if lighting == nil {
+		return
+	}
+	lighting.Flash *= 0.8
+	if lighting.Flash < 0.01 {
+		lighting.Flash = 0
+	}
+}
+
+// where the lit part of the scene should be drawn this frame, which is the screen itself when lighting
+// is off
+func (lighting *LightingSystem) Begin(screen *ebiten.Image) *ebiten.Image {
+	if !lighting.IsEnabled() {
+		return screen
+	}
+
+	lighting.Lights = lighting.Lights[:0]
+
+	width := screen.Bounds().Dx()
+	height := screen.Bounds().Dy()
+	lighting.scene = ensureImage(lighting.scene, width, height)
+	lighting.scene.Clear()
+	// the shader reads the normals even when they are not used, so they always match the scene
+	lighting.normals = ensureImage(lighting.normals, width, height)
+	if lighting.Settings.NormalMaps {
+		lighting.normals.Clear()
+	}
+
+	return lighting.scene
+}
+
+// the normal map of a sprite, made from how bright each pixel is. pixels that are not part of the sprite
+// are left transparent so the flat normal is used there
+func makeNormalMap(raw image.Image) *image.NRGBA {
+	bounds := raw.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	heights := make([]float64, width*height)
+	for y := range height {
+		for x := range width {
+			r, g, b, a := raw.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// the edges of the sprite slope down to nothing because the alpha is part of the height
+			heights[y*width+x] = (0.3*float64(r) + 0.59*float64(g) + 0.11*float64(b)) / 65535 * float64(a) / 65535
+		}
+	}
+
+	at := func(x int, y int) float64 {
+		x = max(0, min(width-1, x))
+		y = max(0, min(height-1, y))
+		return heights[y*width+x]
+	}
+
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			_, _, _, a := raw.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			if a == 0 {
+				continue
+			}
+
+			// sobel
+			dx := (at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1)) - (at(x-1, y-1) + 2*at(x-1, y) + at(x-1, y+1))
+			dy := (at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1)) - (at(x-1, y-1) + 2*at(x, y-1) + at(x+1, y-1))
+
+			nx := -dx * NormalMapStrength
+			ny := -dy * NormalMapStrength
+			length := math.Sqrt(nx*nx + ny*ny + 1)
+
+			out.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(math.Round((nx/length + 1) / 2 * 255)),
+				G: uint8(math.Round((ny/length + 1) / 2 * 255)),
+				B: uint8(math.Round((1/length + 1) / 2 * 255)),
+				A: 255,
+			})
+		}
+	}
+
+	return out
+}
+
+func (lighting *LightingSystem) normalMap(pic *ebiten.Image, raw image.Image) *ebiten.Image {
+	normals, ok := lighting.normalMaps[pic]
+	if !ok {
+		normals = ebiten.NewImageFromImage(makeNormalMap(raw))
+		lighting.normalMaps[pic] = normals
+	}
+	return normals
+}
+
+// draw the normal map of a sprite that is centered on x, y in world coordinates and rotated by radians.
+// the normals are turned along with the sprite
+func (lighting *LightingSystem) DrawNormals(camera *Camera, pic *ebiten.Image, raw image.Image, x float64, y float64, radians float64) {
+	if !lighting.IsEnabled() || !lighting.Settings.NormalMaps {
+		return
+	}
+
+	normals := lighting.normalMap(pic, raw)
+
+	cos := math.Cos(radians)
+	sin := math.Sin(radians)
+
+	// rotate the x and y of each normal around the encoded zero of 0.5
+	var rotate colorm.ColorM
+	rotate.SetElement(0, 0, cos)
+	rotate.SetElement(0, 1, -sin)
+	rotate.SetElement(0, 4, 0.5-0.5*cos+0.5*sin)
+	rotate.SetElement(1, 0, sin)
+	rotate.SetElement(1, 1, cos)
+	rotate.SetElement(1, 4, 0.5-0.5*sin-0.5*cos)
+
+	screenX, screenY := camera.Apply(x, y)
+	options := &colorm.DrawImageOptions{}
+	options.GeoM.Translate(-float64(normals.Bounds().Dx())/2, -float64(normals.Bounds().Dy())/2)
+	options.GeoM.Rotate(radians)
+	options.GeoM.Translate(screenX, screenY)
+	colorm.DrawImage(lighting.normals, normals, rotate, options)
+}
+
+// draw the lit scene onto the screen
+func (lighting *LightingSystem) End(screen *ebiten.Image, shaders *ShaderManager, camera *Camera) {
+	if !lighting.IsEnabled() {
+		return
+	}
+
+	width := float64(lighting.scene.Bounds().Dx())
+	height := float64(lighting.scene.Bounds().Dy())
+
+	visible := make([]PointLight, 0, len(lighting.Lights))
+	for _, light := range lighting.Lights {
+		if light.visible(camera, width, height) {
+			visible = append(visible, light)
+		}
+	}
+
+	// when there are too many keep the brightest
+	if len(visible) > MaxLights {
+		slices.SortFunc(visible, func(a PointLight, b PointLight) int {
+			return cmp.Compare(b.Intensity*b.Radius, a.Intensity*a.Radius)
+		})
+		visible = visible[:MaxLights]
+	}
+
+	clear(lighting.lightUniforms)
+	clear(lighting.colorUniforms)
+	for i, light := range visible {
+		x, y := camera.Apply(light.X, light.Y)
+		copy(lighting.lightUniforms[i*4:], []float32{float32(x), float32(y), float32(light.Radius), float32(light.Intensity)})
+		copy(lighting.colorUniforms[i*4:], []float32{float32(light.Color[0]), float32(light.Color[1]), float32(light.Color[2]), 1})
+	}
+
+	useNormals := float32(0)
+	if lighting.Settings.NormalMaps {
+		useNormals = 1
+	}
+
+	flash := float32(lighting.Flash * 0.6)
+
+	options := &ebiten.DrawRectShaderOptions{}
+	options.Images[0] = lighting.scene
+	options.Images[1] = lighting.normals
+	options.Uniforms = map[string]any{
+		"Lights":      lighting.lightUniforms,
+		"LightColors": lighting.colorUniforms,
+		"LightCount":  len(visible),
+		"Ambient":     float32(LightAmbient),
+		"Flash":       []float32{flash * 0.8, flash * 0.9, flash},
+		"UseNormals":  useNormals,
+	}
+	screen.DrawRectShader(int(width), int(height), shaders.LightingShader, options)
+}
+
+func elementLightColor(element ElementType) [3]float64 {
+	clr := elementColor(element)
+	return [3]float64{float64(clr.R) / 255, float64(clr.G) / 255, float64(clr.B) / 255}
+}
+
+// the glow around a powerup, false for powerups that do not glow
+func powerupLight(powerup Powerup) (PointLight, bool) {
+	light := PointLight{Radius: 80, Intensity: 0.6}
+	switch current := powerup.(type) {
+	case *PowerupEnergy:
+		light.X, light.Y, light.Color = current.x, current.y, [3]float64{0.4, 0.8, 1}
+	case *PowerupHealth:
+		light.X, light.Y, light.Color = current.x, current.y, [3]float64{0.4, 1, 0.5}
+	case *PowerupWeapon:
+		light.X, light.Y, light.Color = current.x, current.y, [3]float64{1, 0.6, 0.2}
+	case *PowerupBomb:
+		light.X, light.Y, light.Color = current.x, current.y, [3]float64{1, 0.3, 0.3}
+	case *PowerupEnergyIncrease:
+		light.X, light.Y, light.Color = current.x, current.y, [3]float64{0.7, 0.5, 1}
+	default:
+		return light, false
+	}
+	return light, true
+}
+
+// register the lights of everything that glows this frame
+func (game *Game) collectLights() {
+	lighting := game.Lighting
+	if !lighting.IsEnabled() {
+		return
+	}
+
+	// a lightning bolt is made of hundreds of bullets, so only every so often one of them is lit
+	boltBullets := make(map[int64]int)
+
+	for _, bullet := range game.Bullets {
+		if bullet.Kind == "lightning" {
+			count := boltBullets[bullet.LightningSeed]
+			boltBullets[bullet.LightningSeed] = count + 1
+			if count%LightningLightSpacing == 0 {
+				lighting.Add(PointLight{X: bullet.x, Y: bullet.y, Radius: 140, Color: [3]float64{0.6, 0.8, 1}, Intensity: math.Min(1, float64(bullet.RemainingLife)/50)})
+			}
+			// the first few frames of a new bolt flash the whole scene
+			if bullet.RemainingLife > LightningLife-LightningFlashTicks {
+				lighting.Strike(1)
+			}
+			continue
+		}
+		lighting.Add(PointLight{X: bullet.x, Y: bullet.y, Radius: 70, Color: elementLightColor(bullet.ElementType), Intensity: 0.6})
+	}
+
+	for _, bullet := range game.EnemyBullets {
+		lighting.Add(PointLight{X: bullet.x, Y: bullet.y, Radius: 50, Color: [3]float64{1, 0.35, 0.3}, Intensity: 0.5})
+	}
+
+	for _, explosion := range game.Explosions {
+		lighting.Add(explosion.Light())
+	}
+
+	for _, player := range game.players() {
+		if player.IsAlive() {
+			lighting.Add(PointLight{X: player.x, Y: player.y + float64(player.pic.Bounds().Dy())/2, Radius: 110, Color: [3]float64{1, 0.6, 0.3}, Intensity: 0.7})
+		}
+	}
+
+	for _, powerup := range game.Powerups {
+		if light, ok := powerupLight(powerup); ok {
+			lighting.Add(light)
+		}
+	}
+}
+
+// the normal maps of the lit sprites
+func (game *Game) drawLightingNormals(camera *Camera) {
+	lighting := game.Lighting
+	if !lighting.IsEnabled() || !lighting.Settings.NormalMaps {
+		return
+	}
+
+	for _, enemy := range game.Enemies {
+		normal, ok := enemy.(*NormalEnemy)
+		if !ok {
+			continue
+		}
+		x, y := normal.move.Coords(normal.x, normal.y)
+		radians := 0.0
+		if normal.Flip {
+			radians = math.Pi
+		}
+		lighting.DrawNormals(camera, normal.pic, normal.rawImage, x, y, radians)
+	}
+
+	for _, asteroid := range game.Asteroids {
+		pic, raw, err := game.ImageManager.LoadImage(asteroid.pic)
+		if err != nil {
+			continue
+		}
+		lighting.DrawNormals(camera, pic, raw, asteroid.x, asteroid.y, asteroid.Radians())
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNormalMapSlopes(t *testing.T) {
+	// a ramp that gets brighter to the right, so the surface faces left
+	raw := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := range 8 {
+		for x := range 8 {
+			raw.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 30), G: uint8(x * 30), B: uint8(x * 30), A: 255})
+		}
+	}
+	raw.SetNRGBA(0, 0, color.NRGBA{})
+
+	normals := makeNormalMap(raw)
+
+	middle := normals.NRGBAAt(4, 4)
+	if middle.R >= 128 || middle.G != 128 || middle.B <= 128 {
+		t.Errorf("ramp normal %+v should point left and out of the screen", middle)
+	}
+
+	if empty := normals.NRGBAAt(0, 0); empty.A != 0 {
+		t.Errorf("transparent pixel got normal %+v", empty)
+	}
+
+	// a flat sprite faces straight out of the screen
+	flat := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := range 4 {
+		for x := range 4 {
+			flat.SetNRGBA(x, y, color.NRGBA{R: 100, G: 100, B: 100, A: 255})
+		}
+	}
+	if normal := makeNormalMap(flat).NRGBAAt(1, 1); normal != (color.NRGBA{R: 128, G: 128, B: 255, A: 255}) {
+		t.Errorf("flat normal %+v", normal)
+	}
+}
+
+func TestLightVisible(t *testing.T) {
+	camera := &Camera{x: 100, y: 200}
+
+	for _, test := range []struct {
+		light PointLight
+		want  bool
+	}{
+		{PointLight{X: 150, Y: 250, Radius: 10}, true},
+		// just off the left edge but the light still reaches in
+		{PointLight{X: 90, Y: 250, Radius: 20}, true},
+		{PointLight{X: 50, Y: 250, Radius: 20}, false},
+		{PointLight{X: 150, Y: 200 + ScreenHeight + 30, Radius: 20}, false},
+	} {
+		if got := test.light.visible(camera, camera.Width(), ScreenHeight); got != test.want {
+			t.Errorf("light %+v visible %v, want %v", test.light, got, test.want)
+		}
+	}
+}
+
+func TestLightningLights(t *testing.T) {
+	settings := DefaultLightingSettings()
+	game := &Game{Player: &Player{}, Lighting: MakeLightingSystem(&settings)}
+
+	for range LightningLightSpacing * 3 {
+		game.Bullets = append(game.Bullets, &Bullet{Kind: "lightning", LightningSeed: 1, RemainingLife: LightningLife})
+	}
+
+	game.collectLights()
+	if len(game.Lighting.Lights) != 3 {
+		t.Errorf("a bolt of %v bullets made %v lights", len(game.Bullets), len(game.Lighting.Lights))
+	}
+	if game.Lighting.Flash != 1 {
+		t.Errorf("a new bolt should flash the scene, got %v", game.Lighting.Flash)
+	}
+
+	for range 30 {
+		game.Lighting.Update()
+	}
+	if game.Lighting.Flash != 0 {
+		t.Errorf("flash still at %v", game.Lighting.Flash)
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+
+	audioFiles "github.com/kazzmir/webgl-shooter/audio"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// lives a player starts arcade mode with, and gets back after a continue
+const ArcadeLives = 3
+const ArcadeContinues = 3
+
+// an extra life is awarded every time the score passes another multiple of this
+const ExtraLifeScore = 1500
+
+// a partner has to stay within ReviveDistance of a wreck for ReviveTime ticks to revive it
+const ReviveDistance = 110
+const ReviveTime = 60 * 3
+
+// ticks to wait after the last player goes down before showing the game over screen
+const GameOverDelay = 90
+
+var GameOver error = errors.New("game over")
+
+// set up the lives and continues used by arcade mode
+func (player *Player) StartArcade() {
+	player.Lives = ArcadeLives
+	player.Continues = ArcadeContinues
+	player.NextExtraLife = ExtraLifeScore
+}
+
+// called after the player has been destroyed. in arcade mode this uses up a life, and without any
+// lives left the wreck stays where it is until a partner revives it
+func (game *Game) handlePlayerDeath(player *Player) {
+	if !game.Arcade {
+		player.Respawn()
+		return
+	}
+
+	if player.Lives > 0 {
+		player.Lives -= 1
+		player.Respawn()
+		return
+	}
+
+	player.ReviveProgress = 0
+	player.velocityX = 0
+	player.velocityY = 0
+}
+
+func (player *Player) Revive() {
+	player.Health = player.MaxHealth / 2
+	player.RespawnBlink = RespawnBlinkDuration
+	player.ReviveProgress = 0
+}
+
+// start over after a game over, the score is lost
+func (player *Player) Continue() {
+	player.Score = 0
+	player.BankedScore = 0
+	player.Lives = ArcadeLives
+	player.NextExtraLife = ExtraLifeScore
+	player.ReviveProgress = 0
+	player.Respawn()
+}
+
+func (player *Player) checkExtraLife(soundManager *SoundManager) {
+	if player.NextExtraLife == 0 || player.Score < player.NextExtraLife {
+		return
+	}
+
+	for player.Score >= player.NextExtraLife {
+		player.Lives += 1
+		player.NextExtraLife += ExtraLifeScore
+	}
+
+	soundManager.PlayEffect(audioFiles.AudioEnergy)
+}
+
+func (game *Game) players() []*Player {
+	players := []*Player{game.Player}
+	if game.RemotePlayer != nil {
+		players = append(players, game.RemotePlayer)
+	}
+	return players
+}
+
+func (game *Game) allPlayersDown() bool {
+	for _, player := range game.players() {
+		if player.IsAlive() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// a downed player comes back if its partner hovers near the wreck
+func (game *Game) updateRevives() {
+	if game.isSlave() || game.RemotePlayer == nil {
+		return
+	}
+
+	for _, pair := range [][2]*Player{{game.Player, game.RemotePlayer}, {game.RemotePlayer, game.Player}} {
+		downed, rescuer := pair[0], pair[1]
+		if downed.IsAlive() {
+			continue
+		}
+
+		if rescuer.IsAlive() && math.Hypot(downed.x-rescuer.x, downed.y-rescuer.y) < ReviveDistance {
+			downed.ReviveProgress += 1
+			if downed.ReviveProgress >= ReviveTime {
+				downed.Revive()
+				game.SoundManager.PlayEffectAt(audioFiles.AudioHealth, downed.x, downed.y)
+			}
+		} else if downed.ReviveProgress > 0 {
+			downed.ReviveProgress -= 1
+		}
+	}
+}
+
+// returns GameOver once every player is down with no lives left
+func (game *Game) updateLives() error {
+	if !game.Arcade {
+		return nil
+	}
+
+	if !game.isSlave() {
+		for _, player := range game.players() {
+			player.checkExtraLife(game.SoundManager)
+		}
+	}
+
+	game.updateRevives()
+
+	if game.allPlayersDown() {
+		game.GameOverCounter += 1
+		if game.GameOverCounter >= GameOverDelay {
+			game.Music.GameOver()
+			return GameOver
+		}
+	} else {
+		game.GameOverCounter = 0
+	}
+
+	return nil
+}
+
+// draw a dimmed ship where a downed player went down, along with how far the revive has progressed
+func (game *Game) drawWreck(screen *ebiten.Image, camera *Camera, player *Player) {
+	if player == nil || player.IsAlive() || !game.Arcade {
+		return
+	}
+
+	var tint colorm.ColorM
+	tint.Scale(0.4, 0.4, 0.4, 0.6)
+	player.drawBase(screen, camera, &tint)
+
+	screenX, screenY := camera.Apply(player.x, player.y)
+	radius := float32(player.pic.Bounds().Dy()) / 2
+	vector.StrokeCircle(screen, float32(screenX), float32(screenY), radius+8, 1, color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}, true)
+
+	if player.ReviveProgress > 0 {
+		var path vector.Path
+		end := float32(-math.Pi/2 + 2*math.Pi*float64(player.ReviveProgress)/ReviveTime)
+		path.Arc(float32(screenX), float32(screenY), radius+8, -math.Pi/2, end, vector.Clockwise)
+		strokeOptions := &vector.StrokeOptions{Width: 4}
+		drawOptions := &vector.DrawPathOptions{AntiAlias: true}
+		drawOptions.ColorScale.ScaleWithColor(color.RGBA{R: 0x52, G: 0xff, B: 0x8c, A: 0xff})
+		vector.StrokePath(screen, &path, strokeOptions, drawOptions)
+	}
+}
+
+// the number of lives left, shown as small ships starting at x, y
+func (game *Game) drawLives(screen *ebiten.Image, player *Player, x float64, y float64) {
+	if !game.Arcade {
+		return
+	}
+
+	shown := min(player.Lives, 5)
+	for i := range shown {
+		var options ebiten.DrawImageOptions
+		options.GeoM.Scale(0.25, 0.25)
+		options.GeoM.Translate(x+float64(i)*20, y)
+		screen.DrawImage(player.pic, &options)
+	}
+
+	face := &text.GoTextFace{Source: game.Font, Size: 15}
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(x+float64(shown)*20+4, y+4)
+	op.ColorScale.ScaleWithColor(color.White)
+	text.Draw(screen, fmt.Sprintf("x%v", player.Lives), face, op)
+}
+
+// in co-op only the master decides whether to continue, the slave waits for the master's snapshot
+type GameOverScreen struct {
+	Font         *text.GoTextFaceSource
+	SoundManager *SoundManager
+	Counter      uint64
+	List         OptionList
+}
+
+func (gameOver *GameOverScreen) makeOptions(run *Run) []*MenuOption {
+	var options []*MenuOption
+
+	options = append(options, &MenuOption{
+		TextFunc: func() string {
+			if run.Game.isSlave() {
+				return "Waiting for partner"
+			}
+			if run.Player.Continues <= 0 {
+				return "No continues left"
+			}
+			return fmt.Sprintf("Continue (%v left)", run.Player.Continues)
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			if run.Game.isSlave() || run.Player.Continues <= 0 {
+				return nil
+			}
+
+			run.Player.Continues -= 1
+			for _, player := range run.Game.players() {
+				player.Continue()
+			}
+			run.Game.GameOverCounter = 0
+			run.Game.Music.Resume()
+			run.Mode = RunGame
+			run.Game.sendSnapshot()
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	options = append(options, &MenuOption{
+		Text: "Quit to menu",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			run.Game.Cancel()
+			run.Game = nil
+			run.Player = nil
+			run.Mode = RunMenu
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	return options
+}
+
+func MakeGameOverScreen(font *text.GoTextFaceSource, soundManager *SoundManager, run *Run) *GameOverScreen {
+	gameOver := &GameOverScreen{
+		Font:         font,
+		SoundManager: soundManager,
+	}
+	gameOver.List.Options = gameOver.makeOptions(run)
+	return gameOver
+}
+
+func (gameOver *GameOverScreen) Update(run *Run) error {
+	gameOver.Counter += 1
+
+	// the master continued, so the slave's player was brought back by a snapshot
+	if run.Game.isSlave() && !run.Game.allPlayersDown() {
+		run.Game.Music.Resume()
+		run.Mode = RunGame
+		return nil
+	}
+
+	for _, key := range gameOver.List.Keys(run) {
+		err := gameOver.List.HandleKey(run, gameOver.SoundManager, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (gameOver *GameOverScreen) Draw(screen *ebiten.Image, player *Player) {
+	titleFace := text.GoTextFace{Source: gameOver.Font, Size: 48}
+	title := "Game Over"
+	viewWidth := float64(screen.Bounds().Dx())
+	width, _ := text.Measure(title, &titleFace, 0)
+	drawText(screen, titleFace, (viewWidth-width)/2, 200, title, color.RGBA{R: 0xff, G: 0x40, B: 0x40, A: 0xff})
+
+	scoreFace := text.GoTextFace{Source: gameOver.Font, Size: 20}
+	score := fmt.Sprintf("Score: %v   Kills: %v", player.Score, player.Kills)
+	width, _ = text.Measure(score, &scoreFace, 0)
+	drawText(screen, scoreFace, (viewWidth-width)/2, 280, score, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	optionWidth := 300.0
+	x := (viewWidth - optionWidth) / 2
+	gameOver.List.Draw(screen, text.GoTextFace{Source: gameOver.Font, Size: 18}, x+10, 360, optionWidth, 6, gameOver.Counter)
+}
+
+func (run *Run) OpenGameOver() {
+	if run.Game == nil {
+		log.Printf("Game over without a game")
+		run.Mode = RunMenu
+		return
+	}
+
+	run.GameOver = MakeGameOverScreen(run.Menu.Font, run.SoundManager, run)
+	run.Mode = RunGameOver
+}
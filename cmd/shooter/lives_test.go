@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestArcadeDeathUsesLives(t *testing.T) {
+	game := &Game{Arcade: true}
+	player := &Player{MaxHealth: 100, Guns: DefaultLoadout().Guns()}
+	player.StartArcade()
+
+	for i := range ArcadeLives {
+		player.Health = 0
+		game.handlePlayerDeath(player)
+		if !player.IsAlive() {
+			t.Fatalf("player should respawn while lives remain, death %d", i)
+		}
+	}
+
+	if player.Lives != 0 {
+		t.Fatalf("lives %d, want 0", player.Lives)
+	}
+
+	player.Health = 0
+	game.handlePlayerDeath(player)
+	if player.IsAlive() {
+		t.Fatal("player without lives should stay down")
+	}
+
+	game.Player = player
+	if !game.allPlayersDown() {
+		t.Fatal("a single downed player should end the game")
+	}
+}
+
+func TestEndlessDeathAlwaysRespawns(t *testing.T) {
+	game := &Game{}
+	player := &Player{MaxHealth: 100, Guns: DefaultLoadout().Guns()}
+
+	for range 10 {
+		player.Health = 0
+		game.handlePlayerDeath(player)
+		if !player.IsAlive() {
+			t.Fatal("endless mode should always respawn")
+		}
+	}
+}
+
+func TestContinueResetsScore(t *testing.T) {
+	player := &Player{MaxHealth: 100, Score: 4000, BankedScore: 3000, Guns: DefaultLoadout().Guns()}
+	player.StartArcade()
+	player.Lives = 0
+
+	player.Continue()
+
+	if player.Score != 0 || player.BankedScore != 0 {
+		t.Fatalf("continue kept the score: %v %v", player.Score, player.BankedScore)
+	}
+	if player.Lives != ArcadeLives || player.NextExtraLife != ExtraLifeScore {
+		t.Fatalf("continue did not restore lives: %v %v", player.Lives, player.NextExtraLife)
+	}
+	if !player.IsAlive() {
+		t.Fatal("player should be alive after continuing")
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// the loadout screen is shown before a run starts and lets the player pick a ship and starting guns
+type LoadoutMenu struct {
+	Font         *text.GoTextFaceSource
+	SoundManager *SoundManager
+	ImageManager *ImageManager
+	Counter      uint64
+	List         OptionList
+	Loadout      Loadout
+	// limited lives instead of respawning forever
+	Arcade bool
+	// called when the player confirms, the chosen loadout is in run.Loadout by then
+	Confirm func(run *Run) error
+}
+
+func (loadoutMenu *LoadoutMenu) makeOptions(confirmText string) []*MenuOption {
+	var options []*MenuOption
+
+	direction := func(key ebiten.Key) int {
+		if key == ebiten.KeyArrowLeft {
+			return -1
+		}
+		return 1
+	}
+
+	var shipKinds []ShipKind
+	for _, ship := range shipDefinitions {
+		shipKinds = append(shipKinds, ship.Kind)
+	}
+
+	options = append(options, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("Ship: %v", shipDefinition(loadoutMenu.Loadout.Ship).Name)
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			loadoutMenu.Loadout.Ship = cycleChoice(shipKinds, loadoutMenu.Loadout.Ship, direction(key))
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	options = append(options, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("Primary: %v", gunKindName(loadoutMenu.Loadout.Primary))
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			loadoutMenu.Loadout.Primary = cycleChoice(primaryGunKinds, loadoutMenu.Loadout.Primary, direction(key))
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	options = append(options, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("Secondary: %v", gunKindName(loadoutMenu.Loadout.Secondary))
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			loadoutMenu.Loadout.Secondary = cycleChoice(secondaryGunKinds, loadoutMenu.Loadout.Secondary, direction(key))
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	options = append(options, &MenuOption{
+		TextFunc: func() string {
+			if loadoutMenu.Arcade {
+				return fmt.Sprintf("Mode: Arcade (%v lives)", ArcadeLives)
+			}
+			return "Mode: Endless"
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			loadoutMenu.Arcade = !loadoutMenu.Arcade
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	options = append(options, &MenuOption{
+		Text: confirmText,
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			run.Loadout = loadoutMenu.Loadout
+			run.Arcade = loadoutMenu.Arcade
+			return loadoutMenu.Confirm(run)
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	options = append(options, &MenuOption{
+		Text: "Back",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			run.Mode = RunMenu
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	return options
+}
+
+func MakeLoadoutMenu(font *text.GoTextFaceSource, soundManager *SoundManager, imageManager *ImageManager, loadout Loadout, confirmText string, confirm func(run *Run) error) *LoadoutMenu {
+	loadoutMenu := &LoadoutMenu{
+		Font:         font,
+		SoundManager: soundManager,
+		ImageManager: imageManager,
+		Loadout:      loadout,
+		Confirm:      confirm,
+	}
+	loadoutMenu.List.Options = loadoutMenu.makeOptions(confirmText)
+	return loadoutMenu
+}
+
+func (loadoutMenu *LoadoutMenu) Update(run *Run) error {
+	loadoutMenu.Counter += 1
+
+	for _, key := range loadoutMenu.List.Keys(run) {
+		switch key {
+		case ebiten.KeyEscape, ebiten.KeyCapsLock:
+			run.Mode = RunMenu
+			return nil
+		default:
+			err := loadoutMenu.List.HandleKey(run, loadoutMenu.SoundManager, key)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// draw a horizontal bar filled to value/maximum
+func drawStatBar(screen *ebiten.Image, face text.GoTextFace, x, y float64, label string, value float64, maximum float64) {
+	drawText(screen, face, x, y, label, color.RGBA{R: 200, G: 200, B: 200, A: 255})
+	barX := float32(x + 130)
+	barY := float32(y + 4)
+	vector.FillRect(screen, barX, barY, 200, 12, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, true)
+	vector.FillRect(screen, barX, barY, float32(200*value/maximum), 12, color.RGBA{R: 0x52, G: 0xc8, B: 0xff, A: 0xff}, true)
+}
+
+func (loadoutMenu *LoadoutMenu) Draw(screen *ebiten.Image) {
+	var x float64 = 100
+	var y float64 = 60
+
+	drawText(screen, text.GoTextFace{Source: loadoutMenu.Font, Size: 28}, x, y, "Loadout", color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	y += 60
+
+	optionWidth, y := loadoutMenu.List.Draw(screen, text.GoTextFace{Source: loadoutMenu.Font, Size: 18}, x, y, 300, 6, loadoutMenu.Counter)
+
+	drawText(screen, text.GoTextFace{Source: loadoutMenu.Font, Size: 14}, x, y+10, "Left and right change the selection", color.RGBA{R: 200, G: 200, B: 200, A: 255})
+
+	ship := shipDefinition(loadoutMenu.Loadout.Ship)
+	previewX := x + optionWidth + 120
+	previewY := 140.0
+
+	pic, _, err := loadoutMenu.ImageManager.LoadImage(ship.Image)
+	if err != nil {
+		log.Printf("Unable to load ship image %v: %v", ship.Image, err)
+	} else {
+		var options ebiten.DrawImageOptions
+		options.GeoM.Translate(-float64(pic.Bounds().Dx())/2, 0)
+		options.GeoM.Scale(1.5, 1.5)
+		options.GeoM.Translate(previewX+165, previewY)
+		screen.DrawImage(pic, &options)
+		previewY += float64(pic.Bounds().Dy())*1.5 + 30
+	}
+
+	drawText(screen, text.GoTextFace{Source: loadoutMenu.Font, Size: 22}, previewX, previewY, ship.Name, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	previewY += 30
+	drawText(screen, text.GoTextFace{Source: loadoutMenu.Font, Size: 14}, previewX, previewY, ship.Description, color.RGBA{R: 200, G: 220, B: 255, A: 255})
+	previewY += 40
+
+	var maxSpeed, maxHealth, maxRegen float64
+	for _, other := range shipDefinitions {
+		maxSpeed = math.Max(maxSpeed, other.Speed)
+		maxHealth = math.Max(maxHealth, other.MaxHealth)
+		maxRegen = math.Max(maxRegen, other.EnergyRegen)
+	}
+
+	statFace := text.GoTextFace{Source: loadoutMenu.Font, Size: 16}
+	drawStatBar(screen, statFace, previewX, previewY, "Speed", ship.Speed, maxSpeed)
+	previewY += 30
+	drawStatBar(screen, statFace, previewX, previewY, "Health", ship.MaxHealth, maxHealth)
+	previewY += 30
+	// every ship has the same base regeneration, the bar shows the total
+	drawStatBar(screen, statFace, previewX, previewY, "Energy regen", 0.4+ship.EnergyRegen, 0.4+maxRegen)
+	previewY += 30
+	if err == nil {
+		bounds := pic.Bounds()
+		drawText(screen, statFace, previewX, previewY, fmt.Sprintf("Hitbox %vx%v", bounds.Dx(), bounds.Dy()), color.RGBA{R: 200, G: 200, B: 200, A: 255})
+	}
+}
+
+// show the loadout screen, confirm is called once the player has picked a loadout
+func (run *Run) OpenLoadout(confirmText string, confirm func(run *Run) error) {
+	run.LoadoutMenu = MakeLoadoutMenu(run.Menu.Font, run.SoundManager, run.Menu.ImageManager, run.Loadout, confirmText, confirm)
+	run.LoadoutMenu.Arcade = run.Arcade
+	run.Mode = RunLoadout
+}
+
+// the slave tells the master which loadout it picked so the master can create the slave's ship
+func (run *Run) announceLoadout() {
+	if run.PeerConnector == nil || !run.PeerConnector.IsConnected() {
+		run.LoadoutAnnounced = false
+		return
+	}
+
+	if run.LoadoutAnnounced || !run.PeerConnector.IsSlave() {
+		return
+	}
+
+	loadout := run.Loadout
+	if err := run.PeerConnector.SendGameMessage(multiplayerEnvelope{
+		Kind:    "loadout",
+		Loadout: &loadout,
+	}); err != nil {
+		log.Printf("Unable to send loadout: %v", err)
+		return
+	}
+
+	run.LoadoutAnnounced = true
+}
@@ -40,6 +40,7 @@ import (
 
 const debugForceBoss = false
 
+// the default width of the view, the actual width is DisplaySettings.Width
 const ScreenWidth = 1200
 const ScreenHeight = 800
 const LogicalWidth = 2000
@@ -49,9 +50,6 @@ const CameraEdgeFadeAlpha = 0.85
 const OffscreenEnemyIndicatorSize = 24
 const OffscreenEnemyIndicatorMaxAlpha = 128.0 / 255.0
 const OffscreenEnemyIndicatorPulseSpeed = 0.12
-const GalaxyParallaxFactor = 0.48
-const PlanetParallaxFactor = 0.64
-const StarParallaxFactor = 0.85
 
 var triangleFillImage = func() *ebiten.Image {
 	img := ebiten.NewImage(1, 1)
@@ -59,29 +57,72 @@ var triangleFillImage = func() *ebiten.Image {
 	return img
 }()
 
-func onLogicalScreen(x float64, y float64, margin float64) bool {
-	return x > -margin && x < LogicalWidth+margin && y > -margin && y < ScreenHeight+margin
+func onLogicalScreen(x float64, y float64, margin float64, worldHeight float64) bool {
+	return x > -margin && x < LogicalWidth+margin && y > -margin && y < worldHeight+margin
 }
 
 type Camera struct {
 	x float64
 	y float64
+	// see CameraScroll in world.go
+	scrollSpeed float64
+	// the width of the view, see SetWidth. the halves of a split screen are narrower, see coop.go
+	width float64
+	// the height of the world of the level, see WorldLayout
+	worldHeight float64
+}
+
+// how much of the world the camera sees across
+func (camera *Camera) Width() float64 {
+	if camera.width > 0 {
+		return camera.width
+	}
+	return ScreenWidth
+}
+
+// how tall the world the camera moves around in is
+func (camera *Camera) WorldHeight() float64 {
+	if camera.worldHeight > 0 {
+		return camera.worldHeight
+	}
+	return ScreenHeight
+}
+
+// follow a change to the width of the view, such as the window being resized
+func (camera *Camera) SetWidth(width float64) {
+	camera.width = width
+	camera.Clamp()
 }
 
 func (camera *Camera) Clamp() {
-	maxX := math.Max(0, float64(LogicalWidth-ScreenWidth))
+	maxX := math.Max(0, float64(LogicalWidth)-camera.Width())
 	camera.x = math.Max(0, math.Min(camera.x, maxX))
-	camera.y = 0
+	maxY := math.Max(0, camera.WorldHeight()-ScreenHeight)
+	camera.y = math.Max(0, math.Min(camera.y, maxY))
 }
 
 func (camera *Camera) TrackPlayer(player *Player) {
-	leftEdge := camera.x + CameraEdgeMargin
-	rightEdge := camera.x + ScreenWidth - CameraEdgeMargin
+	// a narrow view would have its edges cross over
+	margin := math.Min(CameraEdgeMargin, camera.Width()/3)
+	leftEdge := camera.x + margin
+	rightEdge := camera.x + camera.Width() - margin
 
 	if player.x < leftEdge {
-		camera.x = player.x - CameraEdgeMargin
+		camera.x = player.x - margin
 	} else if player.x > rightEdge {
-		camera.x = player.x - (ScreenWidth - CameraEdgeMargin)
+		camera.x = player.x - (camera.Width() - margin)
+	}
+
+	// a scrolling camera moves up on its own
+	if !camera.IsScrolling() {
+		topEdge := camera.y + CameraEdgeMarginY
+		bottomEdge := camera.y + ScreenHeight - CameraEdgeMarginY
+
+		if player.y < topEdge {
+			camera.y = player.y - CameraEdgeMarginY
+		} else if player.y > bottomEdge {
+			camera.y = player.y - (ScreenHeight - CameraEdgeMarginY)
+		}
 	}
 
 	camera.Clamp()
@@ -115,6 +156,15 @@ func drawCenteredImage(screen *ebiten.Image, pic *ebiten.Image, x float64, y flo
 	screen.DrawImage(pic, options)
 }
 
+// like drawCenteredImage but turned clockwise around its center
+func drawRotatedImage(screen *ebiten.Image, pic *ebiten.Image, x float64, y float64, radians float64) {
+	options := &ebiten.DrawImageOptions{}
+	options.GeoM.Translate(-float64(pic.Bounds().Dx())/2, -float64(pic.Bounds().Dy())/2)
+	options.GeoM.Rotate(radians)
+	options.GeoM.Translate(x, y)
+	screen.DrawImage(pic, options)
+}
+
 func drawEdgeFade(screen *ebiten.Image, x1 float32, x2 float32, alpha1 float32, alpha2 float32) {
 	if x2 <= x1 {
 		return
@@ -152,10 +202,11 @@ func drawHorizontalEnemyIndicator(screen *ebiten.Image, y1 float32, y2 float32,
 		return
 	}
 
+	width := float32(screen.Bounds().Dx())
 	vertices := []ebiten.Vertex{
 		{DstX: 0, DstY: y1, SrcX: 0, SrcY: 0, ColorR: 1, ColorG: 0, ColorB: 0, ColorA: alpha1},
-		{DstX: ScreenWidth, DstY: y1, SrcX: 1, SrcY: 0, ColorR: 1, ColorG: 0, ColorB: 0, ColorA: alpha1},
-		{DstX: ScreenWidth, DstY: y2, SrcX: 1, SrcY: 1, ColorR: 1, ColorG: 0, ColorB: 0, ColorA: alpha2},
+		{DstX: width, DstY: y1, SrcX: 1, SrcY: 0, ColorR: 1, ColorG: 0, ColorB: 0, ColorA: alpha1},
+		{DstX: width, DstY: y2, SrcX: 1, SrcY: 1, ColorR: 1, ColorG: 0, ColorB: 0, ColorA: alpha2},
 		{DstX: 0, DstY: y2, SrcX: 0, SrcY: 1, ColorR: 1, ColorG: 0, ColorB: 0, ColorA: alpha2},
 	}
 
@@ -174,7 +225,7 @@ func offscreenEnemySides(enemies []Enemy, camera *Camera) (left bool, right bool
 	}
 
 	viewLeft := int(camera.x)
-	viewRight := viewLeft + ScreenWidth
+	viewRight := viewLeft + int(camera.Width())
 	viewTop := int(camera.y)
 	viewBottom := viewTop + ScreenHeight
 
@@ -209,24 +260,36 @@ func offscreenEnemySides(enemies []Enemy, camera *Camera) (left bool, right bool
 }
 
 func drawOffscreenEnemyIndicators(screen *ebiten.Image, enemies []Enemy, camera *Camera, counter uint64) {
-	left, right, _, _ := offscreenEnemySides(enemies, camera)
+	left, right, top, bottom := offscreenEnemySides(enemies, camera)
 	alpha := offscreenEnemyIndicatorAlpha(counter)
 
 	if left {
 		drawVerticalEnemyIndicator(screen, 0, OffscreenEnemyIndicatorSize, 0, alpha)
 	}
 	if right {
-		drawVerticalEnemyIndicator(screen, ScreenWidth-OffscreenEnemyIndicatorSize, ScreenWidth, alpha, 0)
+		width := float32(screen.Bounds().Dx())
+		drawVerticalEnemyIndicator(screen, width-OffscreenEnemyIndicatorSize, width, alpha, 0)
+	}
+
+	// in a single screen tall level every enemy above the view is just arriving
+	if camera.WorldHeight() > ScreenHeight {
+		if top {
+			drawHorizontalEnemyIndicator(screen, 0, OffscreenEnemyIndicatorSize, alpha, 0)
+		}
+		if bottom {
+			drawHorizontalEnemyIndicator(screen, ScreenHeight-OffscreenEnemyIndicatorSize, ScreenHeight, 0, alpha)
+		}
 	}
 }
 
-func drawOffscreenPlayerIndicator(screen *ebiten.Image, font *text.GoTextFaceSource, camera *Camera, player *Player) {
+// area is the HUD area of the screen the camera's view is drawn on
+func drawOffscreenPlayerIndicator(screen *ebiten.Image, font *text.GoTextFaceSource, camera *Camera, player *Player, area HudArea) {
 	if player == nil || !player.IsAlive() || font == nil {
 		return
 	}
 
 	screenX, screenY := camera.Apply(player.x, player.y)
-	if screenX >= 0 && screenX <= ScreenWidth {
+	if screenX >= 0 && screenX <= camera.Width() && screenY >= 0 && screenY <= ScreenHeight {
 		return
 	}
 
@@ -234,11 +297,20 @@ func drawOffscreenPlayerIndicator(screen *ebiten.Image, font *text.GoTextFaceSou
 	label := "player"
 	textWidth, textHeight := text.Measure(label, &face, 0)
 	padding := 12.0
-	textX := padding
-	if screenX > ScreenWidth {
-		textX = ScreenWidth - textWidth - padding
+
+	// pin the label to the edge the player is past, following the player along that edge
+	textX := math.Max(area.Left+padding, math.Min(area.Right-textWidth-padding, screenX-textWidth/2))
+	if screenX < 0 {
+		textX = area.Left + padding
+	} else if screenX > camera.Width() {
+		textX = area.Right - textWidth - padding
 	}
 	textY := math.Max(0, math.Min(ScreenHeight-textHeight, screenY-textHeight/2))
+	if screenY < 0 {
+		textY = area.Top + padding
+	} else if screenY > ScreenHeight {
+		textY = area.Bottom - textHeight - padding
+	}
 
 	drawText(screen, face, textX, textY, label, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
 }
@@ -306,10 +378,8 @@ func (game *Game) addBulletKillRewards(bullet *Bullet, enemy Enemy) {
 	}
 
 	owner.Kills += 1
+	owner.Credits += 2
 	owner.AddExperience(enemy.Experience())
-	if owner.Kills%20 == 0 {
-		game.AddPowerup(MakeRandomPowerup(randomFloat(10, LogicalWidth-10), -20))
-	}
 }
 
 // generates a bunch of colors between start and end, interpolating linerally
@@ -368,8 +438,11 @@ type Bullet struct {
 	LightningSeed        int64
 	LightningOriginX     float64
 	LightningOriginY     float64
+	LightningAim         Aim
 	LightningLevel       int
 	Gun                  Gun
+	// radians the picture is turned clockwise, see Aim
+	Angle float64
 
 	// optional func that returns true if we should keep the bullet, and false if we should remove it
 	Update     func(bullet *Bullet) bool
@@ -387,9 +460,9 @@ func (bullet *Bullet) Draw(screen *ebiten.Image, shaderManager *ShaderManager, c
 	} else {
 		x, y := camera.Apply(bullet.x, bullet.y)
 		if bullet.animation != nil {
-			bullet.animation.Draw(screen, x, y)
+			bullet.animation.DrawRotated(screen, x, y, bullet.Angle)
 		} else if bullet.pic != nil {
-			drawCenteredImage(screen, bullet.pic, x, y)
+			drawRotatedImage(screen, bullet.pic, x, y, bullet.Angle)
 		}
 	}
 }
@@ -403,296 +476,82 @@ func (bullet *Bullet) Move() {
 	}
 }
 
-func (bullet *Bullet) IsAlive() bool {
-	return bullet.health > 0 && onLogicalScreen(bullet.x, bullet.y, 10)
-}
-
-type StarPosition struct {
-	x, y   float64
-	dx, dy float64
-	Image  *ebiten.Image
-}
-
-type GalaxyPosition struct {
-	x, y  float64
-	tilt  float64
-	scale float64
-}
-
-type PlanetAsset struct {
-	Image *ebiten.Image
-	Cloud *ebiten.Image
-}
-
-type PlanetPosition struct {
-	x, y          float64
-	scale         float64
-	rotationSpeed float64
-	axis          Vector3
-	asset         *PlanetAsset
-}
-
-type Background struct {
-	Backdrop     *ebiten.Image
-	BackdropName gameImages.Image
-	Galaxy       *ebiten.Image
-	GalaxyShader *ebiten.Shader
-	Galaxies     []*GalaxyPosition
-	PlanetShader *ebiten.Shader
-	PlanetAssets []*PlanetAsset
-	Planet       *PlanetPosition
-
-	// Star *ebiten.Image
-	// Star2 *ebiten.Image
-	Stars []*StarPosition
-}
+// how far outside the view bullets keep going, see Game.inView
+const BulletViewMargin = 10
 
-func randomBackdropName() gameImages.Image {
-	backdrops := [...]gameImages.Image{
-		gameImages.ImageGalaxy,
-		gameImages.ImagePillars,
-	}
-
-	return backdrops[rand.N(len(backdrops))]
+// a bullet that leaves the world is dropped by Game.Update, see Game.inWorld
+func (bullet *Bullet) IsAlive() bool {
+	return bullet.health > 0
 }
 
 func randomFloat(min float64, max float64) float64 {
 	return min + rand.Float64()*(max-min)
 }
 
-func randomPlanetAxis() Vector3 {
-	axis := Vector3{
-		X: float32(randomFloat(-0.5, 0.5)),
-		Y: float32(randomFloat(-0.5, 0.5)),
-		Z: float32(randomFloat(-0.5, 0.5)),
-	}
-
-	if axis.X == 0 && axis.Y == 0 && axis.Z == 0 {
-		axis.Z = 1
-	}
+type ShaderManager struct {
+	RedShader         *ebiten.Shader
+	ShadowShader      *ebiten.Shader
+	EdgeShader        *ebiten.Shader
+	ExplosionShader   *ebiten.Shader
+	AlphaCircleShader *ebiten.Shader
 
-	return axis
-}
+	// post processing passes, see postprocess.go
+	BloomShader      *ebiten.Shader
+	AberrationShader *ebiten.Shader
+	VignetteShader   *ebiten.Shader
+	CRTShader        *ebiten.Shader
 
-func makePlanetPosition(assets []*PlanetAsset) *PlanetPosition {
-	return &PlanetPosition{
-		x:             randomFloat(0, float64(LogicalWidth)),
-		y:             randomFloat(-float64(ScreenHeight), float64(ScreenHeight)),
-		scale:         randomFloat(0.3, 0.8),
-		rotationSpeed: randomFloat(1, 4),
-		axis:          randomPlanetAxis(),
-		asset:         assets[rand.N(len(assets))],
-	}
+	// composites point lights onto the scene, see lighting.go
+	LightingShader *ebiten.Shader
 }
 
-func MakeBackground(backdropName gameImages.Image) (*Background, error) {
-	if backdropName == "" {
-		backdropName = randomBackdropName()
-	}
-
-	backdropImage, err := gameImages.LoadImage(backdropName)
-	if err != nil {
-		return nil, err
-	}
-
-	galaxyImage, err := gameImages.LoadImage(gameImages.ImageGalaxy)
-	if err != nil {
-		return nil, err
-	}
-
-	galaxyShader, err := LoadGalaxyShader()
+func MakeShaderManager() (*ShaderManager, error) {
+	redShader, err := LoadRedShader()
 	if err != nil {
 		return nil, err
 	}
 
-	planetShader, err := LoadPlanetShader()
+	shadowShader, err := LoadShadowShader()
 	if err != nil {
 		return nil, err
 	}
 
-	earthImage, err := gameImages.LoadImage(gameImages.ImageEarth)
+	edgeShader, err := LoadEdgeShader()
 	if err != nil {
 		return nil, err
 	}
 
-	marsImage, err := gameImages.LoadImage(gameImages.ImageMars)
+	explosionShader, err := LoadExplosionShader()
 	if err != nil {
 		return nil, err
 	}
 
-	alienWorldImage, err := gameImages.LoadImage(gameImages.ImageAlienWorld)
+	alphaCircleShader, err := LoadAlphaCircleShader()
 	if err != nil {
 		return nil, err
 	}
 
-	cloud1Image, err := gameImages.LoadImage(gameImages.ImageCloud1)
+	bloomShader, err := LoadBloomShader()
 	if err != nil {
 		return nil, err
 	}
 
-	cloudAImage, err := gameImages.LoadImage(gameImages.ImageCloudA)
+	aberrationShader, err := LoadAberrationShader()
 	if err != nil {
 		return nil, err
 	}
 
-	starImage, err := gameImages.LoadImage(gameImages.ImageStar1)
+	vignetteShader, err := LoadVignetteShader()
 	if err != nil {
 		return nil, err
 	}
 
-	starImage2, err := gameImages.LoadImage(gameImages.ImageStar2)
+	crtShader, err := LoadCRTShader()
 	if err != nil {
 		return nil, err
 	}
 
-	planet1, err := gameImages.LoadImage(gameImages.ImagePlanet)
-	if err != nil {
-		return nil, err
-	}
-
-	images := []*ebiten.Image{
-		ebiten.NewImageFromImage(starImage),
-		ebiten.NewImageFromImage(starImage2),
-		ebiten.NewImageFromImage(planet1),
-	}
-
-	stars := make([]*StarPosition, 0)
-	for i := 0; i < 50; i++ {
-		x := randomFloat(0, float64(LogicalWidth))
-		y := randomFloat(0, float64(ScreenHeight))
-		dx := 0.0
-		dy := randomFloat(0.6, 1.1)
-
-		image := images[rand.N(len(images))]
-
-		stars = append(stars, &StarPosition{x: x, y: y, dx: dx, dy: dy, Image: image})
-	}
-
-	numGalaxies := 1
-
-	var galaxies []*GalaxyPosition
-	for range numGalaxies {
-		galaxies = append(galaxies, &GalaxyPosition{
-			x:     randomFloat(0, float64(LogicalWidth)),
-			y:     randomFloat(0-float64(ScreenHeight), float64(ScreenHeight)),
-			tilt:  randomFloat(0.2, 0.8),
-			scale: randomFloat(0.2, 0.8),
-		})
-	}
-
-	earth := ebiten.NewImageFromImage(earthImage)
-	cloud1 := ebiten.NewImageFromImage(cloud1Image)
-	cloudA := ebiten.NewImageFromImage(cloudAImage)
-	planetAssets := []*PlanetAsset{
-		{
-			Image: earth,
-			Cloud: makeCloudImage(earth.Bounds(), cloud1, cloudA),
-		},
-		{
-			Image: ebiten.NewImageFromImage(marsImage),
-		},
-		{
-			Image: ebiten.NewImageFromImage(alienWorldImage),
-		},
-	}
-
-	return &Background{
-		Backdrop:     ebiten.NewImageFromImage(backdropImage),
-		BackdropName: backdropName,
-		Galaxy:       ebiten.NewImageFromImage(galaxyImage),
-		GalaxyShader: galaxyShader,
-		Galaxies:     galaxies,
-		PlanetShader: planetShader,
-		PlanetAssets: planetAssets,
-		Planet:       makePlanetPosition(planetAssets),
-		// Star: ebiten.NewImageFromImage(starImage),
-		Stars: stars,
-	}, nil
-}
-
-func (background *Background) Update() {
-	background.Planet.y += 0.38
-	if background.Planet.y > ScreenHeight+float64(background.PlanetAssets[0].Image.Bounds().Dy())*background.Planet.scale {
-		background.Planet = makePlanetPosition(background.PlanetAssets)
-		background.Planet.y = randomFloat(-float64(ScreenHeight)-250, -250)
-		background.Planet.scale = randomFloat(0.3, 0.8)
-		background.Planet.rotationSpeed = randomFloat(1, 4)
-	}
-
-	for _, galaxy := range background.Galaxies {
-		galaxy.y += 0.22
-		if galaxy.y > ScreenHeight+200 {
-			galaxy.x = randomFloat(0, float64(LogicalWidth))
-			galaxy.y = randomFloat(-float64(ScreenHeight)-200, -200)
-			galaxy.tilt = randomFloat(0.2, 0.8)
-			galaxy.scale = randomFloat(0.2, 0.8)
-		}
-	}
-
-	for _, star := range background.Stars {
-		star.y += star.dy
-		if star.y > ScreenHeight+50 {
-			star.y = -50
-		}
-	}
-}
-
-func (background *Background) Draw(screen *ebiten.Image, camera *Camera, counter uint64) {
-	options := &ebiten.DrawImageOptions{}
-	bounds := background.Backdrop.Bounds()
-	options.GeoM.Scale(float64(screen.Bounds().Dx())/float64(bounds.Dx()), float64(screen.Bounds().Dy())/float64(bounds.Dy()))
-	options.ColorScale.ScaleAlpha(0.3)
-	screen.DrawImage(background.Backdrop, options)
-
-	useTime := float32(counter) / 60.0
-	for _, galaxy := range background.Galaxies {
-		x, y := camera.ApplyParallax(galaxy.x, galaxy.y, GalaxyParallaxFactor)
-		DrawGalaxy(screen, background.GalaxyShader, background.Galaxy, useTime, float32(galaxy.tilt), float32(x), float32(y), float32(galaxy.scale))
-	}
-
-	planetX, planetY := camera.ApplyParallax(background.Planet.x, background.Planet.y, PlanetParallaxFactor)
-	planetTime := float64(counter) / background.Planet.rotationSpeed
-	DrawPlanet(screen, planetX, planetY, background.Planet.scale, background.Planet.axis, background.Planet.asset.Image, background.Planet.asset.Cloud, planetTime, background.PlanetShader)
-
-	for _, star := range background.Stars {
-		x, y := camera.ApplyParallax(star.x, star.y, StarParallaxFactor)
-		options := &ebiten.DrawImageOptions{}
-		options.ColorScale.ScaleAlpha(0.5)
-		options.GeoM.Translate(x, y)
-		screen.DrawImage(star.Image, options)
-	}
-}
-
-type ShaderManager struct {
-	RedShader         *ebiten.Shader
-	ShadowShader      *ebiten.Shader
-	EdgeShader        *ebiten.Shader
-	ExplosionShader   *ebiten.Shader
-	AlphaCircleShader *ebiten.Shader
-}
-
-func MakeShaderManager() (*ShaderManager, error) {
-	redShader, err := LoadRedShader()
-	if err != nil {
-		return nil, err
-	}
-
-	shadowShader, err := LoadShadowShader()
-	if err != nil {
-		return nil, err
-	}
-
-	edgeShader, err := LoadEdgeShader()
-	if err != nil {
-		return nil, err
-	}
-
-	explosionShader, err := LoadExplosionShader()
-	if err != nil {
-		return nil, err
-	}
-
-	alphaCircleShader, err := LoadAlphaCircleShader()
+	lightingShader, err := LoadLightingShader()
 	if err != nil {
 		return nil, err
 	}
@@ -703,11 +562,17 @@ func MakeShaderManager() (*ShaderManager, error) {
 		EdgeShader:        edgeShader,
 		ExplosionShader:   explosionShader,
 		AlphaCircleShader: alphaCircleShader,
+		BloomShader:       bloomShader,
+		AberrationShader:  aberrationShader,
+		VignetteShader:    vignetteShader,
+		CRTShader:         crtShader,
+		LightingShader:    lightingShader,
 	}, nil
 }
 
 const BombDelay = 60
 const RespawnBlinkDuration = 120
+const PlayerHurtTime = 20
 
 type Player struct {
 	x, y                 float64
@@ -729,20 +594,53 @@ type Player struct {
 	SoundShoot   chan bool
 	Bombs        int
 	BombCounter  int
+	BombCapacity int
+
+	// currency spent in the shop between levels
+	Credits uint64
+	// the part of Score that has already been converted into credits
+	BankedScore uint64
 
 	Level      int
 	Experience float64
 
+	Ship ShipKind
+
+	// arcade mode, see lives.go
+	Lives          int
+	Continues      int
+	NextExtraLife  uint64
+	ReviveProgress int
+
+	// extra energy capacity and regeneration collected from powerups
+	MaxEnergyBonus   float64
+	EnergyRegenBonus float64
+
 	PowerupEnergy int
 	RespawnBlink  int
+	// ticks left of the screen distortion after taking a hit
+	HurtTime int
+	// never takes damage, toggled from the console
+	God bool
+
+	// engine trail, see particles.go
+	Thruster ParticleEmitter
 }
 
 func (player *Player) IncreaseBombs() {
-	if player.Bombs < 5 {
+	if player.Bombs < player.BombCapacity {
 		player.Bombs += 1
 	}
 }
 
+// convert score earned since the last call into credits
+func (player *Player) BankScore() {
+	if player.Score > player.BankedScore {
+		player.Credits += (player.Score - player.BankedScore) / 10
+	}
+	player.BankedScore = player.Score
+}
+
 func experienceNeeded(level int) float64 {
 	return 45 * math.Pow(1.4, float64(level))
 }
@@ -756,24 +654,25 @@ func (player *Player) AddExperience(amount float64) {
 }
 
 func (player *Player) GetMaxEnergy() float64 {
-	return 100 * (1 + float64(player.Level)*0.2)
+	return 100*(1+float64(player.Level)*0.2) + player.MaxEnergyBonus
 }
 
 func (player *Player) GetEnergyIncreasePerFrame() float64 {
-	return 0.4 + float64(player.Level)*0.25
+	return 0.4 + float64(player.Level)*0.25 + player.EnergyRegenBonus + shipDefinition(player.Ship).EnergyRegen
 }
 
-/*
 func (player *Player) IncreaseMaxEnergy(amount float64) {
-    player.MaxEnergy += amount
-    player.EnergyIncreasePerFrame += 0.03
+	player.MaxEnergyBonus += amount
+	player.EnergyRegenBonus += 0.03
 }
-*/
 
 func (player *Player) Damage(amount float64) {
-	if player.RespawnBlink > 0 {
+	if player.IsInvulnerable() {
 		return
 	}
+	if amount > 0 {
+		player.HurtTime = PlayerHurtTime
+	}
 	player.Health -= amount
 	if player.Health < 0 {
 		player.Health = 0
@@ -785,7 +684,7 @@ func (player *Player) IsAlive() bool {
 }
 
 func (player *Player) IsInvulnerable() bool {
-	return player.RespawnBlink > 0
+	return player.RespawnBlink > 0 || player.God
 }
 
 func (player *Player) Bounds() image.Rectangle {
@@ -843,7 +742,8 @@ func (player *Player) EnableNextGun() {
 	}
 }
 
-func (player *Player) Move() {
+// worldHeight is how far down the player can go
+func (player *Player) Move(worldHeight float64) {
 	player.Counter += 1
 	if player.RespawnBlink > 0 {
 		player.RespawnBlink -= 1
@@ -878,8 +778,8 @@ func (player *Player) Move() {
 
 	if player.y < 0 {
 		player.y = 0
-	} else if player.y > ScreenHeight {
-		player.y = ScreenHeight
+	} else if player.y > worldHeight {
+		player.y = worldHeight
 	}
 
 	player.GunEnergy += player.GetEnergyIncreasePerFrame()
@@ -900,17 +800,20 @@ func (player *Player) Move() {
 	}
 }
 
-func (player *Player) Shoot(imageManager *ImageManager, soundManager *SoundManager) []*Bullet {
+// the guns fire from the nose of the ship, which turns to face the aim
+func (player *Player) Shoot(imageManager *ImageManager, soundManager *SoundManager, aim Aim) []*Bullet {
 
 	var bullets []*Bullet
 
+	noseX, noseY := aim.Apply(0, float64(player.pic.Bounds().Dy())/2)
 	for _, gun := range player.Guns {
 		if gun.IsEnabled() && (player.PowerupEnergy > 0 || gun.EnergyUsed() <= player.GunEnergy) {
-			more, err := gun.Shoot(imageManager, player.x, player.y-float64(player.pic.Bounds().Dy())/2)
+			more, err := gun.Shoot(imageManager, player.x+noseX, player.y+noseY, aim)
 			if err != nil {
 				log.Printf("Could not create bullets: %v", err)
 			} else {
 				if more != nil {
+					more = applyGunUpgrades(gun, more)
 					if player.PowerupEnergy == 0 {
 						player.GunEnergy -= gun.EnergyUsed()
 					}
@@ -919,7 +822,7 @@ func (player *Player) Shoot(imageManager *ImageManager, soundManager *SoundManag
 					select {
 					case <-player.SoundShoot:
 						// soundManager.Play(audioFiles.AudioShoot1)
-						gun.DoSound(soundManager)
+						gun.DoSound(soundManager, player.x, player.y)
 						go func() {
 							time.Sleep(10 * time.Millisecond)
 							player.SoundShoot <- true
@@ -943,11 +846,13 @@ var AlphaBlender ebiten.Blend = ebiten.Blend{
 	BlendOperationAlpha:         ebiten.BlendOperationAdd,
 }
 
-func (player *Player) DrawHud(screen *ebiten.Image, imageManager *ImageManager, font *text.GoTextFaceSource) {
+// the HUD is anchored to the top left corner of the HUD area
+// left and top are where the HUD starts, the second player of a local co-op game has theirs on the right
+func (player *Player) DrawHud(screen *ebiten.Image, imageManager *ImageManager, font *text.GoTextFaceSource, elementShapes bool, left float64, top float64) {
 	face := &text.GoTextFace{Source: font, Size: 15}
 
 	op := &text.DrawOptions{}
-	op.GeoM.Translate(2, 1)
+	op.GeoM.Translate(left+2, top+1)
 	op.ColorScale.ScaleWithColor(color.White)
 	text.Draw(screen, fmt.Sprintf("Score: %v", player.Score), face, op)
 
@@ -975,10 +880,13 @@ func (player *Player) DrawHud(screen *ebiten.Image, imageManager *ImageManager,
 
 	gunFace := &text.GoTextFace{Source: font, Size: 10}
 
-	var iconX float64 = 150
-	var iconY float64 = 3
+	iconX := left + 150
+	iconY := top + 3
 	for i, gun := range player.Guns {
 		gun.DrawIcon(screen, imageManager, iconX, iconY, gunFace)
+		if elementShapes {
+			drawGunBoxShapes(screen, gun, iconX, iconY)
+		}
 
 		op := &text.DrawOptions{}
 		op.GeoM.Translate(iconX+2, iconY+22)
@@ -998,17 +906,18 @@ func (player *Player) DrawHud(screen *ebiten.Image, imageManager *ImageManager,
 	if err != nil {
 		log.Printf("Could not load energy image: %v", err)
 	} else {
-		energyY := float64(130)
+		energyX := left + 5
+		energyY := top + 130
 
 		if player.PowerupEnergy > 0 {
-			vector.FillRect(screen, 5, float32(energyY), float32(energy.Bounds().Dx()), float32(energy.Bounds().Dy()), PowerupColor, true)
+			vector.FillRect(screen, float32(energyX), float32(energyY), float32(energy.Bounds().Dx()), float32(energy.Bounds().Dy()), PowerupColor, true)
 		} else {
 			options := &ebiten.DrawImageOptions{}
 			useHeight := int(player.GunEnergy / player.GetMaxEnergy() * float64(energy.Bounds().Dy()))
 
-			options.GeoM.Translate(5, energyY+float64(energy.Bounds().Dy())-float64(useHeight))
+			options.GeoM.Translate(energyX, energyY+float64(energy.Bounds().Dy())-float64(useHeight))
 
-			vector.FillRect(screen, 5, float32(energyY+1), float32(energy.Bounds().Dx()), float32(energy.Bounds().Dy()), premultiplyAlpha(color.RGBA{R: 0xaa, G: 0xe9, B: 0xfb, A: 180}), false)
+			vector.FillRect(screen, float32(energyX), float32(energyY+1), float32(energy.Bounds().Dx()), float32(energy.Bounds().Dy()), premultiplyAlpha(color.RGBA{R: 0xaa, G: 0xe9, B: 0xfb, A: 180}), false)
 
 			sub := energy.SubImage(image.Rect(0, energy.Bounds().Dy()-int(useHeight), energy.Bounds().Dx(), energy.Bounds().Dy())).(*ebiten.Image)
 			screen.DrawImage(sub, options)
@@ -1022,11 +931,12 @@ func (player *Player) DrawHud(screen *ebiten.Image, imageManager *ImageManager,
 		options := &ebiten.DrawImageOptions{}
 		useHeight := int(player.Health / player.MaxHealth * float64(health.Bounds().Dy()))
 
-		yVal := 420.0
+		xVal := left + 5
+		yVal := top + 420
 
-		options.GeoM.Translate(5, yVal+float64(health.Bounds().Dy())-float64(useHeight))
+		options.GeoM.Translate(xVal, yVal+float64(health.Bounds().Dy())-float64(useHeight))
 
-		vector.StrokeRect(screen, 5, float32(yVal), float32(health.Bounds().Dx()), float32(health.Bounds().Dy()), 1, premultiplyAlpha(color.RGBA{R: 0xaa, G: 0xe9, B: 0xfb, A: 200}), true)
+		vector.StrokeRect(screen, float32(xVal), float32(yVal), float32(health.Bounds().Dx()), float32(health.Bounds().Dy()), 1, premultiplyAlpha(color.RGBA{R: 0xaa, G: 0xe9, B: 0xfb, A: 200}), true)
 
 		sub := health.SubImage(image.Rect(0, health.Bounds().Dy()-int(useHeight), health.Bounds().Dx(), health.Bounds().Dy())).(*ebiten.Image)
 		screen.DrawImage(sub, options)
@@ -1121,7 +1031,7 @@ func saveHeapDump() {
 }
 
 func (player *Player) HandleKeys(game *Game, run *Run) error {
-	return player.ApplyInput(game, run, gatherLocalPlayerInput(), true)
+	return player.ApplyInput(game, run, gatherLocalPlayerInput(game.Controls), true)
 }
 
 func (player *Player) Respawn() {
@@ -1136,49 +1046,61 @@ func (player *Player) Respawn() {
 
 const JumpDuration = 50
 
-func MakePlayer(x, y float64, cheats bool) (*Player, error) {
-
-	playerImage, err := gameImages.LoadImage(gameImages.ImagePlayer)
-
+// change the ship sprite, which also changes the hitbox
+func (player *Player) SetShip(kind ShipKind) error {
+	ship := shipDefinition(kind)
+	shipImage, err := gameImages.LoadImage(ship.Image)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
+	player.Ship = ship.Kind
+	player.rawImage = shipImage
+	player.pic = ebiten.NewImageFromImage(shipImage)
+	return nil
+}
+
+func MakePlayer(x, y float64, cheats bool, loadout Loadout) (*Player, error) {
 	soundChan := make(chan bool, 2)
 	soundChan <- true
 
+	ship := shipDefinition(loadout.Ship)
+
 	player := &Player{
-		x:        x,
-		y:        y,
-		rawImage: playerImage,
-		pic:      ebiten.NewImageFromImage(playerImage),
+		x: x,
+		y: y,
 		// Gun: &BasicGun{},
 		// Gun: &DualBasicGun{},
-		GunEnergy: 100.0,
-		Health:    100.0,
-		MaxHealth: 100.0,
-		Bombs:     0,
-		Level:     0,
-		Guns: []Gun{
-			&BasicGun{enabled: true, level: 0, elementType: ElementPhysical},
-			// &DualBasicGun{enabled: false},
-			// &BeamGun{enabled: true, level: 0},
-			// &MissleGun{enabled: true, level: 0},
-			// &LightningGun{enabled: true, level: 7},
-		},
+		GunEnergy:    100.0,
+		Health:       ship.MaxHealth,
+		MaxHealth:    ship.MaxHealth,
+		Bombs:        0,
+		BombCapacity: 5,
+		Level:        0,
+		Guns:         loadout.Guns(),
 		// Gun: &BeamGun{},
 		Jump:       -50,
 		Score:      0,
 		SoundShoot: soundChan,
+		Thruster:   ParticleEmitter{Name: "thruster"},
+	}
+
+	err := player.SetShip(ship.Kind)
+	if err != nil {
+		return nil, err
 	}
 
 	if cheats {
 		player.Level = 9
-		player.Guns = append(player.Guns,
+		for _, gun := range []Gun{
 			&BeamGun{enabled: true, level: 5, elementType: ElementPlasma},
 			&LightningGun{enabled: true, level: 5, elementType: ElementLightning},
 			&MissleGun{enabled: true, level: 5, elementType: ElementPhysical},
-		)
+		} {
+			if !haveGun(player.Guns, gun) {
+				player.Guns = append(player.Guns, gun)
+			}
+		}
 	}
 
 	return player, nil
@@ -1301,18 +1223,37 @@ func (manager *ImageManager) LoadAnimation(name gameImages.Image) (*Animation, e
 }
 
 type SoundHandler struct {
-	Make     func() (*audio.Player, func(), bool)
-	MakeLoop func() (*audio.Player, error)
+	Make func() (*audio.Player, func(), bool)
+	// the sound looping between its loop points forever, used by the music mixer
+	Loop func() (io.Reader, error)
+	// the most plays of this sound at once, a new play past this stops the oldest one
+	PlayLimit int64
+	Bus       AudioBus
+	// from the audio manifest, multiplied with the volume of the bus
+	Volume float64
+	// decoded while it plays instead of up front, see makeStreamingSoundHandler
+	Streamed bool
+	// a player whose channels are scaled by left and right, see panning.go
+	MakePanned func(left float64, right float64) (*audio.Player, func(), bool)
 	// Players chan *audio.Player
 }
 
 type SoundManager struct {
-	Sounds        map[audioFiles.AudioName]*SoundHandler
-	Context       *audio.Context
-	SampleRate    int
-	Quit          context.Context
-	MusicVolume   float64
-	EffectsVolume float64
+	Sounds     map[audioFiles.AudioName]*SoundHandler
+	Library    *audioFiles.Library
+	Context    *audio.Context
+	SampleRate int
+	Quit       context.Context
+	Volumes    BusVolumes
+	// effects played at a position are panned relative to this, set by the game being played
+	Listener *Camera
+
+	// what is playing and how the music is ducked, see mixer.go
+	voices        []*mixerVoice
+	voiceSequence uint64
+	duck          float64
+	duckLevel     float64
+	duckHold      int
 }
 
 func clampVolume(volume float64) float64 {
@@ -1325,44 +1266,49 @@ func clampVolume(volume float64) float64 {
 	return volume
 }
 
-func (manager *SoundManager) SetMusicVolume(volume float64) {
-	manager.MusicVolume = clampVolume(volume)
-}
+func MakeSoundManager(quit context.Context, audioContext *audio.Context, library *audioFiles.Library, volumes BusVolumes) (*SoundManager, error) {
+	manager := SoundManager{
+		Sounds:     make(map[audioFiles.AudioName]*SoundHandler),
+		Library:    library,
+		SampleRate: 48000,
+		Context:    audioContext,
+		Quit:       quit,
+		duck:       1,
+	}
 
-func (manager *SoundManager) GetMusicVolume() float64 {
-	return manager.MusicVolume
-}
+	for _, bus := range audioBuses {
+		manager.SetVolume(bus, volumes[bus])
+	}
 
-func (manager *SoundManager) SetEffectsVolume(volume float64) {
-	manager.EffectsVolume = clampVolume(volume)
+	return &manager, manager.LoadAll()
 }
 
-func (manager *SoundManager) GetEffectsVolume() float64 {
-	return manager.EffectsVolume
-}
+// the sound is decoded the first time it is played, using the bus, limits and loop points from its
+// manifest entry
+func MakeSoundHandler(library *audioFiles.Library, name audioFiles.AudioName, context *audio.Context, sampleRate int) (*SoundHandler, error) {
+	definition, ok := library.Manifest[name]
+	if !ok {
+		return nil, fmt.Errorf("No such sound %v", name)
+	}
 
-func MakeSoundManager(quit context.Context, audioContext *audio.Context, musicVolume float64, effectsVolume float64) (*SoundManager, error) {
-	manager := SoundManager{
-		Sounds:        make(map[audioFiles.AudioName]*SoundHandler),
-		SampleRate:    48000,
-		Context:       audioContext,
-		Quit:          quit,
-		MusicVolume:   clampVolume(musicVolume),
-		EffectsVolume: clampVolume(effectsVolume),
+	bus, err := parseAudioBus(definition.Bus)
+	if err != nil {
+		return nil, err
 	}
 
-	return &manager, manager.LoadAll()
-}
+	playLimit := int64(definition.Limit)
+
+	if definition.Stream {
+		return makeStreamingSoundHandler(library, name, context, sampleRate, bus), nil
+	}
 
-// playLimit is the maximum number of concurrent plays of this sound
-func MakeSoundHandler(name audioFiles.AudioName, context *audio.Context, sampleRate int, playLimit int64) (*SoundHandler, error) {
 	var data []byte
 
 	var create sync.Once
 
 	load := func() {
 		log.Printf("Creating sound %v", name)
-		stream, err := audioFiles.LoadSound(name, sampleRate)
+		stream, err := library.Load(name, sampleRate)
 		if err != nil {
 			log.Printf("Error loading sound %v: %v", name, err)
 			return
@@ -1384,44 +1330,101 @@ func MakeSoundHandler(name audioFiles.AudioName, context *audio.Context, sampleR
 		},
 	}
 
-	var counter atomic.Int64
-
 	return &SoundHandler{
+		PlayLimit: playLimit,
+		Bus:       bus,
+		Volume:    definition.Volume,
+		// the mixer keeps the plays under the limit, see makeRoom
 		Make: func() (*audio.Player, func(), bool) {
-			// if over the limit then just do not play the sound
-			if counter.Load() < playLimit {
-				counter.Add(1)
+			player := pool.Get().(*audio.Player)
 
-				player := pool.Get().(*audio.Player)
+			finish := func() {
+				player.Rewind()
+				pool.Put(player)
+			}
 
-				finish := func() {
-					player.Rewind()
-					pool.Put(player)
-					counter.Add(-1)
-				}
+			return player, finish, true
+		},
+		Loop: func() (io.Reader, error) {
+			create.Do(load)
+			return definition.Loop(bytes.NewReader(data), int64(len(data)), sampleRate), nil
+		},
+		MakePanned: func(left float64, right float64) (*audio.Player, func(), bool) {
+			create.Do(load)
+			// panned players are not pooled since the panning is part of the stream
+			player, err := context.NewPlayer(MakePannedReader(bytes.NewReader(data), left, right))
+			if err != nil {
+				log.Printf("Unable to create panned player for %v: %v", name, err)
+				return nil, nil, false
+			}
 
-				return player, finish, true
+			finish := func() {
+				player.Close()
 			}
 
+			return player, finish, true
+		},
+	}, nil
+}
+
+// a handler for a long track that keeps only the compressed data in memory and decodes each play of it
+// as the play goes. plays are not pooled or limited here, the mixer limits them
+func makeStreamingSoundHandler(library *audioFiles.Library, name audioFiles.AudioName, context *audio.Context, sampleRate int, bus AudioBus) *SoundHandler {
+	definition := library.Manifest[name]
+
+	play := func(wrap func(io.Reader) io.Reader) (*audio.Player, func(), bool) {
+		stream, err := library.Load(name, sampleRate)
+		if err != nil {
+			log.Printf("Error loading sound %v: %v", name, err)
+			return nil, nil, false
+		}
+
+		player, err := context.NewPlayer(wrap(stream))
+		if err != nil {
+			log.Printf("Unable to create player for %v: %v", name, err)
 			return nil, nil, false
+		}
+
+		return player, func() {
+			player.Close()
+		}, true
+	}
+
+	return &SoundHandler{
+		PlayLimit: int64(definition.Limit),
+		Bus:       bus,
+		Volume:    definition.Volume,
+		Streamed:  true,
+		Make: func() (*audio.Player, func(), bool) {
+			return play(func(stream io.Reader) io.Reader {
+				return stream
+			})
 		},
-		MakeLoop: func() (*audio.Player, error) {
-			create.Do(load)
-			return context.NewPlayer(audio.NewInfiniteLoop(bytes.NewReader(data), int64(len(data))+1000))
+		MakePanned: func(left float64, right float64) (*audio.Player, func(), bool) {
+			return play(func(stream io.Reader) io.Reader {
+				return MakePannedReader(stream, left, right)
+			})
 		},
-	}, nil
+		Loop: func() (io.Reader, error) {
+			return library.LoadLoop(name, sampleRate)
+		},
+	}
 }
 
 func (manager *SoundManager) LoadAll() error {
 
-	sounds := audioFiles.AllSounds
-	for _, sound := range sounds {
-		handler, err := MakeSoundHandler(sound, manager.Context, manager.SampleRate, 10)
+	for _, sound := range manager.Library.Names() {
+		handler, err := MakeSoundHandler(manager.Library, sound, manager.Context, manager.SampleRate)
 		if err != nil {
 			return fmt.Errorf("Error loading %v: %v", sound, err)
 		}
 		manager.Sounds[sound] = handler
 
+		// warming up a streamed sound would decode it for nothing
+		if handler.Streamed {
+			continue
+		}
+
 		go func() {
 			_, f, ok := handler.Make()
 			if ok {
@@ -1437,50 +1440,29 @@ func (manager *SoundManager) PlayEffect(name audioFiles.AudioName) {
 	if handler, ok := manager.Sounds[name]; ok {
 		player, finish, canPlay := handler.Make()
 		if canPlay {
-			player.SetVolume(manager.GetEffectsVolume() / 100.0)
-			player.Play()
-
-			go func() {
-				for {
-					if player.IsPlaying() {
-						time.Sleep(100 * time.Millisecond)
-					} else {
-						finish()
-						break
-					}
-				}
-			}()
+			manager.playVoice(name, handler, player, finish, 1)
 		}
 	}
 }
 
-func (manager *SoundManager) PlayMusic(name audioFiles.AudioName, stop context.Context) {
-	if handler, ok := manager.Sounds[name]; ok {
-		go func() {
-			player, err := handler.MakeLoop()
-			if err != nil {
-				log.Printf("Failed to play audio loop %v: %v", name, err)
-			} else {
-				player.SetVolume(manager.GetMusicVolume() / 100.0)
-				go func() {
-					for {
-						select {
-						case <-stop.Done():
-							player.Close()
-							return
-						case <-time.After(100 * time.Millisecond):
-							if !player.IsPlaying() {
-								return
-							}
-
-							player.SetVolume(manager.GetMusicVolume() / 100.0)
-						}
-					}
-				}()
+// play an effect that happens at x, y in the world, panned and attenuated relative to the listener
+func (manager *SoundManager) PlayEffectAt(name audioFiles.AudioName, x float64, y float64) {
+	if manager.Listener == nil {
+		manager.PlayEffect(name)
+		return
+	}
 
-				player.Play()
-			}
-		}()
+	left, right := spatialGains(manager.Listener, x, y)
+	if left < 0.01 && right < 0.01 {
+		return
+	}
+
+	if handler, ok := manager.Sounds[name]; ok {
+		gain := math.Max(left, right)
+		player, finish, canPlay := handler.MakePanned(left, right)
+		if canPlay {
+			manager.playVoice(name, handler, player, finish, gain)
+		}
 	}
 }
 
@@ -1517,6 +1499,7 @@ type Game struct {
 	Enemies       []Enemy
 	Powerups      []Powerup
 	Explosions    []Explosion
+	Particles     *ParticleSystem
 	Bombs         []*Bomb
 	ShaderManager *ShaderManager
 	ImageManager  *ImageManager
@@ -1536,7 +1519,19 @@ type Game struct {
 	DoEnd sync.Once
 	End   atomic.Bool
 
+	// starts the music the first time the level updates
 	MusicPlayer sync.Once
+	// nil if there is no sound, see music.go
+	Music *MusicDirector
+
+	// limited lives and continues, see lives.go
+	Arcade          bool
+	GameOverCounter int
+
+	// decides which powerups enemies drop, see drops.go
+	DropRand *rand.Rand
+	// value of Counter when the last powerup was dropped
+	LastDrop uint64
 
 	Quit   context.Context
 	Cancel context.CancelFunc
@@ -1545,6 +1540,40 @@ type Game struct {
 	Counter uint64
 	ShowFPS bool
 
+	// hitboxes, object counts and timings, see debug.go
+	Debug *DebugOverlay
+	// damage numbers and the freeze and flash on heavy hits, see hitfeedback.go
+	HitFeedback   *HitFeedbackSettings
+	DamageNumbers []*DamageNumber
+	// ticks left of the world being frozen
+	HitStop  int
+	HitFlash int
+
+	// point lights on the background, enemies and asteroids, see lighting.go
+	Lighting *LightingSystem
+	// colorblind palette and shape indicators, see accessibility.go
+	Accessibility *AccessibilitySettings
+	// shared with the run, see display.go
+	Display *DisplaySettings
+	// shared with the run, see gamepad.go and controls.go
+	Gamepads *GamepadManager
+	Controls *Controls
+	// the virtual stick and buttons, see touch.go
+	Touch *TouchControls
+	// aiming at the cursor, see mouse.go
+	Mouse *MouseInput
+	// both players on this machine, see coop.go
+	Coop *LocalCoop
+
+	// developer commands, see console.go
+	Console *Console
+	// 1 is normal speed, changed from the console
+	TimeScale float64
+	// fraction of a tick left over from the last frame when TimeScale is not a whole number
+	tickCarry float64
+	// the input of the local players, read once per frame by ReadInput and handed to the ticks by takeInput
+	frameInput [MaxLocalPlayers]playerInputState
+
 	// time when the last screenshot was taken
 	LastScreenshot time.Time
 	Camera         *Camera
@@ -1572,10 +1601,18 @@ func (game *Game) Close() {
 	game.Cancel()
 }
 
+// number of kinds of normal enemy that Game.MakeEnemy knows about
+const EnemyKinds = 9
+
 func (game *Game) MakeEnemy(x float64, y float64, kind int, move Movement) error {
 	var enemy Enemy
 	var err error
 
+	// a move that is already a behavior was chosen by the caller, such as a carrier's minions
+	if _, ok := move.(Behavior); !ok {
+		move = makeBehavior(enemyBehaviors[kind], move)
+	}
+
 	switch kind {
 	case 0:
 		pic, raw, err := game.ImageManager.LoadImage(gameImages.ImageEnemy1)
@@ -1640,6 +1677,9 @@ func (game *Game) MakeEnemy(x float64, y float64, kind int, move Movement) error
 
 	if normal, ok := enemy.(*NormalEnemy); ok {
 		normal.Kind = fmt.Sprintf("enemy-%d", kind)
+		if _, ok := move.(*CarrierBehavior); ok {
+			normal.Shield = 10 * game.Difficulty
+		}
 	}
 
 	game.AddEnemy(enemy)
@@ -1665,8 +1705,9 @@ func (game *Game) MakeEnemies(count int) error {
 		}
 
 		x := randomFloat(50, LogicalWidth-50)
-		y := float64(-200)
-		kind := rand.N(9)
+		// just above the view, so the enemies fly in wherever the camera is in a tall level
+		y := game.Camera.y - 200
+		kind := rand.N(EnemyKinds)
 
 		move := makeMovement()
 
@@ -1697,18 +1738,18 @@ func (game *Game) BigShake() {
 	game.ShakeTime = 20
 }
 
-func (game *Game) DrawFinalScreen(screen ebiten.FinalScreen, offscreen *ebiten.Image, geoM ebiten.GeoM) {
+func (game *Game) DrawFinalScreen(screen ebiten.FinalScreen, offscreen *ebiten.Image, geoM ebiten.GeoM, filter ebiten.Filter, postProcessor *PostProcessor) {
 	if game.ShakeTime > 0 {
 		geoM.Translate(randomFloat(-4, 4), randomFloat(-4, 4))
 	}
 
-	screen.DrawImage(offscreen, &ebiten.DrawImageOptions{
-		GeoM: geoM,
+	postProcessor.Draw(screen, offscreen, geoM, filter, PostProcessFrame{
+		Hurt: float64(game.Player.HurtTime) / PlayerHurtTime,
 	})
 }
 
 func (game *Game) TakeScreenshot() {
-	output := ebiten.NewImage(ScreenWidth, ScreenHeight)
+	output := ebiten.NewImage(game.Display.Width, ScreenHeight)
 	game.Draw(output)
 	filename := fmt.Sprintf("shooter-%s.png", time.Now().Format("2006-01-02-150405"))
 	file, err := os.Create(filename)
@@ -1719,6 +1760,24 @@ func (game *Game) TakeScreenshot() {
 	}
 }
 
+// called once per frame before the ticks of the frame run, so a key pressed this frame counts once no
+// matter how many ticks the time scale runs
+func (game *Game) ReadInput() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF1) && time.Since(game.LastScreenshot) > 1*time.Second {
+		game.TakeScreenshot()
+		game.LastScreenshot = time.Now()
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		game.Debug.Toggle()
+	}
+
+	game.frameInput[0] = keepPresses(game.frameInput[0], game.resolvePlayerInput())
+	if game.Coop != nil {
+		game.frameInput[1] = keepPresses(game.frameInput[1], game.resolvePartnerInput())
+	}
+}
+
 func (game *Game) Update(run *Run) error {
 
 	// print fps every two seconds
@@ -1726,17 +1785,23 @@ func (game *Game) Update(run *Run) error {
 		log.Printf("FPS: %.2f", ebiten.ActualFPS())
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyF1) && time.Since(game.LastScreenshot) > 1*time.Second {
-		game.TakeScreenshot()
-		game.LastScreenshot = time.Now()
+	// freeze everything for a moment after a heavy hit
+	if game.HitStop > 0 {
+		game.HitStop -= 1
+		return nil
 	}
 
+	game.Lighting.Update()
+
 	game.UpdateCounters()
 
 	game.Counter += 1
 	if game.ShakeTime > 0 {
 		game.ShakeTime -= 1
 	}
+	if game.Player.HurtTime > 0 {
+		game.Player.HurtTime -= 1
+	}
 
 	makeAnimatedExplosion := func(x float64, y float64, name gameImages.Image) {
 		animation, err := game.ImageManager.LoadAnimation(name)
@@ -1751,21 +1816,29 @@ func (game *Game) Update(run *Run) error {
 	explodeEnemy := func(enemy Enemy) {
 		x, y := enemy.Coords()
 		makeAnimatedExplosion(x, y, gameImages.ImageExplosion2)
+		game.Particles.Burst("enemy-explosion", x, y)
+		game.Particles.Burst("enemy-debris", x, y)
+		game.dropEnemyLoot(enemy)
 	}
 
 	explodeAsteroid := func(asteroid *Asteroid) {
 		makeAnimatedExplosion(asteroid.x, asteroid.y, gameImages.ImageExplosion3)
+		game.Particles.Burst("asteroid-fragments", asteroid.x, asteroid.y)
 	}
 
 	respawnPlayer := func(player *Player) {
-		game.SoundManager.PlayEffect(audioFiles.AudioExplosion3)
+		game.SoundManager.PlayEffectAt(audioFiles.AudioExplosion3, player.x, player.y)
+		game.SoundManager.Duck(DuckBigExplosion)
 		makeAnimatedExplosion(player.x, player.y, gameImages.ImageExplosion2)
-		player.Respawn()
+		game.Particles.Burst("enemy-explosion", player.x, player.y)
+		game.Particles.Burst("enemy-debris", player.x, player.y)
+		game.handlePlayerDeath(player)
 	}
 
 	if game.End.Load() {
 		game.DoEnd.Do(func() {
 			game.FadeOut = GameFadeOut * 3
+			game.Music.Victory()
 		})
 	}
 
@@ -1786,25 +1859,39 @@ func (game *Game) Update(run *Run) error {
 	}
 
 	game.MusicPlayer.Do(func() {
-		choices := []audioFiles.AudioName{audioFiles.AudioChillSong, audioFiles.AudioStellarPulseSong}
-		use := choices[rand.N(len(choices))]
-		game.SoundManager.PlayMusic(use, game.Quit)
+		if game.SoundManager != nil && game.SoundManager.Context != nil {
+			game.Music = MakeMusicDirector(game.SoundManager, game.Quit)
+		}
 	})
+	game.updateMusic()
+
+	timer := game.Debug.Timer(DebugUpdate)
+	defer timer.Finish()
 
-	game.Background.Update()
+	game.Background.Update(game.Camera)
+	game.Camera.Scroll()
 
+	// taken even while dead so presses do not pile up until the respawn
+	input := game.takeInput(0)
 	if game.Player.IsAlive() {
-		input := game.resolvePlayerInput()
 		err := game.Player.ApplyInput(game, run, input, !game.isSlave())
 		if err != nil {
 			return err
 		}
 
-		game.Player.Move()
+		game.Player.Move(game.Camera.WorldHeight())
+		game.Camera.Contain(game.Player)
 		game.maybeSendPlayerState()
-		game.Camera.TrackPlayer(game.Player)
+		if game.Coop == nil {
+			game.Camera.TrackPlayer(game.Player)
+		}
 	}
 
+	if err := game.updateLocalCoop(run); err != nil {
+		return err
+	}
+	timer.Lap("player")
+
 	for _, asteroid := range game.Asteroids {
 		asteroid.Move()
 		if !game.Player.IsInvulnerable() && asteroid.Collide(game.Player, game.ImageManager) {
@@ -1818,7 +1905,7 @@ func (game *Game) Update(run *Run) error {
 			if !asteroid.IsAlive() {
 				game.Shake()
 
-				game.SoundManager.PlayEffect(audioFiles.AudioExplosion3)
+				game.SoundManager.PlayEffectAt(audioFiles.AudioExplosion3, asteroid.x, asteroid.y)
 				explodeAsteroid(asteroid)
 			}
 		}
@@ -1832,12 +1919,14 @@ func (game *Game) Update(run *Run) error {
 
 			if !asteroid.IsAlive() {
 				game.Shake()
-				game.SoundManager.PlayEffect(audioFiles.AudioExplosion3)
+				game.SoundManager.PlayEffectAt(audioFiles.AudioExplosion3, asteroid.x, asteroid.y)
 				explodeAsteroid(asteroid)
 			}
 		}
 	}
 
+	timer.Lap("asteroids")
+
 	var powerupOut []Powerup
 	for _, powerup := range game.Powerups {
 		powerup.Move()
@@ -1851,11 +1940,12 @@ func (game *Game) Update(run *Run) error {
 			powerup.Activate(game.RemotePlayer, game.SoundManager)
 		}
 
-		if powerup.IsAlive() {
+		if _, y := powerup.Coords(); powerup.IsAlive() && game.aboveBottom(y, 20) {
 			powerupOut = append(powerupOut, powerup)
 		}
 	}
 	game.Powerups = powerupOut
+	timer.Lap("powerups")
 
 	for _, enemy := range game.Enemies {
 		targetPlayer := game.pickEnemyTarget()
@@ -1864,15 +1954,28 @@ func (game *Game) Update(run *Run) error {
 			game.AddEnemyBullets(bullets...)
 		}
 
+		if normal, ok := enemy.(*NormalEnemy); ok {
+			for _, spawn := range normal.TakeSpawns() {
+				if game.isSlave() {
+					continue
+				}
+				err := game.MakeEnemy(spawn.x, spawn.y, carrierMinionKind, &KamikazeBehavior{velocityY: 2})
+				if err != nil {
+					log.Printf("Unable to launch minion: %v", err)
+				}
+			}
+		}
+
 		if game.Player.IsAlive() && !game.Player.IsInvulnerable() {
 			collideX, collideY, isCollide := enemy.CollidePlayer(game.Player)
 
 			if isCollide {
 				game.GetCounter("player hit enemy", 30).Do(func() {
-					game.SoundManager.PlayEffect(audioFiles.AudioHit1)
+					game.SoundManager.PlayEffectAt(audioFiles.AudioHit1, collideX, collideY)
 				})
 
 				makeAnimatedExplosion(collideX, collideY, gameImages.ImageHit2)
+				game.Particles.Burst("player-hit", collideX, collideY)
 
 				enemy.Damage(2)
 				game.Player.Damage(2)
@@ -1883,7 +1986,7 @@ func (game *Game) Update(run *Run) error {
 				if !enemy.IsAlive() {
 					game.Player.Score += 1
 					game.Player.Kills += 1
-					game.SoundManager.PlayEffect(audioFiles.AudioExplosion3)
+					game.SoundManager.PlayEffectAt(audioFiles.AudioExplosion3, collideX, collideY)
 
 					explodeEnemy(enemy)
 				}
@@ -1894,10 +1997,11 @@ func (game *Game) Update(run *Run) error {
 			collideX, collideY, isCollide := enemy.CollidePlayer(game.RemotePlayer)
 			if isCollide {
 				game.GetCounter("slave hit enemy", 30).Do(func() {
-					game.SoundManager.PlayEffect(audioFiles.AudioHit1)
+					game.SoundManager.PlayEffectAt(audioFiles.AudioHit1, collideX, collideY)
 				})
 
 				makeAnimatedExplosion(collideX, collideY, gameImages.ImageHit2)
+				game.Particles.Burst("player-hit", collideX, collideY)
 				enemy.Damage(2)
 				game.RemotePlayer.Damage(2)
 				if !game.RemotePlayer.IsAlive() {
@@ -1907,13 +2011,15 @@ func (game *Game) Update(run *Run) error {
 				if !enemy.IsAlive() {
 					game.RemotePlayer.Score += 1
 					game.RemotePlayer.Kills += 1
-					game.SoundManager.PlayEffect(audioFiles.AudioExplosion3)
+					game.SoundManager.PlayEffectAt(audioFiles.AudioExplosion3, collideX, collideY)
 					explodeEnemy(enemy)
 				}
 			}
 		}
 	}
 
+	timer.Lap("enemies")
+
 	explosionOut := make([]Explosion, 0)
 	for _, explosion := range game.Explosions {
 		explosion.Move()
@@ -1923,11 +2029,24 @@ func (game *Game) Update(run *Run) error {
 	}
 	game.Explosions = explosionOut
 
+	for _, player := range game.players() {
+		if player.IsAlive() {
+			player.Thruster.Emit(game.Particles, player.x, player.y+float64(player.pic.Bounds().Dy())/2)
+		}
+	}
+	game.Particles.Update()
+	game.updateDamageNumbers()
+	timer.Lap("effects")
+
 	// run bullet physics at 3x
 	for i := 0; i < 3; i++ {
 		var outBullets []*Bullet
 		for _, bullet := range game.Bullets {
 			bullet.Move()
+			// a bullet that leaves the top or bottom of the view would hit enemies before anyone sees them
+			if !game.inView(bullet.y, BulletViewMargin) || !game.inWorld(bullet.x, bullet.y, 10) {
+				continue
+			}
 
 			for _, asteroid := range game.Asteroids {
 				if asteroid.IsAlive() && asteroid.Collision(bullet.x, bullet.y, game.ImageManager) {
@@ -1935,7 +2054,7 @@ func (game *Game) Update(run *Run) error {
 					game.addBulletScore(bullet, 1)
 					bullet.Damage(1)
 
-					game.SoundManager.PlayEffect(audioFiles.AudioHit1)
+					game.SoundManager.PlayEffectAt(audioFiles.AudioHit1, bullet.x, bullet.y)
 
 					animation, err := game.ImageManager.LoadAnimation(gameImages.ImageHit)
 					if err != nil {
@@ -1943,14 +2062,12 @@ func (game *Game) Update(run *Run) error {
 					} else {
 						game.Explosions = append(game.Explosions, MakeAnimatedExplosion(bullet.x, bullet.y, animation))
 					}
+					game.Particles.Burst("bullet-impact", bullet.x, bullet.y)
 
 					if !asteroid.IsAlive() {
 						game.Shake()
-						game.SoundManager.PlayEffect(audioFiles.AudioExplosion3)
-						animation, err := game.ImageManager.LoadAnimation(gameImages.ImageExplosion3)
-						if err == nil {
-							game.Explosions = append(game.Explosions, MakeAnimatedExplosion(asteroid.x, asteroid.y, animation))
-						}
+						game.SoundManager.PlayEffectAt(audioFiles.AudioExplosion3, asteroid.x, asteroid.y)
+						explodeAsteroid(asteroid)
 						break
 					}
 				}
@@ -1964,23 +2081,17 @@ func (game *Game) Update(run *Run) error {
 							bullet.Gun.IncreaseExperience(bullet.Strength)
 						}
 						bullet.Damage(1)
-						enemy.Damage(bullet.Strength)
+						game.showHit(bullet.x, bullet.y, enemy.DescribeHit(bullet))
+						enemy.Hit(bullet)
 						if !enemy.IsAlive() {
 							game.Shake()
 							game.addBulletKillRewards(bullet, enemy)
-							game.SoundManager.PlayEffect(audioFiles.AudioExplosion3)
+							game.SoundManager.PlayEffectAt(audioFiles.AudioExplosion3, bullet.x, bullet.y)
 
-							// create a powerup every X kills
 							explodeEnemy(enemy)
-
-							// create a powerup where the enemy died every once in a while
-							if rand.N(20) == 0 {
-								x, y := enemy.Coords()
-								game.AddPowerup(MakeRandomPowerup(x, y))
-							}
 						}
 
-						game.SoundManager.PlayEffect(audioFiles.AudioHit1)
+						game.SoundManager.PlayEffectAt(audioFiles.AudioHit1, bullet.x, bullet.y)
 
 						animation, err := game.ImageManager.LoadAnimation(gameImages.ImageHit)
 						if err != nil {
@@ -1988,6 +2099,7 @@ func (game *Game) Update(run *Run) error {
 						} else {
 							game.Explosions = append(game.Explosions, MakeAnimatedExplosion(bullet.x, bullet.y, animation))
 						}
+						game.Particles.Burst("bullet-impact", bullet.x, bullet.y)
 						break
 					}
 				}
@@ -2007,9 +2119,12 @@ func (game *Game) Update(run *Run) error {
 		var outEnemyBullets []*Bullet
 		for _, bullet := range game.EnemyBullets {
 			bullet.Move()
+			if !game.inView(bullet.y, BulletViewMargin) || !game.inWorld(bullet.x, bullet.y, 10) {
+				continue
+			}
 
 			if game.Player.IsAlive() && !game.Player.IsInvulnerable() && game.Player.Collide(bullet.x, bullet.y) {
-				game.SoundManager.PlayEffect(audioFiles.AudioHit2)
+				game.SoundManager.PlayEffectAt(audioFiles.AudioHit2, bullet.x, bullet.y)
 
 				game.Player.Damage(bullet.Strength)
 				if !game.Player.IsAlive() {
@@ -2022,12 +2137,13 @@ func (game *Game) Update(run *Run) error {
 				} else {
 					log.Printf("Could not load explosion sheet: %v", err)
 				}
+				game.Particles.Burst("player-hit", bullet.x, bullet.y)
 
 				bullet.Damage(1)
 			}
 
 			if bullet.IsAlive() && game.isMaster() && game.RemotePlayer != nil && game.RemotePlayer.IsAlive() && !game.RemotePlayer.IsInvulnerable() && game.RemotePlayer.Collide(bullet.x, bullet.y) {
-				game.SoundManager.PlayEffect(audioFiles.AudioHit2)
+				game.SoundManager.PlayEffectAt(audioFiles.AudioHit2, bullet.x, bullet.y)
 				game.RemotePlayer.Damage(bullet.Strength)
 				if !game.RemotePlayer.IsAlive() {
 					respawnPlayer(game.RemotePlayer)
@@ -2039,6 +2155,7 @@ func (game *Game) Update(run *Run) error {
 				} else {
 					log.Printf("Could not load explosion sheet: %v", err)
 				}
+				game.Particles.Burst("player-hit", bullet.x, bullet.y)
 
 				bullet.Damage(1)
 			}
@@ -2050,10 +2167,14 @@ func (game *Game) Update(run *Run) error {
 		game.EnemyBullets = outEnemyBullets
 	}
 
+	timer.Lap("bullets")
+
 	bombExplode := func(bomb *Bomb) {
 		game.WhiteFlash = GameWhiteFlash
 		game.BigShake()
-		game.SoundManager.PlayEffect(audioFiles.AudioExplosion3)
+		game.SoundManager.PlayEffectAt(audioFiles.AudioExplosion3, bomb.x, bomb.y)
+		game.SoundManager.Duck(DuckBigExplosion)
+		game.Particles.Burst("bomb-shockwave", bomb.x, bomb.y)
 
 		var bombDamage float64 = 50
 
@@ -2086,10 +2207,12 @@ func (game *Game) Update(run *Run) error {
 		}
 	}
 	game.Bombs = bombOut
+	timer.Lap("bombs")
 
 	enemyOut := make([]Enemy, 0)
 	for _, enemy := range game.Enemies {
-		if enemy.IsAlive() {
+		// enemies arrive from above the world, so they are only gone once they leave it some other way
+		if x, y := enemy.Coords(); enemy.IsAlive() && (y < 0 || game.inWorld(x, y, 100)) {
 			enemyOut = append(enemyOut, enemy)
 		}
 	}
@@ -2097,66 +2220,73 @@ func (game *Game) Update(run *Run) error {
 
 	asteroidOut := make([]*Asteroid, 0)
 	for _, asteroid := range game.Asteroids {
-		if asteroid.IsAlive() {
+		if asteroid.IsAlive() && game.aboveBottom(asteroid.y, 100) {
 			asteroidOut = append(asteroidOut, asteroid)
 		}
 	}
 	game.Asteroids = asteroidOut
 
-	if rand.N(6000) == 0 {
-		if !game.isSlave() {
-			game.AddPowerup(MakeRandomPowerup(randomFloat(10, LogicalWidth-10), -20))
-		}
-	}
-
 	if !game.BossMode && !game.End.Load() {
 		if !game.isSlave() && (len(game.Enemies) == 0 || (len(game.Enemies) < 10 && rand.N(100) == 0)) {
 			game.MakeEnemies(1)
 		}
 
 		if !game.isSlave() && len(game.Asteroids) < 15 && rand.N(200) == 0 {
-			game.AddAsteroid(MakeAsteroid(randomFloat(-50, LogicalWidth+50), -50))
+			game.AddAsteroid(MakeAsteroid(randomFloat(-50, LogicalWidth+50), game.Camera.y-50))
 		}
 
 		// create the boss after 2 minutes
 		const bossTime = 60 * 120
 		// const bossTime = 60 * 1
 		if debugForceBoss || (game.Counter > bossTime && rand.N(1000) == 0) {
-			game.BossMode = true
-			game.DoBoss.Do(func() {
-				log.Printf("Created boss!")
-				boss1Pic, rawImage, err := game.ImageManager.LoadImage(gameImages.ImageBoss1)
-				if err != nil {
-					log.Printf("Unable to load boss: %v", err)
-				} else {
-					boss, err := MakeBoss1(LogicalWidth/2, -150, rawImage, boss1Pic, game.Difficulty)
-					if err != nil {
-						log.Printf("Unable to make boss: %v", err)
-					} else {
-						game.AddEnemy(boss)
-
-						go func() {
-							for {
-								select {
-								case <-game.Quit.Done():
-									return
-								case <-boss.Dead():
-									game.End.Store(true)
-									return
-								}
-							}
-						}()
-
-					}
-				}
-			})
+			game.SpawnBoss()
 		}
 
 	}
 
 	game.maybeSendSnapshot()
+	timer.Lap("spawning")
 
-	return nil
+	return game.updateLives()
+}
+
+// bring in the boss, which ends the level when it dies. only the first call does anything
+func (game *Game) SpawnBoss() {
+	game.BossMode = true
+	game.DoBoss.Do(func() {
+		log.Printf("Created boss!")
+		boss1Pic, rawImage, err := game.ImageManager.LoadImage(gameImages.ImageBoss1)
+		if err != nil {
+			log.Printf("Unable to load boss: %v", err)
+			return
+		}
+
+		boss, err := MakeBoss1(LogicalWidth/2, game.Camera.y-Boss1Entry, rawImage, boss1Pic, game.Difficulty)
+		if err != nil {
+			log.Printf("Unable to make boss: %v", err)
+			return
+		}
+
+		game.AddEnemy(boss)
+
+		// the boss announces itself over the whole screen rather than from where it enters
+		if game.SoundManager != nil {
+			game.SoundManager.PlayEffect(audioFiles.AudioExplosion1)
+			game.SoundManager.Duck(DuckBossRoar)
+		}
+
+		go func() {
+			for {
+				select {
+				case <-game.Quit.Done():
+					return
+				case <-boss.Dead():
+					game.End.Store(true)
+					return
+				}
+			}
+		}()
+	})
 }
 
 // draw a big orange circle that fades out towards the edge of the circle
@@ -2185,7 +2315,74 @@ func (game *Game) TestAlphaCircle(screen *ebiten.Image, x float64, y float64) {
 }
 
 func (game *Game) Draw(screen *ebiten.Image) {
-	game.Background.Draw(screen, game.Camera, game.Counter)
+	timer := game.Debug.Timer(DebugDraw)
+
+	if game.Coop != nil && game.Coop.Split {
+		game.drawSplit(screen, timer)
+	} else {
+		game.drawWorld(screen, game.Camera, timer)
+	}
+
+	area := game.Display.HudArea(screen.Bounds())
+	if game.Coop != nil {
+		game.drawCoopHud(screen, area)
+	} else if game.Player.IsAlive() || game.Arcade {
+		game.Player.DrawHud(screen, game.ImageManager, game.Font, game.elementShapes(), area.Left, area.Top)
+		game.drawLives(screen, game.Player, area.Right-170, area.Top+26)
+	}
+
+	if game.Multiplayer != nil && game.Multiplayer.Peer != nil && game.Multiplayer.Peer.HasLatency() {
+		face := &text.GoTextFace{Source: game.Font, Size: 15}
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(area.Right-170, area.Top+4)
+		latencyMS := game.Multiplayer.Peer.LatencyMS()
+		latencyColor := color.RGBA{R: 0xff, G: 0, B: 0, A: 0xff}
+		if latencyMS < 20 {
+			latencyColor = color.RGBA{R: 0, G: 0xff, B: 0, A: 0xff}
+		} else if latencyMS < 100 {
+			latencyColor = color.RGBA{R: 0xff, G: 0xff, B: 0, A: 0xff}
+		}
+		op.ColorScale.ScaleWithColor(latencyColor)
+		text.Draw(screen, fmt.Sprintf("Peer: %dms", latencyMS), face, op)
+	}
+
+	width := float32(screen.Bounds().Dx())
+	if game.WhiteFlash > 0 {
+		flash := premultiplyAlpha(color.RGBA{R: 255, G: 255, B: 255, A: uint8(game.WhiteFlash * 255 / GameWhiteFlash)})
+		vector.FillRect(screen, 0, 0, width, ScreenHeight, &flash, true)
+	}
+
+	if game.HitFlash > 0 {
+		flash := premultiplyAlpha(color.RGBA{R: 255, G: 255, B: 255, A: uint8(game.HitFlash * 60 / HitFlashTicks)})
+		vector.FillRect(screen, 0, 0, width, ScreenHeight, &flash, true)
+	}
+
+	if game.FadeIn < GameFadeIn {
+		vector.FillRect(screen, 0, 0, width, ScreenHeight, &color.RGBA{R: 0, G: 0, B: 0, A: uint8(255 - game.FadeIn*255/GameFadeIn)}, true)
+	}
+
+	if game.FadeOut > 0 && game.FadeOut <= GameFadeOut {
+		vector.FillRect(screen, 0, 0, width, ScreenHeight, &color.RGBA{R: 0, G: 0, B: 0, A: uint8(255 - game.FadeOut*255/GameFadeOut)}, true)
+	}
+
+	timer.Lap("hud")
+	timer.Finish()
+
+	game.drawDebugOverlay(screen)
+
+	// vector.StrokeRect(screen, 0, 0, 100, 100, 3, &color.RGBA{R: 255, G: 0, B: 0, A: 128}, true)
+	// vector.FillRect(screen, 0, 0, 100, 100, &color.RGBA{R: 255, G: 0, B: 0, A: 64}, true)
+
+}
+
+// everything that is drawn through a camera, the game's own or one half of a split screen
+func (game *Game) drawWorld(screen *ebiten.Image, camera *Camera, timer *DebugTimer) {
+	// the background, enemies and asteroids are lit, everything that glows is drawn on top of them
+	lit := game.Lighting.Begin(screen)
+	game.collectLights()
+
+	game.Background.Draw(lit, camera, game.Counter)
+	timer.Lap("background")
 
 	makeSlaveTint := func() *colorm.ColorM {
 		var tint colorm.ColorM
@@ -2194,104 +2391,101 @@ func (game *Game) Draw(screen *ebiten.Image) {
 	}
 
 	for _, enemy := range game.Enemies {
-		enemy.Draw(screen, game.ShaderManager, game.Camera)
+		enemy.Draw(lit, game.ShaderManager, camera)
+	}
+	timer.Lap("enemies")
+
+	drawAsteroids := func(target *ebiten.Image) {
+		for _, asteroid := range game.Asteroids {
+			asteroid.Draw(target, game.ImageManager, game.ShaderManager, camera)
+		}
+		timer.Lap("asteroids")
 	}
 
+	// without lighting the asteroids stay on top of the powerups and effects, as they always were
+	litAsteroids := game.Lighting.IsEnabled()
+	if litAsteroids {
+		drawAsteroids(lit)
+	}
+
+	game.drawLightingNormals(camera)
+	game.Lighting.End(screen, game.ShaderManager, camera)
+	timer.Lap("lighting")
+
 	for _, powerup := range game.Powerups {
-		powerup.Draw(screen, game.ImageManager, game.ShaderManager, game.Camera.WorldGeoM())
+		powerup.Draw(screen, game.ImageManager, game.ShaderManager, camera.WorldGeoM())
 	}
 
 	for _, explosion := range game.Explosions {
-		explosion.Draw(screen, game.ShaderManager, game.Camera)
+		explosion.Draw(screen, game.ShaderManager, camera)
 	}
 
-	for _, asteroid := range game.Asteroids {
-		asteroid.Draw(screen, game.ImageManager, game.ShaderManager, game.Camera)
+	game.Particles.Draw(screen, camera)
+	timer.Lap("effects")
+
+	if !litAsteroids {
+		drawAsteroids(screen)
 	}
 
 	// game.TestAlphaCircle(screen, game.Player.x - game.Camera.x, game.Player.y)
 
+	game.drawWreck(screen, camera, game.Player)
+	game.drawWreck(screen, camera, game.RemotePlayer)
+
 	if game.RemotePlayer != nil && game.RemotePlayer.IsAlive() {
 		if game.isMaster() {
-			game.RemotePlayer.DrawWithTint(screen, game.ShaderManager, game.Camera, makeSlaveTint())
+			game.RemotePlayer.DrawWithTint(screen, game.ShaderManager, camera, makeSlaveTint())
+			game.drawPartnerOutline(screen, camera, game.RemotePlayer)
 		} else {
-			game.RemotePlayer.Draw(screen, game.ShaderManager, game.Camera)
+			game.RemotePlayer.Draw(screen, game.ShaderManager, camera)
 		}
 	}
 
-	drawOffscreenPlayerIndicator(screen, game.Font, game.Camera, game.RemotePlayer)
+	drawOffscreenPlayerIndicator(screen, game.Font, camera, game.RemotePlayer, game.Display.HudArea(screen.Bounds()))
 
 	if game.Player.IsAlive() {
 		if game.isSlave() {
-			game.Player.DrawWithTint(screen, game.ShaderManager, game.Camera, makeSlaveTint())
+			game.Player.DrawWithTint(screen, game.ShaderManager, camera, makeSlaveTint())
+			game.drawPartnerOutline(screen, camera, game.Player)
 		} else {
-			game.Player.Draw(screen, game.ShaderManager, game.Camera)
+			game.Player.Draw(screen, game.ShaderManager, camera)
 		}
 	}
 
+	timer.Lap("players")
+
 	for _, bullet := range game.Bullets {
-		bullet.Draw(screen, game.ShaderManager, game.Camera)
+		bullet.Draw(screen, game.ShaderManager, camera)
 	}
 
 	for _, bullet := range game.EnemyBullets {
-		bullet.Draw(screen, game.ShaderManager, game.Camera)
+		bullet.Draw(screen, game.ShaderManager, camera)
 	}
 
 	for _, bomb := range game.Bombs {
-		bomb.Draw(screen, game.ImageManager, game.ShaderManager, game.Camera)
+		bomb.Draw(screen, game.ImageManager, game.ShaderManager, camera)
 	}
 
-	if game.Camera.x < CameraEdgeFadeWidth {
-		leftAlpha := float32(CameraEdgeFadeAlpha * (1.0 - game.Camera.x/CameraEdgeFadeWidth))
-		rightX := float32(CameraEdgeFadeWidth - game.Camera.x)
+	for _, number := range game.DamageNumbers {
+		number.Draw(screen, game.Font, camera, game.elementShapes())
+	}
+	timer.Lap("bullets")
+
+	if camera.x < CameraEdgeFadeWidth {
+		leftAlpha := float32(CameraEdgeFadeAlpha * (1.0 - camera.x/CameraEdgeFadeWidth))
+		rightX := float32(CameraEdgeFadeWidth - camera.x)
 		drawEdgeFade(screen, 0, rightX, leftAlpha, 0)
 	}
 
-	maxCameraX := float64(LogicalWidth - ScreenWidth)
-	rightDistance := maxCameraX - game.Camera.x
+	maxCameraX := float64(LogicalWidth) - camera.Width()
+	rightDistance := maxCameraX - camera.x
 	if rightDistance < CameraEdgeFadeWidth {
-		leftX := float32(ScreenWidth - (CameraEdgeFadeWidth - rightDistance))
+		leftX := float32(camera.Width() - (CameraEdgeFadeWidth - rightDistance))
 		rightAlpha := float32(CameraEdgeFadeAlpha * (1.0 - rightDistance/CameraEdgeFadeWidth))
-		drawEdgeFade(screen, leftX, ScreenWidth, 0, rightAlpha)
-	}
-
-	drawOffscreenEnemyIndicators(screen, game.Enemies, game.Camera, game.Counter)
-
-	if game.Player.IsAlive() {
-		game.Player.DrawHud(screen, game.ImageManager, game.Font)
-	}
-
-	if game.Multiplayer != nil && game.Multiplayer.Peer != nil && game.Multiplayer.Peer.HasLatency() {
-		face := &text.GoTextFace{Source: game.Font, Size: 15}
-		op := &text.DrawOptions{}
-		op.GeoM.Translate(ScreenWidth-170, 4)
-		latencyMS := game.Multiplayer.Peer.LatencyMS()
-		latencyColor := color.RGBA{R: 0xff, G: 0, B: 0, A: 0xff}
-		if latencyMS < 20 {
-			latencyColor = color.RGBA{R: 0, G: 0xff, B: 0, A: 0xff}
-		} else if latencyMS < 100 {
-			latencyColor = color.RGBA{R: 0xff, G: 0xff, B: 0, A: 0xff}
-		}
-		op.ColorScale.ScaleWithColor(latencyColor)
-		text.Draw(screen, fmt.Sprintf("Peer: %dms", latencyMS), face, op)
+		drawEdgeFade(screen, leftX, float32(camera.Width()), 0, rightAlpha)
 	}
 
-	if game.WhiteFlash > 0 {
-		flash := premultiplyAlpha(color.RGBA{R: 255, G: 255, B: 255, A: uint8(game.WhiteFlash * 255 / GameWhiteFlash)})
-		vector.FillRect(screen, 0, 0, ScreenWidth, ScreenHeight, &flash, true)
-	}
-
-	if game.FadeIn < GameFadeIn {
-		vector.FillRect(screen, 0, 0, ScreenWidth, ScreenHeight, &color.RGBA{R: 0, G: 0, B: 0, A: uint8(255 - game.FadeIn*255/GameFadeIn)}, true)
-	}
-
-	if game.FadeOut > 0 && game.FadeOut <= GameFadeOut {
-		vector.FillRect(screen, 0, 0, ScreenWidth, ScreenHeight, &color.RGBA{R: 0, G: 0, B: 0, A: uint8(255 - game.FadeOut*255/GameFadeOut)}, true)
-	}
-
-	// vector.StrokeRect(screen, 0, 0, 100, 100, 3, &color.RGBA{R: 255, G: 0, B: 0, A: 128}, true)
-	// vector.FillRect(screen, 0, 0, 100, 100, &color.RGBA{R: 255, G: 0, B: 0, A: 64}, true)
-
+	drawOffscreenEnemyIndicators(screen, game.Enemies, camera, game.Counter)
 }
 
 func (game *Game) PreloadAssets() error {
@@ -2318,62 +2512,97 @@ func premultiplyAlpha(value color.RGBA) color.RGBA {
 type RunMode int
 
 const (
-	RunGame RunMode = iota
-	RunMenu RunMode = iota
+	RunGame     RunMode = iota
+	RunMenu     RunMode = iota
+	RunShop     RunMode = iota
+	RunLoadout  RunMode = iota
+	RunGameOver RunMode = iota
 )
 
 type Run struct {
 	Player        *Player
 	Game          *Game
 	Menu          *Menu
+	Shop          *Shop
+	LoadoutMenu   *LoadoutMenu
+	GameOver      *GameOverScreen
 	Mode          RunMode
 	Quit          context.Context
 	Cancel        context.CancelFunc
-	MusicVolume   float64
-	EffectsVolume float64
+	Volumes       BusVolumes
 	SoundManager  *SoundManager
 	PeerConnector PeerConnector
 	Cheats        bool
-}
 
-func (run *Run) DrawFinalScreen(screen ebiten.FinalScreen, offscreen *ebiten.Image, geoM ebiten.GeoM) {
+	// the loadout picked on the loadout screen, used by the next run
+	Loadout Loadout
+	// play with limited lives, picked on the loadout screen
+	Arcade bool
+	// the loadout the slave announced, used for the master's copy of the slave's ship
+	PeerLoadout      Loadout
+	LoadoutAnnounced bool
+
+	// the chain of shaders applied to the finished frame, configured from the graphics menu
+	PostProcessor *PostProcessor
+	// resolution, scaling and HUD placement, see display.go
+	Display *DisplaySettings
+	// kept across levels so the overlay stays open
+	Debug *DebugOverlay
+	// kept across levels along with its history
+	Console *Console
+	// configured from the graphics menu
+	HitFeedback   *HitFeedbackSettings
+	Lighting      *LightingSettings
+	Accessibility *AccessibilitySettings
+	// controllers plugged in and the player each belongs to
+	Gamepads *GamepadManager
+	// the key and button of each action for each local player, changed from the controls menu
+	Controls [MaxLocalPlayers]*Controls
+	// the on screen controls of phones and tablets
+	Touch *TouchControls
+	// hovering and clicking in menus, and aiming at the cursor if that is turned on
+	Mouse *MouseInput
+}
+
+func (run *Run) DrawFinalScreen(screen ebiten.FinalScreen, offscreen *ebiten.Image, fit ebiten.GeoM) {
+	geoM, filter := run.Display.ScaleGeoM(screen.Bounds(), offscreen.Bounds(), fit)
+	run.Display.Placed(fit, geoM)
+
 	if run.Game != nil && run.Mode == RunGame {
-		run.Game.DrawFinalScreen(screen, offscreen, geoM)
+		run.Game.DrawFinalScreen(screen, offscreen, geoM, filter, run.PostProcessor)
 	} else {
-		screen.DrawImage(offscreen, &ebiten.DrawImageOptions{
-			GeoM: geoM,
-		})
+		run.PostProcessor.Draw(screen, offscreen, geoM, filter, PostProcessFrame{})
 	}
 }
 
-func (run *Run) GetMusicVolume() float64 {
-	return run.MusicVolume
-}
-
-func (run *Run) GetEffectsVolume() float64 {
-	return run.EffectsVolume
+func (run *Run) GetVolume(bus AudioBus) float64 {
+	return run.Volumes[bus]
 }
 
-func (run *Run) updateVolumes() {
+func (run *Run) SetVolume(bus AudioBus, volume float64) {
+	run.Volumes[bus] = clampVolume(volume)
 	if run.SoundManager != nil {
-		run.SoundManager.SetMusicVolume(run.MusicVolume)
-		run.SoundManager.SetEffectsVolume(run.EffectsVolume)
+		run.SoundManager.SetVolume(bus, run.Volumes[bus])
 	}
 }
 
-func (run *Run) SetMusicVolume(volume float64) {
-	run.MusicVolume = clampVolume(volume)
-	run.updateVolumes()
-}
-
-func (run *Run) SetEffectsVolume(volume float64) {
-	run.EffectsVolume = clampVolume(volume)
-	run.updateVolumes()
-}
-
 func (run *Run) Update() error {
+	if run.SoundManager != nil {
+		run.SoundManager.Update()
+	}
+	if run.Gamepads != nil {
+		run.Gamepads.Update()
+	}
+	if run.Touch != nil {
+		run.Touch.Update(run.Display, run.Display.HudArea(run.Display.View()))
+	}
+	if run.Mouse != nil {
+		run.Mouse.Update(run.Display, run.Mode == RunGame && run.Mouse.Aim)
+	}
+
 	if run.PeerConnector != nil {
 		run.PeerConnector.Tick()
+		run.announceLoadout()
 	}
 
 	if run.PeerConnector != nil {
@@ -2382,7 +2611,7 @@ func (run *Run) Update() error {
 			if err := run.Game.processNetworkMessages(run, messages); err != nil {
 				return err
 			}
-		} else if run.Mode == RunMenu {
+		} else if run.Mode == RunMenu || run.Mode == RunLoadout {
 			if err := run.handleMenuMultiplayerMessages(messages); err != nil {
 				return err
 			}
@@ -2391,22 +2620,45 @@ func (run *Run) Update() error {
 
 	switch run.Mode {
 	case RunGame:
-		err := run.Game.Update(run)
+		run.Console.Update(run.Game)
+		run.Game.ReadInput()
+
+		var err error
+		for range run.Game.TicksThisFrame() {
+			err = run.Game.Update(run)
+			if err != nil {
+				break
+			}
+		}
+
 		if errors.Is(err, LevelEnd) {
-			notifyPeer := run.Game != nil && run.Game.isMaster()
-			return run.StartNextLevel(run.Game.Difficulty*1.5, notifyPeer, "")
+			run.OpenShop(run.Game.Difficulty * 1.5)
+			return nil
+		} else if errors.Is(err, GameOver) {
+			run.OpenGameOver()
+			return nil
 		} else {
 			return err
 		}
 	case RunMenu:
 		return run.Menu.Update(run)
+	case RunShop:
+		return run.Shop.Update(run)
+	case RunLoadout:
+		return run.LoadoutMenu.Update(run)
+	case RunGameOver:
+		return run.GameOver.Update(run)
 	}
 
 	return fmt.Errorf("Unknown mode %v", run.Mode)
 }
 
 func (run *Run) Layout(outsideWidth int, outsideHeight int) (int, int) {
-	return ScreenWidth, ScreenHeight
+	run.Display.Width = run.Display.ViewWidth(outsideWidth, outsideHeight)
+	if run.Game != nil {
+		run.Game.Camera.SetWidth(float64(run.Display.Width))
+	}
+	return run.Display.Width, ScreenHeight
 }
 
 func (run *Run) Draw(screen *ebiten.Image) {
@@ -2414,11 +2666,34 @@ func (run *Run) Draw(screen *ebiten.Image) {
 		run.Game.Draw(screen)
 	}
 
+	if run.Mode == RunGame && run.Game != nil {
+		if run.Touch != nil {
+			run.Touch.Draw(screen, run.Game.Font)
+		}
+		run.Console.Draw(screen, run.Game.Font)
+	}
+
+	width := float32(screen.Bounds().Dx())
 	if run.Mode == RunMenu {
-		vector.FillRect(screen, 0, 0, ScreenWidth, ScreenHeight, color.RGBA{R: 0, G: 0, B: 0, A: 92}, true)
+		vector.FillRect(screen, 0, 0, width, ScreenHeight, color.RGBA{R: 0, G: 0, B: 0, A: 92}, true)
 		run.Menu.Draw(screen)
 	}
 
+	if run.Mode == RunShop {
+		vector.FillRect(screen, 0, 0, width, ScreenHeight, color.RGBA{R: 0, G: 0, B: 0, A: 200}, true)
+		run.Shop.Draw(screen)
+	}
+
+	if run.Mode == RunLoadout {
+		vector.FillRect(screen, 0, 0, width, ScreenHeight, color.RGBA{R: 0, G: 0, B: 0, A: 200}, true)
+		run.LoadoutMenu.Draw(screen)
+	}
+
+	if run.Mode == RunGameOver {
+		vector.FillRect(screen, 0, 0, width, ScreenHeight, color.RGBA{R: 0, G: 0, B: 0, A: 160}, true)
+		run.GameOver.Draw(screen, run.Player)
+	}
+
 	/*
 	   switch run.Mode {
 	       case RunGame: run.Game.Draw(screen)
@@ -2427,13 +2702,15 @@ func (run *Run) Draw(screen *ebiten.Image) {
 	*/
 }
 
-func MakeGame(soundManager *SoundManager, run *Run, difficulty float64, backdropName gameImages.Image) (*Game, error) {
+func MakeGame(soundManager *SoundManager, run *Run, difficulty float64, backgroundName string) (*Game, error) {
 	if run.Player == nil {
 		return nil, fmt.Errorf("game: no player created")
 	}
 
+	layout := worldLayoutFor(difficulty)
+
 	run.Player.x = LogicalWidth / 2
-	run.Player.y = ScreenHeight - 100
+	run.Player.y = playerStartY(layout.Height)
 
 	/*
 	   player, err := MakePlayer(ScreenWidth / 2, ScreenHeight - 100)
@@ -2442,7 +2719,7 @@ func MakeGame(soundManager *SoundManager, run *Run, difficulty float64, backdrop
 	   }
 	*/
 
-	background, err := MakeBackground(backdropName)
+	background, err := MakeBackground(backgroundName, difficulty)
 	if err != nil {
 		return nil, err
 	}
@@ -2457,6 +2734,11 @@ func MakeGame(soundManager *SoundManager, run *Run, difficulty float64, backdrop
 		return nil, err
 	}
 
+	particles, err := MakeParticleSystem()
+	if err != nil {
+		return nil, err
+	}
+
 	quitContext, cancel := context.WithCancel(run.Quit)
 
 	game := Game{
@@ -2465,6 +2747,7 @@ func MakeGame(soundManager *SoundManager, run *Run, difficulty float64, backdrop
 		Player:        run.Player,
 		Font:          font,
 		ShaderManager: shaderManager,
+		Particles:     particles,
 		ImageManager:  MakeImageManager(),
 		SoundManager:  soundManager,
 		FadeIn:        0,
@@ -2472,11 +2755,29 @@ func MakeGame(soundManager *SoundManager, run *Run, difficulty float64, backdrop
 		Quit:          quitContext,
 		Cancel:        cancel,
 		Difficulty:    difficulty,
-		Camera:        &Camera{x: float64(LogicalWidth-ScreenWidth) / 2, y: 0},
+		Camera:        MakeCamera(layout, float64(run.Display.Width)),
+		Arcade:        run.Arcade,
+		Debug:         run.Debug,
+		Console:       run.Console,
+		HitFeedback:   run.HitFeedback,
+		Lighting:      MakeLightingSystem(run.Lighting),
+		Accessibility: run.Accessibility,
+		Display:       run.Display,
+		Gamepads:      run.Gamepads,
+		Controls:      run.Controls[0],
+		Touch:         run.Touch,
+		Mouse:         run.Mouse,
+		TimeScale:     1,
+		DropRand:      rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
 	}
 
 	game.Camera.TrackPlayer(game.Player)
 
+	// effects are heard from where this level's camera is looking
+	if soundManager != nil {
+		soundManager.Listener = game.Camera
+	}
+
 	if game.Multiplayer == nil || game.Multiplayer.Role != multiplayerRoleSlave {
 		err = game.MakeEnemies(2)
 		if err != nil {
@@ -2502,6 +2803,7 @@ func main() {
 	log.SetFlags(log.Ldate | log.Lshortfile | log.Lmicroseconds)
 
 	cheats := flag.Bool("cheats", false, "enable cheats")
+	sounds := flag.String("sounds", "", "directory of sound files and a manifest.json that replace or add to the built in sounds")
 	flag.Parse()
 
 	// 1gb is enough for now
@@ -2534,13 +2836,18 @@ func main() {
 
 	audioContext := audio.NewContext(48000)
 
-	var initialMusicVolume float64 = 80
-	var initialEffectsVolume float64 = 80
+	initialVolumes := DefaultBusVolumes()
 
 	quit, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	soundManager, err := MakeSoundManager(quit, audioContext, initialMusicVolume, initialEffectsVolume)
+	library, err := audioFiles.OpenLibrary(*sounds)
+	if err != nil {
+		log.Printf("Unable to load sounds: %v", err)
+		return
+	}
+
+	soundManager, err := MakeSoundManager(quit, audioContext, library, initialVolumes)
 	if err != nil {
 		log.Printf("Unable to create sound manager: %v", err)
 		return
@@ -2548,7 +2855,17 @@ func main() {
 
 	peerConnector := newPeerConnector()
 
-	menu, err := createMenu(quit, soundManager, initialMusicVolume, initialEffectsVolume, *cheats, peerConnector)
+	postProcessSettings := DefaultPostProcessSettings()
+	displaySettings := DefaultDisplaySettings()
+	hitFeedback := DefaultHitFeedbackSettings()
+	lighting := DefaultLightingSettings()
+	accessibility := DefaultAccessibilitySettings()
+	controls := LoadControls(0)
+	partnerControls := LoadControls(1)
+	layouts := [MaxLocalPlayers]*Controls{&controls, &partnerControls}
+	mouse := LoadMouseInput()
+
+	menu, err := createMenu(quit, soundManager, initialVolumes, *cheats, peerConnector, &postProcessSettings, &displaySettings, &hitFeedback, &lighting, &accessibility, layouts, mouse)
 	if err != nil {
 		log.Printf("Unable to create menu: %v", err)
 		return
@@ -2568,11 +2885,23 @@ func main() {
 		Quit:          quit,
 		Cancel:        cancel,
 		Menu:          menu,
-		MusicVolume:   initialMusicVolume,
-		EffectsVolume: initialEffectsVolume,
+		Volumes:       initialVolumes,
 		SoundManager:  soundManager,
 		PeerConnector: peerConnector,
 		Cheats:        *cheats,
+		Loadout:       DefaultLoadout(),
+		PeerLoadout:   DefaultLoadout(),
+		PostProcessor: MakePostProcessor(menu.ShaderManager, &postProcessSettings, &accessibility),
+		Display:       &displaySettings,
+		Debug:         MakeDebugOverlay(),
+		Console:       MakeConsole(),
+		HitFeedback:   &hitFeedback,
+		Lighting:      &lighting,
+		Accessibility: &accessibility,
+		Gamepads:      MakeGamepadManager(layouts),
+		Controls:      layouts,
+		Touch:         MakeTouchControls(),
+		Mouse:         mouse,
 	}
 
 	log.Printf("Running")
@@ -8,14 +8,13 @@ import (
 	"log"
 	"math"
 	"math/rand/v2"
+	"slices"
 	"sync"
 
-	audioFiles "github.com/kazzmir/webgl-shooter/audio"
 	fontLib "github.com/kazzmir/webgl-shooter/font"
 	gameImages "github.com/kazzmir/webgl-shooter/images"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
 )
@@ -60,6 +59,14 @@ type MenuOption struct {
 	TextFunc func() string
 	Action   MenuAction
 	Respond  []ebiten.Key
+	// drawn as a bar next to the option that the mouse can drag
+	Slider *MenuSlider
+}
+
+// a value from 0 to 1 that an option shows as a bar, see MouseInput.MenuKeys
+type MenuSlider struct {
+	Value func() float64
+	Set   func(run *Run, value float64)
 }
 
 type HintFunc func(*Hint, *ebiten.Image, *ImageManager, *ShaderManager, *text.GoTextFaceSource, ebiten.GeoM) error
@@ -118,20 +125,38 @@ func (hint *Hint) Update() {
 type Menu struct {
 	Font                   *text.GoTextFaceSource
 	Counter                uint64
-	Options                []*MenuOption
-	MultiplayerOptions     []*MenuOption
+	Options                OptionList
+	MultiplayerOptions     OptionList
 	MultiplayerStartOption *MenuOption
-	Selected               int
-	MultiplayerSelected    int
 	MultiplayerOpen        bool
+	GraphicsOptions        OptionList
+	GraphicsOpen           bool
+	AccessibilityOptions   OptionList
+	AccessibilityOpen      bool
+	AudioOptions           OptionList
+	AudioOpen              bool
+	ControlsOptions        OptionList
+	ControlsOpen           bool
 	SoundManager           *SoundManager
 	ImageManager           *ImageManager
 	ShaderManager          *ShaderManager
 	PeerConnector          PeerConnector
 	PeerEditor             *PeerEditor
 
+	// the controls of each local player, ControlsPlayer is the one the controls menu changes
+	Controls       [MaxLocalPlayers]*Controls
+	ControlsPlayer int
+	// waiting for the key or button to bind to CaptureAction, see controls.go
+	Capturing     bool
+	CaptureAction InputAction
+	// what happened to another action's binding when the last one was captured
+	ControlsNotice string
+
 	Hints      []*Hint
 	ActiveHint int
+
+	// shared with the run, the menu is drawn over the whole view
+	Display *DisplaySettings
 }
 
 func (option *MenuOption) Label() string {
@@ -152,32 +177,40 @@ func (option *MenuOption) DoesRespond(key ebiten.Key) bool {
 	return false
 }
 
-func (menu *Menu) currentOptions() []*MenuOption {
-	if menu.MultiplayerOpen {
-		if menu.PeerConnector != nil && menu.PeerConnector.IsConnected() && menu.PeerConnector.IsMaster() && menu.MultiplayerStartOption != nil {
-			options := make([]*MenuOption, 0, len(menu.MultiplayerOptions)+1)
-			last := len(menu.MultiplayerOptions) - 1
-			if last < 0 {
-				return menu.MultiplayerOptions
-			}
-			options = append(options, menu.MultiplayerOptions[:last]...)
-			options = append(options, menu.MultiplayerStartOption)
-			options = append(options, menu.MultiplayerOptions[last])
-			return options
-		}
+func (menu *Menu) currentList() *OptionList {
+	if menu.GraphicsOpen {
+		return &menu.GraphicsOptions
+	}
 
-		return menu.MultiplayerOptions
+	if menu.AccessibilityOpen {
+		return &menu.AccessibilityOptions
 	}
 
-	return menu.Options
-}
+	if menu.AudioOpen {
+		return &menu.AudioOptions
+	}
+
+	if menu.ControlsOpen {
+		return &menu.ControlsOptions
+	}
 
-func (menu *Menu) currentSelected() *int {
 	if menu.MultiplayerOpen {
-		return &menu.MultiplayerSelected
+		menu.showStartOption(menu.PeerConnector != nil && menu.PeerConnector.IsConnected() && menu.PeerConnector.IsMaster())
+		return &menu.MultiplayerOptions
 	}
 
-	return &menu.Selected
+	return &menu.Options
+}
+
+// the start option is only among the multiplayer options while connected as the master, just before the last one
+func (menu *Menu) showStartOption(show bool) {
+	list := &menu.MultiplayerOptions
+	index := slices.Index(list.Options, menu.MultiplayerStartOption)
+	if show && index == -1 && menu.MultiplayerStartOption != nil && len(list.Options) > 0 {
+		list.Options = slices.Insert(list.Options, len(list.Options)-1, menu.MultiplayerStartOption)
+	} else if !show && index != -1 {
+		list.Options = slices.Delete(list.Options, index, index+1)
+	}
 }
 
 func (menu *Menu) ChooseHint() {
@@ -198,16 +231,22 @@ func (menu *Menu) ChooseHint() {
 func (menu *Menu) Update(run *Run) error {
 	menu.Counter = (menu.Counter + 1)
 
-	keys := make([]ebiten.Key, 0)
-	keys = inpututil.AppendJustPressedKeys(keys)
 	chars := make([]rune, 0)
 	chars = ebiten.AppendInputChars(chars)
 
 	if menu.PeerEditor != nil && menu.PeerEditor.Active {
-		menu.PeerEditor.Handle(chars, keys)
+		// letters bound to actions are only letters while typing
+		menu.PeerEditor.Handle(chars, run.typedKeys())
+		return nil
+	}
+
+	if menu.Capturing {
+		menu.updateCapture(run)
 		return nil
 	}
 
+	keys := menu.currentList().Keys(run)
+
 	if menu.ActiveHint == -1 || menu.Hints[menu.ActiveHint].Active == false {
 		menu.ChooseHint()
 	}
@@ -215,13 +254,24 @@ func (menu *Menu) Update(run *Run) error {
 	menu.Hints[menu.ActiveHint].Update()
 
 	for _, key := range keys {
-		options := menu.currentOptions()
-		selected := menu.currentSelected()
-		if *selected >= len(options) {
-			*selected = len(options) - 1
-		}
 		switch key {
 		case ebiten.KeyEscape, ebiten.KeyCapsLock:
+			if menu.GraphicsOpen {
+				menu.GraphicsOpen = false
+				return nil
+			}
+			if menu.AccessibilityOpen {
+				menu.AccessibilityOpen = false
+				return nil
+			}
+			if menu.AudioOpen {
+				menu.AudioOpen = false
+				return nil
+			}
+			if menu.ControlsOpen {
+				menu.ControlsOpen = false
+				return nil
+			}
 			if menu.MultiplayerOpen {
 				menu.MultiplayerOpen = false
 				return nil
@@ -229,23 +279,10 @@ func (menu *Menu) Update(run *Run) error {
 			if run.Game != nil {
 				run.Mode = RunGame
 			}
-		case ebiten.KeyArrowUp:
-			*selected -= 1
-			if *selected < 0 {
-				*selected = len(options) - 1
-			}
-			menu.SoundManager.PlayEffect(audioFiles.AudioBeep)
-		case ebiten.KeyArrowDown:
-			*selected = (*selected + 1) % len(options)
-			menu.SoundManager.PlayEffect(audioFiles.AudioBeep)
 		default:
-			option := options[*selected]
-			if option.DoesRespond(key) {
-				menu.SoundManager.PlayEffect(audioFiles.AudioBeep)
-				err := option.Action(option, run, key)
-				if err != nil {
-					return err
-				}
+			err := menu.currentList().HandleKey(run, menu.SoundManager, key)
+			if err != nil {
+				return err
 			}
 		}
 	}
@@ -259,48 +296,25 @@ func (menu *Menu) Draw(screen *ebiten.Image) {
 	var x float64 = 100
 	var y float64 = 100
 
-	angle := float64(menu.Counter%360) * math.Pi / 180.0 * 9
-	// log.Printf("Counter: %v angle: %v", menu.Counter % 360, angle)
-
-	// angle = 90.0 * math.Pi / 180.0
-	a := int((math.Sin(angle) + 1) * 128)
-	if a > 255 {
-		a = 255
-	}
-
 	// vector.DrawFilledRect(screen, float32(x - 10), float32(y - 10), 100, 30, &color.RGBA{R: 255, G: 255, B: 255, A: uint8(menu.Counter % 255)}, true)
 
-	face := text.GoTextFace{Source: menu.Font, Size: 20}
-
-	_, height := text.Measure("X", &face, 0)
+	_, y = menu.currentList().Draw(screen, text.GoTextFace{Source: menu.Font, Size: 20}, x, y, 150, 10, menu.Counter)
 
-	options := menu.currentOptions()
-	selected := *menu.currentSelected()
-	if selected >= len(options) {
-		selected = len(options) - 1
+	if menu.GraphicsOpen {
+		drawText(screen, text.GoTextFace{Source: menu.Font, Size: 28}, x, 60, "Graphics", color.RGBA{R: 255, G: 255, B: 255, A: 255})
 	}
 
-	optionWidth := 150.0
-	for _, option := range options {
-		width, _ := text.Measure(option.Label(), &face, 0)
-		optionWidth = math.Max(optionWidth, width+20)
+	if menu.AccessibilityOpen {
+		drawText(screen, text.GoTextFace{Source: menu.Font, Size: 28}, x, 60, "Accessibility", color.RGBA{R: 255, G: 255, B: 255, A: 255})
 	}
 
-	for i, option := range options {
-		label := option.Label()
-		drawColor := color.RGBA{R: 255, G: 255, B: 255, A: 32}
-		if selected == i {
-			drawColor = color.RGBA{R: 255, G: 255, B: 255, A: uint8(a)}
-		}
-		vector.FillRect(screen, float32(x-10), float32(y-10), float32(optionWidth), float32(height+10+10), premultiplyAlpha(drawColor), true)
-
-		op := &text.DrawOptions{}
-		op.GeoM.Translate(x, y)
-		red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
-		op.ColorScale.ScaleWithColor(red)
-		text.Draw(screen, label, &face, op)
+	if menu.AudioOpen {
+		drawText(screen, text.GoTextFace{Source: menu.Font, Size: 28}, x, 60, "Audio", color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	}
 
-		y += float64(height + 40)
+	if menu.ControlsOpen {
+		drawText(screen, text.GoTextFace{Source: menu.Font, Size: 28}, x, 60, "Controls", color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		drawText(screen, text.GoTextFace{Source: menu.Font, Size: 14}, x+150, 70, menu.ControlsNotice, color.RGBA{R: 0xff, G: 0xf0, B: 0xa0, A: 0xff})
 	}
 
 	if menu.MultiplayerOpen && menu.PeerConnector != nil {
@@ -398,14 +412,15 @@ func (menu *Menu) Draw(screen *ebiten.Image) {
 		}
 	}
 
-	drawText(screen, text.GoTextFace{Source: menu.Font, Size: 15}, ScreenWidth-170, ScreenHeight-20, "Made by Jon Rafkind", color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	area := menu.Display.HudArea(screen.Bounds())
+	drawText(screen, text.GoTextFace{Source: menu.Font, Size: 15}, area.Right-170, area.Bottom-20, "Made by Jon Rafkind", color.RGBA{R: 255, G: 255, B: 255, A: 255})
 
 	if menu.PeerEditor != nil && menu.PeerEditor.Active {
 		menu.PeerEditor.Draw(screen, menu.Font, menu.Counter)
 	}
 }
 
-func makeHintKeys() *Hint {
+func makeHintKeys(controls *Controls) *Hint {
 	return &Hint{
 		Active: false,
 		Time:   0,
@@ -422,13 +437,13 @@ func makeHintKeys() *Hint {
 			text.Draw(screen, "Keys", &face, op)
 			op.GeoM.Translate(5, 0)
 			all := []string{
-				"Arrow up: move ship up",
-				"Arrow down: move ship down",
-				"Arrow left: move ship left",
-				"Arrow right: move ship right",
-				"Spacebar: shoot",
-				"Left Shift: increase speed",
-				"B: release bomb",
+				fmt.Sprintf("%v: move ship up", keyName(controls.Keys[ActionUp])),
+				fmt.Sprintf("%v: move ship down", keyName(controls.Keys[ActionDown])),
+				fmt.Sprintf("%v: move ship left", keyName(controls.Keys[ActionLeft])),
+				fmt.Sprintf("%v: move ship right", keyName(controls.Keys[ActionRight])),
+				fmt.Sprintf("%v: shoot", keyName(controls.Keys[ActionShoot])),
+				fmt.Sprintf("%v: increase speed", keyName(controls.Keys[ActionJump])),
+				fmt.Sprintf("%v: release bomb", keyName(controls.Keys[ActionBomb])),
 			}
 			for _, s := range all {
 				op.GeoM.Translate(0, fontSize+1)
@@ -567,105 +582,120 @@ func makeHintPowerups() *Hint {
 			op.GeoM.Translate(0, 40)
 			text.Draw(screen, "Add a bomb to your arsenal", &face, op)
 
-			/*
-			   powerup = MakePowerupEnergyIncrease(x + 30, y + 200)
-			   powerup.Draw(screen, imageManager, shaderManager, scaler)
-			   op.GeoM.Translate(0, 40)
-			   text.Draw(screen, "Increase maximum energy and fill rate", &face, op)
-			*/
+			powerup = MakePowerupEnergyIncrease(x+30, y+200)
+			powerup.Draw(screen, imageManager, shaderManager, scaler)
+			op.GeoM.Translate(0, 40)
+			text.Draw(screen, "Increase maximum energy and fill rate", &face, op)
 
 			return nil
 		},
 	}
 }
 
-func createMenu(quit context.Context, soundManager *SoundManager, initialMusicVolume float64, initialEffectsVolume float64, cheats bool, peerConnector PeerConnector) (*Menu, error) {
+// left and right change the volume of a bus by 10, enter mutes it and unmutes it back to where it was.
+// dragging the slider sets the volume directly and unmutes it
+func makeVolumeOption(bus AudioBus, initialVolume float64) *MenuOption {
+	muted := false
+	lastVolume := initialVolume
+	return &MenuOption{
+		TextFunc: func() string {
+			if muted {
+				return fmt.Sprintf("%v Muted", bus)
+			}
+			return fmt.Sprintf("%v %v", bus, lastVolume)
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			switch key {
+			case ebiten.KeyArrowLeft:
+				if !muted {
+					run.SetVolume(bus, run.GetVolume(bus)-10)
+					lastVolume = run.GetVolume(bus)
+				}
+			case ebiten.KeyArrowRight:
+				if !muted {
+					run.SetVolume(bus, run.GetVolume(bus)+10)
+					lastVolume = run.GetVolume(bus)
+				}
+			case ebiten.KeyEnter:
+				muted = !muted
+				if muted {
+					run.SetVolume(bus, 0)
+				} else {
+					run.SetVolume(bus, lastVolume)
+				}
+			}
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+		Slider: &MenuSlider{
+			Value: func() float64 {
+				if muted {
+					return 0
+				}
+				return lastVolume / 100
+			},
+			Set: func(run *Run, value float64) {
+				muted = false
+				run.SetVolume(bus, math.Round(value*100))
+				lastVolume = run.GetVolume(bus)
+			},
+		},
+	}
+}
+
+func createMenu(quit context.Context, soundManager *SoundManager, volumes BusVolumes, cheats bool, peerConnector PeerConnector, postProcess *PostProcessSettings, display *DisplaySettings, hitFeedback *HitFeedbackSettings, lighting *LightingSettings, accessibility *AccessibilitySettings, controls [MaxLocalPlayers]*Controls, mouse *MouseInput) (*Menu, error) {
 
 	var options []*MenuOption
 	var multiplayerOptions []*MenuOption
+	var graphicsOptions []*MenuOption
+	var accessibilityOptions []*MenuOption
+	var audioOptions []*MenuOption
+	var controlsOptions []*MenuOption
 	var multiplayerStartOption *MenuOption
 	var menu *Menu
 
+	// the loadout menu has put the player's choice in run.Loadout, there is no partner to give a loadout to
 	startNewGame := func(run *Run) error {
-		return run.StartGame("", false, "")
+		return run.StartGame("", false, "", DefaultLoadout())
 	}
 
 	options = append(options, &MenuOption{
 		Text: "New game",
 		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
-			return startNewGame(run)
+			run.OpenLoadout("Launch", startNewGame)
+			return nil
 		},
 		Respond: []ebiten.Key{ebiten.KeyEnter},
 	})
 
-	musicMuted := false
-	lastMusicVolume := initialMusicVolume
 	options = append(options, &MenuOption{
-		Text: fmt.Sprintf("Music %v", initialMusicVolume),
+		Text: "Local co-op",
 		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
-			switch key {
-			case ebiten.KeyArrowLeft:
-				if !musicMuted {
-					run.SetMusicVolume(run.GetMusicVolume() - 10)
-					lastMusicVolume = run.GetMusicVolume()
-				}
-			case ebiten.KeyArrowRight:
-				if !musicMuted {
-					run.SetMusicVolume(run.GetMusicVolume() + 10)
-					lastMusicVolume = run.GetMusicVolume()
-				}
-			case ebiten.KeyEnter:
-				musicMuted = !musicMuted
-				if musicMuted {
-					run.SetMusicVolume(0)
-				} else {
-					run.SetMusicVolume(lastMusicVolume)
-				}
-			}
-
-			if musicMuted {
-				self.Text = "Music Muted"
-			} else {
-				self.Text = fmt.Sprintf("Music %v", run.GetMusicVolume())
-			}
+			run.OpenLoadout("Launch", func(run *Run) error {
+				return run.StartLocalCoop()
+			})
 			return nil
 		},
-		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
 	})
 
-	effectsMuted := false
-	lastEffectsVolume := initialEffectsVolume
 	options = append(options, &MenuOption{
-		Text: fmt.Sprintf("Effects %v", initialEffectsVolume),
+		Text: "Audio",
 		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
-			switch key {
-			case ebiten.KeyArrowLeft:
-				if !effectsMuted {
-					run.SetEffectsVolume(run.GetEffectsVolume() - 10)
-					lastEffectsVolume = run.GetEffectsVolume()
-				}
-			case ebiten.KeyArrowRight:
-				if !effectsMuted {
-					run.SetEffectsVolume(run.GetEffectsVolume() + 10)
-					lastEffectsVolume = run.GetEffectsVolume()
-				}
-			case ebiten.KeyEnter:
-				effectsMuted = !effectsMuted
-				if effectsMuted {
-					run.SetEffectsVolume(0)
-				} else {
-					run.SetEffectsVolume(lastEffectsVolume)
-				}
-			}
+			menu.AudioOpen = true
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
 
-			if effectsMuted {
-				self.Text = "Effects Muted"
-			} else {
-				self.Text = fmt.Sprintf("Effects %v", run.GetEffectsVolume())
-			}
+	options = append(options, &MenuOption{
+		Text: "Controls",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			menu.ControlsOpen = true
+			menu.ControlsNotice = ""
 			return nil
 		},
-		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
 	})
 
 	options = append(options, &MenuOption{
@@ -684,6 +714,264 @@ func createMenu(quit context.Context, soundManager *SoundManager, initialMusicVo
 		Respond: []ebiten.Key{ebiten.KeyEnter},
 	})
 
+	options = append(options, &MenuOption{
+		Text: "Graphics",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			menu.GraphicsOpen = true
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	options = append(options, &MenuOption{
+		Text: "Accessibility",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			menu.AccessibilityOpen = true
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	cycleDirection := func(key ebiten.Key) int {
+		if key == ebiten.KeyArrowLeft {
+			return -1
+		}
+		return 1
+	}
+
+	qualities := []PostProcessQuality{PostProcessLow, PostProcessMedium, PostProcessHigh}
+	graphicsOptions = append(graphicsOptions, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("Quality: %v", postProcess.Quality)
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			postProcess.Quality = cycleChoice(qualities, postProcess.Quality, cycleDirection(key))
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	// a toggle for one pass, passes the quality setting turns off are marked as such
+	makePassOption := func(name string, pass PostProcessPass, enabled *bool) *MenuOption {
+		return &MenuOption{
+			TextFunc: func() string {
+				switch {
+				case !*enabled:
+					return fmt.Sprintf("%v: Off", name)
+				case postProcess.Quality < pass.MinimumQuality():
+					return fmt.Sprintf("%v: On (needs %v quality)", name, pass.MinimumQuality())
+				default:
+					return fmt.Sprintf("%v: On", name)
+				}
+			},
+			Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+				*enabled = !*enabled
+				return nil
+			},
+			Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+		}
+	}
+
+	graphicsOptions = append(graphicsOptions, makePassOption("Bloom", PassBloom, &postProcess.Bloom))
+	graphicsOptions = append(graphicsOptions, makePassOption("Hit distortion", PassAberration, &postProcess.Aberration))
+	graphicsOptions = append(graphicsOptions, makePassOption("Vignette", PassVignette, &postProcess.Vignette))
+	graphicsOptions = append(graphicsOptions, makePassOption("CRT filter", PassCRT, &postProcess.CRT))
+
+	graphicsOptions = append(graphicsOptions, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("Resolution: %v", resolutions[display.Resolution].Name)
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			display.Resolution = (display.Resolution + cycleDirection(key) + len(resolutions)) % len(resolutions)
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	scalingModes := []ScalingMode{ScaleFit, ScaleInteger, ScalePixelPerfect}
+	graphicsOptions = append(graphicsOptions, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("Scaling: %v", display.Scaling)
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			display.Scaling = cycleChoice(scalingModes, display.Scaling, cycleDirection(key))
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	graphicsOptions = append(graphicsOptions, &MenuOption{
+		TextFunc: func() string {
+			if display.Borderless {
+				return "Borderless window: On"
+			}
+			return "Borderless window: Off"
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			display.SetBorderless(!display.Borderless)
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	safeAreas := []float64{0, 0.025, 0.05}
+	graphicsOptions = append(graphicsOptions, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("HUD margin: %v%%", display.SafeArea*100)
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			display.SafeArea = cycleChoice(safeAreas, display.SafeArea, cycleDirection(key))
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	makeToggleOption := func(name string, enabled *bool) *MenuOption {
+		return &MenuOption{
+			TextFunc: func() string {
+				if *enabled {
+					return fmt.Sprintf("%v: On", name)
+				}
+				return fmt.Sprintf("%v: Off", name)
+			},
+			Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+				*enabled = !*enabled
+				return nil
+			},
+			Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+		}
+	}
+
+	graphicsOptions = append(graphicsOptions, makeToggleOption("Damage numbers", &hitFeedback.DamageNumbers))
+	graphicsOptions = append(graphicsOptions, makeToggleOption("Hit stop", &hitFeedback.HitStop))
+	graphicsOptions = append(graphicsOptions, makeToggleOption("Hit flash", &hitFeedback.HitFlash))
+	graphicsOptions = append(graphicsOptions, makeToggleOption("Lighting", &lighting.Enabled))
+	graphicsOptions = append(graphicsOptions, makeToggleOption("Normal maps", &lighting.NormalMaps))
+
+	graphicsOptions = append(graphicsOptions, &MenuOption{
+		Text: "Back",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			menu.GraphicsOpen = false
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	accessibilityOptions = append(accessibilityOptions, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("Palette: %v", accessibility.Palette)
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			accessibility.Palette = cycleChoice(colorblindPalettes, accessibility.Palette, cycleDirection(key))
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	accessibilityOptions = append(accessibilityOptions, makeToggleOption("Element shapes", &accessibility.ElementShapes))
+	accessibilityOptions = append(accessibilityOptions, makeToggleOption("Partner outline", &accessibility.PartnerOutline))
+
+	accessibilityOptions = append(accessibilityOptions, &MenuOption{
+		Text: "Back",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			menu.AccessibilityOpen = false
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	for _, bus := range audioBuses {
+		audioOptions = append(audioOptions, makeVolumeOption(bus, volumes[bus]))
+	}
+
+	audioOptions = append(audioOptions, &MenuOption{
+		Text: "Back",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			menu.AudioOpen = false
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	controlsOptions = append(controlsOptions, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("Player %v", menu.ControlsPlayer+1)
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			menu.ControlsPlayer = (menu.ControlsPlayer + cycleDirection(key) + MaxLocalPlayers) % MaxLocalPlayers
+			menu.ControlsNotice = ""
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	// choosing the option waits for the key or button to bind to the action, see updateCapture
+	makeBindingOption := func(action InputAction) *MenuOption {
+		return &MenuOption{
+			TextFunc: func() string {
+				if menu.Capturing && menu.CaptureAction == action {
+					return fmt.Sprintf("%v: press a key or button", action)
+				}
+
+				controls := menu.Controls[menu.ControlsPlayer]
+				label := fmt.Sprintf("%v: %v / %v", action, keyName(controls.Keys[action]), buttonName(controls.Buttons[action]))
+				if controls.HasConflict(action) {
+					label += " (conflict)"
+				}
+				return label
+			},
+			Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+				menu.startCapture(action)
+				return nil
+			},
+			Respond: []ebiten.Key{ebiten.KeyEnter},
+		}
+	}
+
+	for action := range InputActions {
+		controlsOptions = append(controlsOptions, makeBindingOption(action))
+	}
+
+	controlsOptions = append(controlsOptions, &MenuOption{
+		TextFunc: func() string {
+			if mouse.Aim {
+				return "Mouse aim: On"
+			}
+			return "Mouse aim: Off"
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			mouse.Aim = !mouse.Aim
+			mouse.Save()
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter},
+	})
+
+	controlsOptions = append(controlsOptions, &MenuOption{
+		Text: "Reset to defaults",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			*menu.Controls[menu.ControlsPlayer] = defaultControlsFor(menu.ControlsPlayer)
+			menu.Controls[menu.ControlsPlayer].Save(menu.ControlsPlayer)
+			// the mouse aims for player 1
+			if menu.ControlsPlayer == 0 {
+				mouse.Aim = false
+				mouse.Save()
+			}
+			menu.ControlsNotice = fmt.Sprintf("Player %v controls reset to defaults", menu.ControlsPlayer+1)
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	controlsOptions = append(controlsOptions, &MenuOption{
+		Text: "Back",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			menu.ControlsOpen = false
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
 	options = append(options, &MenuOption{
 		Text: "Continue",
 		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
@@ -691,10 +979,13 @@ func createMenu(quit context.Context, soundManager *SoundManager, initialMusicVo
 
 			if run.Game == nil {
 				if run.Player == nil {
-					player, err := MakePlayer(0, 0, cheats)
+					player, err := MakePlayer(0, 0, cheats, run.Loadout)
 					if err != nil {
 						return err
 					}
+					if run.Arcade {
+						player.StartArcade()
+					}
 					run.Player = player
 				}
 
@@ -752,10 +1043,24 @@ func createMenu(quit context.Context, soundManager *SoundManager, initialMusicVo
 		Respond: []ebiten.Key{ebiten.KeyEnter},
 	})
 
+	multiplayerOptions = append(multiplayerOptions, &MenuOption{
+		Text: "Loadout",
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			run.OpenLoadout("Done", func(run *Run) error {
+				// announce the new choice to the master
+				run.LoadoutAnnounced = false
+				run.Mode = RunMenu
+				return nil
+			})
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
 	multiplayerStartOption = &MenuOption{
 		Text: "Start game",
 		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
-			return run.StartGame(multiplayerRoleMaster, true, "")
+			return run.StartGame(multiplayerRoleMaster, true, "", run.PeerLoadout)
 		},
 		Respond: []ebiten.Key{ebiten.KeyEnter},
 	}
@@ -789,7 +1094,7 @@ func createMenu(quit context.Context, soundManager *SoundManager, initialMusicVo
 	}
 
 	hints := []*Hint{
-		makeHintKeys(),
+		makeHintKeys(controls[0]),
 		makeHintPowerups(),
 		makeHintEnergy(),
 		makeHintHealth(),
@@ -797,15 +1102,21 @@ func createMenu(quit context.Context, soundManager *SoundManager, initialMusicVo
 
 	menu = &Menu{
 		Font:                   font,
-		Options:                options,
-		MultiplayerOptions:     multiplayerOptions,
+		Options:                OptionList{Options: options},
+		MultiplayerOptions:     OptionList{Options: multiplayerOptions},
 		MultiplayerStartOption: multiplayerStartOption,
+		GraphicsOptions:        OptionList{Options: graphicsOptions},
+		AccessibilityOptions:   OptionList{Options: accessibilityOptions},
+		AudioOptions:           OptionList{Options: audioOptions},
+		ControlsOptions:        OptionList{Options: controlsOptions},
+		Controls:               controls,
 		ImageManager:           MakeImageManager(),
 		ShaderManager:          shaderManager,
 		PeerConnector:          peerConnector,
 		SoundManager:           soundManager,
 		Hints:                  hints,
 		ActiveHint:             -1,
+		Display:                display,
 	}
 
 	return menu, nil
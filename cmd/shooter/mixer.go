@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+
+	audioFiles "github.com/kazzmir/webgl-shooter/audio"
+)
+
+// every sound plays through one of these, each with its own volume slider
+type AudioBus int
+
+const (
+	BusMusic AudioBus = iota
+	BusWeapons
+	BusExplosions
+	BusUI
+	// cues about the player's own ship, such as pickups and extra lives
+	BusVoice
+	AudioBuses
+)
+
+var audioBuses = []AudioBus{BusMusic, BusWeapons, BusExplosions, BusUI, BusVoice}
+
+func (bus AudioBus) String() string {
+	switch bus {
+	case BusMusic:
+		return "Music"
+	case BusWeapons:
+		return "Weapons"
+	case BusExplosions:
+		return "Explosions"
+	case BusUI:
+		return "UI"
+	case BusVoice:
+		return "Voice"
+	}
+
+	return "Unknown"
+}
+
+// volume of each bus from 0 to 100
+type BusVolumes [AudioBuses]float64
+
+func DefaultBusVolumes() BusVolumes {
+	var volumes BusVolumes
+	for _, bus := range audioBuses {
+		volumes[bus] = 80
+	}
+	return volumes
+}
+
+// the bus named by a sound in the audio manifest, such as "weapons"
+func parseAudioBus(name string) (AudioBus, error) {
+	for _, bus := range audioBuses {
+		if strings.EqualFold(bus.String(), name) {
+			return bus, nil
+		}
+	}
+	return BusUI, fmt.Errorf("unknown bus %q", name)
+}
+
+// at most this many effects play at once, music does not count
+const MaxVoices = 24
+
+// how far the music drops under a big explosion or the boss's roar
+const DuckBigExplosion = 0.4
+const DuckBossRoar = 0.25
+
+// ticks the music stays ducked before it starts coming back
+const DuckHoldTicks = 20
+
+// ticks for the music to go from ducked back to full volume
+const DuckReleaseTicks = 40
+
+// ticks for the music to drop when ducked, short enough to be heard as part of the hit
+const DuckAttackTicks = 4
+
+// a sound that is playing
+type mixerVoice struct {
+	name   audioFiles.AudioName
+	bus    AudioBus
+	player *audio.Player
+	// returns the player to its handler, may be nil
+	finish func()
+	// the sound's own volume from the manifest
+	volume float64
+	// how loud the sound is before its bus volume, lower for effects far from the camera
+	gain float64
+	// when the sound started relative to the others, higher is newer
+	sequence uint64
+}
+
+// the voice to stop to make room for a new sound of the given gain: the quietest, and the oldest of those
+// if several are as quiet. returns -1 if the new sound would be quieter than anything playing, in which
+// case the new sound is the one to drop
+func chooseVictim(voices []*mixerVoice, gain float64) int {
+	victim := -1
+	for i, voice := range voices {
+		if voice.bus == BusMusic {
+			continue
+		}
+		if victim == -1 || voice.gain < voices[victim].gain || (voice.gain == voices[victim].gain && voice.sequence < voices[victim].sequence) {
+			victim = i
+		}
+	}
+
+	if victim != -1 && voices[victim].gain > gain {
+		return -1
+	}
+	return victim
+}
+
+// the oldest voice playing this sound, or -1
+func oldestVoice(voices []*mixerVoice, name audioFiles.AudioName) int {
+	oldest := -1
+	for i, voice := range voices {
+		if voice.name == name && (oldest == -1 || voice.sequence < voices[oldest].sequence) {
+			oldest = i
+		}
+	}
+	return oldest
+}
+
+func countVoices(voices []*mixerVoice, keep func(*mixerVoice) bool) int {
+	count := 0
+	for _, voice := range voices {
+		if keep(voice) {
+			count += 1
+		}
+	}
+	return count
+}
+
+func (manager *SoundManager) SetVolume(bus AudioBus, volume float64) {
+	manager.Volumes[bus] = clampVolume(volume)
+}
+
+func (manager *SoundManager) GetVolume(bus AudioBus) float64 {
+	return manager.Volumes[bus]
+}
+
+// the volume a player on this bus is set to, including ducking of the music
+func (manager *SoundManager) busGain(bus AudioBus) float64 {
+	gain := manager.Volumes[bus] / 100
+	if bus == BusMusic {
+		gain *= manager.duck
+	}
+	return gain
+}
+
+// drop the music to level for a moment, see Update. overlapping ducks keep the deepest level
+func (manager *SoundManager) Duck(level float64) {
+	if manager.duckHold == 0 || level < manager.duckLevel {
+		manager.duckLevel = level
+	}
+	manager.duckHold = DuckHoldTicks
+}
+
+func (manager *SoundManager) stopVoice(index int) {
+	voice := manager.voices[index]
+	voice.player.Pause()
+	if voice.finish != nil {
+		voice.finish()
+	}
+	manager.voices = append(manager.voices[:index], manager.voices[index+1:]...)
+}
+
+// stop whatever has to stop for a new play of name at gain. returns false if the new sound should not
+// play at all
+func (manager *SoundManager) makeRoom(name audioFiles.AudioName, limit int64, gain float64) bool {
+	// a new play of a sound that is already at its limit replaces its oldest play
+	same := countVoices(manager.voices, func(voice *mixerVoice) bool {
+		return voice.name == name
+	})
+	if int64(same) >= limit {
+		manager.stopVoice(oldestVoice(manager.voices, name))
+	}
+
+	effects := countVoices(manager.voices, func(voice *mixerVoice) bool {
+		return voice.bus != BusMusic
+	})
+	if effects >= MaxVoices {
+		victim := chooseVictim(manager.voices, gain)
+		if victim == -1 {
+			return false
+		}
+		manager.stopVoice(victim)
+	}
+
+	return true
+}
+
+// play a player made by the handler once there is room for it. the player is made before anything is
+// stopped, so a sound that fails to load does not cut off another one for nothing
+func (manager *SoundManager) playVoice(name audioFiles.AudioName, handler *SoundHandler, player *audio.Player, finish func(), gain float64) {
+	if !manager.makeRoom(name, handler.PlayLimit, gain) {
+		finish()
+		return
+	}
+
+	manager.addVoice(name, handler.Bus, handler.Volume, player, finish, gain)
+}
+
+// start playing a player on the bus and keep its volume up to date until it finishes
+func (manager *SoundManager) addVoice(name audioFiles.AudioName, bus AudioBus, volume float64, player *audio.Player, finish func(), gain float64) {
+	manager.voiceSequence += 1
+	manager.voices = append(manager.voices, &mixerVoice{
+		name:     name,
+		bus:      bus,
+		player:   player,
+		finish:   finish,
+		volume:   volume,
+		gain:     gain,
+		sequence: manager.voiceSequence,
+	})
+
+	player.SetVolume(manager.busGain(bus) * volume)
+	player.Play()
+}
+
+// called once per tick to move the ducking along, apply the bus volumes and finish voices that are done
+func (manager *SoundManager) Update() {
+	if manager.duckHold > 0 {
+		manager.duckHold -= 1
+		manager.duck = moveTowards(manager.duck, manager.duckLevel, 1.0/DuckAttackTicks)
+	} else {
+		manager.duck = moveTowards(manager.duck, 1, 1.0/DuckReleaseTicks)
+	}
+
+	playing := manager.voices[:0]
+	for _, voice := range manager.voices {
+		if !voice.player.IsPlaying() {
+			if voice.finish != nil {
+				voice.finish()
+			}
+			continue
+		}
+
+		voice.player.SetVolume(manager.busGain(voice.bus) * voice.volume)
+		playing = append(playing, voice)
+	}
+	clear(manager.voices[len(playing):])
+	manager.voices = playing
+}
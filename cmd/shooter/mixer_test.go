@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	audioFiles "github.com/kazzmir/webgl-shooter/audio"
+)
+
+func TestChooseVictim(t *testing.T) {
+	voices := []*mixerVoice{
+		{name: audioFiles.AudioChillSong, bus: BusMusic, gain: 0, sequence: 1},
+		{name: audioFiles.AudioShoot1, bus: BusWeapons, gain: 0.5, sequence: 2},
+		{name: audioFiles.AudioHit1, bus: BusWeapons, gain: 0.2, sequence: 3},
+		{name: audioFiles.AudioExplosion3, bus: BusExplosions, gain: 0.2, sequence: 4},
+	}
+
+	// the music is never stolen, and of the two quietest effects the older one goes
+	if victim := chooseVictim(voices, 1); victim != 2 {
+		t.Errorf("stole voice %v, want 2", victim)
+	}
+
+	// a new sound quieter than everything playing is dropped instead
+	if victim := chooseVictim(voices, 0.1); victim != -1 {
+		t.Errorf("stole voice %v for a quiet sound", victim)
+	}
+
+	if victim := chooseVictim(voices[:1], 1); victim != -1 {
+		t.Errorf("stole voice %v with only music playing", victim)
+	}
+}
+
+func TestOldestVoice(t *testing.T) {
+	voices := []*mixerVoice{
+		{name: audioFiles.AudioHit1, sequence: 5},
+		{name: audioFiles.AudioShoot1, sequence: 2},
+		{name: audioFiles.AudioHit1, sequence: 3},
+	}
+
+	if oldest := oldestVoice(voices, audioFiles.AudioHit1); oldest != 2 {
+		t.Errorf("oldest hit is %v, want 2", oldest)
+	}
+	if oldest := oldestVoice(voices, audioFiles.AudioBeep); oldest != -1 {
+		t.Errorf("found %v for a sound that is not playing", oldest)
+	}
+}
+
+func TestDuck(t *testing.T) {
+	manager := &SoundManager{duck: 1}
+	manager.SetVolume(BusMusic, 50)
+	manager.SetVolume(BusWeapons, 50)
+
+	manager.Duck(DuckBigExplosion)
+	manager.Duck(DuckBossRoar)
+	manager.Duck(DuckBigExplosion)
+	for range DuckAttackTicks {
+		manager.Update()
+	}
+
+	// overlapping ducks keep the deepest one, and only the music is ducked
+	if math.Abs(manager.busGain(BusMusic)-0.5*DuckBossRoar) > 1e-9 {
+		t.Errorf("ducked music gain is %v", manager.busGain(BusMusic))
+	}
+	if manager.busGain(BusWeapons) != 0.5 {
+		t.Errorf("weapons gain is %v", manager.busGain(BusWeapons))
+	}
+
+	for range DuckHoldTicks + DuckReleaseTicks {
+		manager.Update()
+	}
+	if manager.busGain(BusMusic) != 0.5 {
+		t.Errorf("music gain is %v after the duck", manager.busGain(BusMusic))
+	}
+}
+
+func TestManifestBuses(t *testing.T) {
+	library, err := audioFiles.OpenLibrary("")
+	if err != nil {
+		t.Fatalf("unable to open sounds: %v", err)
+	}
+
+	for name, definition := range library.Manifest {
+		if _, err := parseAudioBus(definition.Bus); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+	}
+
+	for _, bus := range audioBuses {
+		if parsed, err := parseAudioBus(bus.String()); err != nil || parsed != bus {
+			t.Errorf("%v parsed as %v: %v", bus, parsed, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// the mouse in menus, and in the game when the guns aim at the cursor
+type MouseInput struct {
+	// the guns point at the cursor and the left button fires, set from the controls menu
+	Aim bool
+
+	// where the cursor was last frame. hovering only selects an option once the cursor moves, so a
+	// cursor resting on an option does not fight the keyboard
+	lastX float64
+	lastY float64
+	moved bool
+	// the option whose slider is being dragged, or -1
+	dragging int
+}
+
+func MakeMouseInput() *MouseInput {
+	return &MouseInput{
+		dragging: -1,
+	}
+}
+
+// the name the mouse settings are saved under, see loadSetting
+const mouseSetting = "mouse.json"
+
+// the part of the mouse that is kept between runs
+type savedMouse struct {
+	Aim bool `json:"aim"`
+}
+
+// a mouse with whatever was saved from the controls menu
+func LoadMouseInput() *MouseInput {
+	mouse := MakeMouseInput()
+
+	data, err := loadSetting(mouseSetting)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Unable to load mouse settings: %v", err)
+		}
+		return mouse
+	}
+
+	var saved savedMouse
+	if err := json.Unmarshal(data, &saved); err != nil {
+		log.Printf("Unable to read saved mouse settings: %v", err)
+		return mouse
+	}
+
+	mouse.Aim = saved.Aim
+	return mouse
+}
+
+func (mouse *MouseInput) Save() {
+	data, err := json.MarshalIndent(savedMouse{Aim: mouse.Aim}, "", "  ")
+	if err != nil {
+		log.Printf("Unable to save mouse settings: %v", err)
+		return
+	}
+	if err := saveSetting(mouseSetting, data); err != nil {
+		log.Printf("Unable to save mouse settings: %v", err)
+	}
+}
+
+// where the cursor is on the view
+func cursorPosition(display *DisplaySettings) (float64, float64) {
+	x, y := ebiten.CursorPosition()
+	return display.PointerPosition(x, y)
+}
+
+// called once per frame. aiming shows a crosshair instead of the usual cursor
+func (mouse *MouseInput) Update(display *DisplaySettings, aiming bool) {
+	x, y := cursorPosition(display)
+	mouse.moved = x != mouse.lastX || y != mouse.lastY
+	mouse.lastX = x
+	mouse.lastY = y
+
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		mouse.dragging = -1
+	}
+
+	if aiming {
+		ebiten.SetCursorShape(ebiten.CursorShapeCrosshair)
+	} else {
+		ebiten.SetCursorShape(ebiten.CursorShapeDefault)
+	}
+}
+
+// the keys a list of options should see for the mouse this frame. moving over an option selects it and
+// clicking it works like a tap, see optionKeys. the wheel turns the option under the cursor up and down,
+// and a slider follows the cursor while it is dragged
+func (mouse *MouseInput) MenuKeys(run *Run, layout *OptionLayout, options []*MenuOption, selected *int) []ebiten.Key {
+	cursorX, cursorY := cursorPosition(run.Display)
+
+	if mouse.dragging >= 0 {
+		// the drag keeps going when the cursor slips off the bar
+		if mouse.dragging < len(options) && options[mouse.dragging].Slider != nil {
+			options[mouse.dragging].Slider.Set(run, layout.SliderValue(mouse.dragging, cursorX))
+		}
+		return nil
+	}
+
+	hovered, _ := layout.At(cursorX, cursorY)
+	slider, value := layout.SliderAt(cursorX, cursorY)
+	if slider != -1 {
+		hovered = slider
+	}
+	if hovered < 0 || hovered >= len(options) {
+		return nil
+	}
+
+	if mouse.moved {
+		*selected = hovered
+	}
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		if slider != -1 && options[slider].Slider != nil {
+			*selected = slider
+			mouse.dragging = slider
+			options[slider].Slider.Set(run, value)
+			return nil
+		}
+		return optionKeys(layout, options, selected, []touchPoint{{x: cursorX, y: cursorY}})
+	}
+
+	option := options[hovered]
+	_, wheel := ebiten.Wheel()
+	switch {
+	case wheel > 0 && option.DoesRespond(ebiten.KeyArrowRight):
+		*selected = hovered
+		return []ebiten.Key{ebiten.KeyArrowRight}
+	case wheel < 0 && option.DoesRespond(ebiten.KeyArrowLeft):
+		*selected = hovered
+		return []ebiten.Key{ebiten.KeyArrowLeft}
+	}
+
+	return nil
+}
+
+// the direction from the player to the cursor and the left button as the fire button. the cursor is
+// on the screen so it is moved into the world through the camera the player is drawn with
+func (mouse *MouseInput) PlayerInput(game *Game, player *Player) playerInputState {
+	var input playerInputState
+	if !mouse.Aim {
+		return input
+	}
+
+	x, y := cursorPosition(game.Display)
+	camera := game.Camera
+	left := 0.0
+	if game.Coop != nil && game.Coop.Split {
+		for i, followed := range game.Coop.Followed {
+			if followed == player {
+				camera = game.Coop.Views[i]
+				left = float64(i) * camera.Width()
+			}
+		}
+	}
+
+	input.AimX = camera.x + x - left - player.x
+	input.AimY = camera.y + y - player.y
+	input.Shoot = ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+	return input
+}
+
+// keys from tapping or clicking on a list of options. the mouse is left alone while the touch controls
+// are showing so a tap is not also read as a click
+func (run *Run) pointerKeys(layout *OptionLayout, options []*MenuOption, selected *int) []ebiten.Key {
+	if run.Touch != nil && run.Touch.Active {
+		return run.Touch.MenuKeys(layout, options, selected)
+	}
+	if run.Mouse != nil {
+		return run.Mouse.MenuKeys(run, layout, options, selected)
+	}
+	return nil
+}
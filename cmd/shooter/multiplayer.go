@@ -30,6 +30,14 @@ type playerInputState struct {
 	Shoot     bool    `json:"shoot"`
 	OpenMenu  bool    `json:"open_menu"`
 	ToggleGun [5]bool `json:"toggle_gun"`
+	// analog movement from a controller stick, from -1 to 1
+	MoveX float64 `json:"move_x,omitempty"`
+	MoveY float64 `json:"move_y,omitempty"`
+	// switch to the next gun if positive or the previous one if negative, see cycleGun
+	CycleGun int `json:"cycle_gun,omitempty"`
+	// the direction the guns point in, straight up if both are 0, see MakeAim
+	AimX float64 `json:"aim_x,omitempty"`
+	AimY float64 `json:"aim_y,omitempty"`
 }
 
 type multiplayerEnvelope struct {
@@ -44,16 +52,22 @@ type multiplayerEnvelope struct {
 	PowerupCollected *powerupCollectedMessage `json:"powerup_collected,omitempty"`
 	Spawn            *spawnMessage            `json:"spawn,omitempty"`
 	Snapshot         *snapshotMessage         `json:"snapshot,omitempty"`
+	Loadout          *Loadout                 `json:"loadout,omitempty"`
 }
 
 type startGameMessage struct {
-	Difficulty float64          `json:"difficulty"`
-	Background gameImages.Image `json:"background,omitempty"`
+	Difficulty float64 `json:"difficulty"`
+	// name of the background definition, see background.go
+	Background string `json:"background,omitempty"`
+	// the master's loadout, used to build its player on the slave
+	Loadout Loadout `json:"loadout"`
+	Arcade  bool    `json:"arcade,omitempty"`
 }
 
 type levelStartMessage struct {
-	Difficulty float64          `json:"difficulty"`
-	Background gameImages.Image `json:"background,omitempty"`
+	Difficulty float64 `json:"difficulty"`
+	// name of the background definition, see background.go
+	Background string `json:"background,omitempty"`
 }
 
 type latencyPingMessage struct {
@@ -72,6 +86,8 @@ type lightningShotMessage struct {
 	Y         float64 `json:"y"`
 	Level     int     `json:"level"`
 	Owner     string  `json:"owner"`
+	AimX      float64 `json:"aim_x,omitempty"`
+	AimY      float64 `json:"aim_y,omitempty"`
 }
 
 type powerupCollectedMessage struct {
@@ -104,6 +120,11 @@ type gameMultiplayer struct {
 	Peer                     PeerConnector
 	RemoteInput              playerInputState
 	PendingCollectedPowerups []powerupState
+	// size of the last snapshot sent or received, shown by the debug overlay
+	SnapshotBytes int
+	// the master has heard the partner's player state since the level started. until then its copy of the
+	// partner is from before the shop, so it is left out of snapshots or it would undo what was bought
+	PartnerHeard bool
 }
 
 type playerState struct {
@@ -125,14 +146,28 @@ type playerState struct {
 	Experience    float64    `json:"experience"`
 	Guns          []gunState `json:"guns"`
 	RespawnBlink  int        `json:"respawn_blink"`
+
+	MaxEnergyBonus   float64 `json:"max_energy_bonus"`
+	EnergyRegenBonus float64 `json:"energy_regen_bonus"`
+	BombCapacity     int     `json:"bomb_capacity"`
+	Credits          uint64  `json:"credits"`
+
+	Ship ShipKind `json:"ship,omitempty"`
+
+	Lives          int    `json:"lives"`
+	Continues      int    `json:"continues"`
+	NextExtraLife  uint64 `json:"next_extra_life"`
+	ReviveProgress int    `json:"revive_progress"`
 }
 
 type gunState struct {
-	Kind       string  `json:"kind"`
-	Enabled    bool    `json:"enabled"`
-	Level      int     `json:"level"`
-	Experience float64 `json:"experience"`
-	Counter    int     `json:"counter"`
+	Kind       string    `json:"kind"`
+	Enabled    bool      `json:"enabled"`
+	Level      int       `json:"level"`
+	Experience float64   `json:"experience"`
+	Counter    int       `json:"counter"`
+	Branch     GunBranch `json:"branch,omitempty"`
+	Tier       int       `json:"tier,omitempty"`
 }
 
 type bulletState struct {
@@ -151,6 +186,7 @@ type bulletState struct {
 	LightningX     float64     `json:"lightning_x"`
 	LightningY     float64     `json:"lightning_y"`
 	LightningLevel int         `json:"lightning_level"`
+	Angle          float64     `json:"angle,omitempty"`
 }
 
 type asteroidState struct {
@@ -194,6 +230,8 @@ type enemyState struct {
 	Flip     bool          `json:"flip"`
 	Hurt     int           `json:"hurt"`
 	Movement movementState `json:"movement"`
+	MaxLife  float64       `json:"max_life,omitempty"`
+	Shield   float64       `json:"shield,omitempty"`
 }
 
 type movementState struct {
@@ -207,45 +245,46 @@ type movementState struct {
 	MoveX     float64 `json:"move_x"`
 	MoveY     float64 `json:"move_y"`
 	Counter   uint64  `json:"counter"`
+	// the top of the area the boss moves around in
+	Top float64 `json:"top,omitempty"`
+
+	// used by the behaviors in behavior.go
+	HomeX     float64        `json:"home_x,omitempty"`
+	HomeY     float64        `json:"home_y,omitempty"`
+	BaseX     float64        `json:"base_x,omitempty"`
+	Direction float64        `json:"direction,omitempty"`
+	Distance  float64        `json:"distance,omitempty"`
+	Spawned   int            `json:"spawned,omitempty"`
+	Active    bool           `json:"active,omitempty"`
+	Inner     *movementState `json:"inner,omitempty"`
 }
 
-func gatherLocalPlayerInput() playerInputState {
-	input := playerInputState{}
-	keys := inpututil.AppendPressedKeys(nil)
-	for _, key := range keys {
-		switch key {
-		case ebiten.KeyArrowUp:
-			input.Up = true
-		case ebiten.KeyArrowDown:
-			input.Down = true
-		case ebiten.KeyArrowLeft:
-			input.Left = true
-		case ebiten.KeyArrowRight:
-			input.Right = true
-		case ebiten.KeyShift:
-			input.Jump = true
-		case ebiten.KeyB:
-			input.Bomb = true
-		case ebiten.KeySpace:
-			input.Shoot = true
-		}
+// what the keyboard is doing for a local player, on the default keys when there are no controls
+func gatherLocalPlayerInput(controls *Controls) playerInputState {
+	if controls == nil {
+		defaults := DefaultControls()
+		controls = &defaults
 	}
 
-	for _, key := range inpututil.AppendJustPressedKeys(nil) {
-		switch key {
-		case ebiten.KeyEscape, ebiten.KeyCapsLock:
-			input.OpenMenu = true
-		case ebiten.KeyDigit1:
-			input.ToggleGun[0] = true
-		case ebiten.KeyDigit2:
-			input.ToggleGun[1] = true
-		case ebiten.KeyDigit3:
-			input.ToggleGun[2] = true
-		case ebiten.KeyDigit4:
-			input.ToggleGun[3] = true
-		case ebiten.KeyDigit5:
-			input.ToggleGun[4] = true
-		}
+	input := playerInputState{
+		Up:       controls.KeyPressed(ActionUp),
+		Down:     controls.KeyPressed(ActionDown),
+		Left:     controls.KeyPressed(ActionLeft),
+		Right:    controls.KeyPressed(ActionRight),
+		Jump:     controls.KeyPressed(ActionJump),
+		Bomb:     controls.KeyPressed(ActionBomb),
+		Shoot:    controls.KeyPressed(ActionShoot),
+		OpenMenu: inpututil.IsKeyJustPressed(ebiten.KeyEscape) || controls.KeyJustPressed(ActionMenu),
+	}
+
+	for i := range input.ToggleGun {
+		input.ToggleGun[i] = controls.KeyJustPressed(ActionWeapon1 + InputAction(i))
+	}
+	if controls.KeyJustPressed(ActionNextGun) {
+		input.CycleGun += 1
+	}
+	if controls.KeyJustPressed(ActionPreviousGun) {
+		input.CycleGun -= 1
 	}
 
 	return input
@@ -259,6 +298,10 @@ func (player *Player) ApplyInput(game *Game, run *Run, input playerInputState, a
 		maxVelocity = 5.5
 	}
 
+	speed := shipDefinition(player.Ship).Speed
+	playerAccel *= speed
+	maxVelocity *= speed
+
 	if input.Up {
 		player.velocityY -= playerAccel
 	}
@@ -271,6 +314,8 @@ func (player *Player) ApplyInput(game *Game, run *Run, input playerInputState, a
 	if input.Right {
 		player.velocityX += playerAccel
 	}
+	player.velocityX += playerAccel * input.MoveX
+	player.velocityY += playerAccel * input.MoveY
 	if input.Jump && player.Jump <= -50 {
 		player.Jump = JumpDuration
 	}
@@ -281,7 +326,7 @@ func (player *Player) ApplyInput(game *Game, run *Run, input playerInputState, a
 		player.BombCounter = BombDelay
 	}
 	if (allowProjectiles || game.isSlave()) && input.Shoot {
-		game.AddPlayerBullets(game.Player.Shoot(game.ImageManager, game.SoundManager)...)
+		game.AddPlayerBullets(player, player.Shoot(game.ImageManager, game.SoundManager, MakeAim(input.AimX, input.AimY))...)
 	}
 
 	player.velocityX = math.Min(maxVelocity, math.Max(-maxVelocity, player.velocityX))
@@ -295,6 +340,7 @@ func (player *Player) ApplyInput(game *Game, run *Run, input playerInputState, a
 			enableGun(player.Guns, i)
 		}
 	}
+	cycleGun(player.Guns, input.CycleGun)
 
 	if player.Jump > -50 {
 		player.Jump -= 1
@@ -303,36 +349,43 @@ func (player *Player) ApplyInput(game *Game, run *Run, input playerInputState, a
 	return nil
 }
 
-func (run *Run) StartGame(role string, notifyPeer bool, backdropName gameImages.Image) error {
+// remoteLoadout is the loadout of the other player, and is only used in multiplayer
+func (run *Run) StartGame(role string, notifyPeer bool, backgroundName string, remoteLoadout Loadout) error {
 	run.Mode = RunGame
 
 	if run.Game != nil {
 		run.Game.Cancel()
 	}
 
-	player, err := MakePlayer(0, 0, run.Cheats)
+	player, err := MakePlayer(0, 0, run.Cheats, run.Loadout)
 	if err != nil {
 		return err
 	}
+	if run.Arcade {
+		player.StartArcade()
+	}
 	run.Player = player
 
-	game, err := MakeGame(run.SoundManager, run, 1, backdropName)
+	game, err := MakeGame(run.SoundManager, run, 1, backgroundName)
 	if err != nil {
 		return err
 	}
 
 	if role != "" {
-		remotePlayer, err := MakePlayer(0, 0, false)
+		remotePlayer, err := MakePlayer(0, 0, false, remoteLoadout)
 		if err != nil {
 			return err
 		}
+		if run.Arcade {
+			remotePlayer.StartArcade()
+		}
 		game.Multiplayer = &gameMultiplayer{
 			Role: role,
 			Peer: run.PeerConnector,
 		}
 		game.RemotePlayer = remotePlayer
 		baseX := float64(LogicalWidth) / 2
-		baseY := float64(ScreenHeight - 100)
+		baseY := playerStartY(game.Camera.WorldHeight())
 		if role == multiplayerRoleMaster {
 			game.Player.x = baseX - multiplayerSpawnOffset
 			game.RemotePlayer.x = baseX + multiplayerSpawnOffset
@@ -353,7 +406,7 @@ func (run *Run) StartGame(role string, notifyPeer bool, backdropName gameImages.
 		if notifyPeer && role == multiplayerRoleMaster && run.PeerConnector != nil {
 			if err := run.PeerConnector.SendGameMessage(multiplayerEnvelope{
 				Kind:      "start_game",
-				StartGame: &startGameMessage{Difficulty: game.Difficulty, Background: game.Background.BackdropName},
+				StartGame: &startGameMessage{Difficulty: game.Difficulty, Background: game.Background.Name, Loadout: run.Loadout, Arcade: run.Arcade},
 			}); err != nil {
 				log.Printf("Unable to send start game message: %v", err)
 			}
@@ -365,15 +418,15 @@ func (run *Run) StartGame(role string, notifyPeer bool, backdropName gameImages.
 	return nil
 }
 
-func (run *Run) setupNextLevel(difficulty float64, role string, remotePlayer *Player, backdropName gameImages.Image) (*Game, error) {
-	game, err := MakeGame(run.SoundManager, run, difficulty, backdropName)
+func (run *Run) setupNextLevel(difficulty float64, role string, remotePlayer *Player, backgroundName string) (*Game, error) {
+	game, err := MakeGame(run.SoundManager, run, difficulty, backgroundName)
 	if err != nil {
 		return nil, err
 	}
 
 	if role != "" {
 		if remotePlayer == nil {
-			remotePlayer, err = MakePlayer(0, 0, false)
+			remotePlayer, err = MakePlayer(0, 0, false, DefaultLoadout())
 			if err != nil {
 				return nil, err
 			}
@@ -384,7 +437,7 @@ func (run *Run) setupNextLevel(difficulty float64, role string, remotePlayer *Pl
 		}
 		game.RemotePlayer = remotePlayer
 		baseX := float64(LogicalWidth) / 2
-		baseY := float64(ScreenHeight - 100)
+		baseY := playerStartY(game.Camera.WorldHeight())
 		if role == multiplayerRoleMaster {
 			game.Player.x = baseX - multiplayerSpawnOffset
 			game.RemotePlayer.x = baseX + multiplayerSpawnOffset
@@ -407,18 +460,22 @@ func (run *Run) setupNextLevel(difficulty float64, role string, remotePlayer *Pl
 	return game, nil
 }
 
-func (run *Run) StartNextLevel(difficulty float64, notifyPeer bool, backdropName gameImages.Image) error {
+func (run *Run) StartNextLevel(difficulty float64, notifyPeer bool, backgroundName string) error {
 	role := ""
 	var remotePlayer *Player
 	if run.Game != nil && run.Game.Multiplayer != nil {
 		role = run.Game.Multiplayer.Role
 		remotePlayer = run.Game.RemotePlayer
 	}
+	local := run.Game != nil && run.Game.Coop != nil
 
-	game, err := run.setupNextLevel(difficulty, role, remotePlayer, backdropName)
+	game, err := run.setupNextLevel(difficulty, role, remotePlayer, backgroundName)
 	if err != nil {
 		return err
 	}
+	if local {
+		game.startLocalCoop(run)
+	}
 
 	if run.Game != nil {
 		run.Game.Close()
@@ -429,7 +486,7 @@ func (run *Run) StartNextLevel(difficulty float64, notifyPeer bool, backdropName
 	if notifyPeer && game.isMaster() && game.Multiplayer != nil && game.Multiplayer.Peer != nil {
 		if err := game.Multiplayer.Peer.SendGameMessage(multiplayerEnvelope{
 			Kind:       "level_start",
-			LevelStart: &levelStartMessage{Difficulty: difficulty, Background: game.Background.BackdropName},
+			LevelStart: &levelStartMessage{Difficulty: difficulty, Background: game.Background.Name},
 		}); err != nil {
 			log.Printf("Unable to send level start message: %v", err)
 		}
@@ -446,8 +503,12 @@ func (run *Run) handleMenuMultiplayerMessages(messages [][]byte) error {
 			log.Printf("Unable to decode peer message: %v", err)
 			continue
 		}
+		if envelope.Kind == "loadout" && envelope.Loadout != nil && run.PeerConnector != nil && run.PeerConnector.IsMaster() {
+			run.PeerLoadout = *envelope.Loadout
+		}
 		if envelope.Kind == "start_game" && run.PeerConnector != nil && run.PeerConnector.IsSlave() {
-			return run.StartGame(multiplayerRoleSlave, false, envelope.StartGame.Background)
+			run.Arcade = envelope.StartGame.Arcade
+			return run.StartGame(multiplayerRoleSlave, false, envelope.StartGame.Background, envelope.StartGame.Loadout)
 		}
 	}
 	return nil
@@ -462,7 +523,47 @@ func (game *Game) isSlave() bool {
 }
 
 func (game *Game) resolvePlayerInput() playerInputState {
-	return gatherLocalPlayerInput()
+	// keys typed into the console should not also fly the ship
+	if game.Console.IsCapturing() {
+		return playerInputState{}
+	}
+	input := gatherLocalPlayerInput(game.Controls)
+	if game.Gamepads != nil {
+		input = mergeInput(input, game.Gamepads.PlayerInput(0))
+	}
+	if game.Touch != nil {
+		input = mergeInput(input, game.Touch.PlayerInput())
+	}
+	if game.Mouse != nil {
+		input = mergeInput(input, game.Mouse.PlayerInput(game, game.Player))
+	}
+	return input
+}
+
+// the input of a local player for one tick. presses only count on the first tick that runs after they
+// were read, see ReadInput
+func (game *Game) takeInput(player int) playerInputState {
+	input := game.frameInput[player]
+	game.frameInput[player] = clearPresses(input)
+	return input
+}
+
+// the input read this frame, with any presses from a frame that ran no ticks still waiting
+func keepPresses(waiting playerInputState, input playerInputState) playerInputState {
+	input.OpenMenu = input.OpenMenu || waiting.OpenMenu
+	for i := range input.ToggleGun {
+		input.ToggleGun[i] = input.ToggleGun[i] || waiting.ToggleGun[i]
+	}
+	input.CycleGun += waiting.CycleGun
+	return input
+}
+
+// the input without the keys that only count the moment they are pressed
+func clearPresses(input playerInputState) playerInputState {
+	input.OpenMenu = false
+	input.ToggleGun = [5]bool{}
+	input.CycleGun = 0
+	return input
 }
 
 func (game *Game) processNetworkMessages(run *Run, messages [][]byte) error {
@@ -477,6 +578,7 @@ func (game *Game) processNetworkMessages(run *Run, messages [][]byte) error {
 		case "player_state":
 			if envelope.PlayerState != nil && game.RemotePlayer != nil {
 				applyPlayerState(game.RemotePlayer, *envelope.PlayerState)
+				game.Multiplayer.PartnerHeard = true
 			}
 		case "bullet_made":
 			if game.isMaster() && envelope.BulletMade != nil {
@@ -496,6 +598,7 @@ func (game *Game) processNetworkMessages(run *Run, messages [][]byte) error {
 			}
 		case "snapshot":
 			if game.isSlave() && envelope.Snapshot != nil {
+				game.Multiplayer.SnapshotBytes = len(raw)
 				if err := game.applySnapshot(*envelope.Snapshot); err != nil {
 					return err
 				}
@@ -508,7 +611,12 @@ func (game *Game) processNetworkMessages(run *Run, messages [][]byte) error {
 			}
 		case "level_start":
 			if game.isSlave() && envelope.LevelStart != nil {
-				return run.StartNextLevel(envelope.LevelStart.Difficulty, false, envelope.LevelStart.Background)
+				if err := run.StartNextLevel(envelope.LevelStart.Difficulty, false, envelope.LevelStart.Background); err != nil {
+					return err
+				}
+				// the master sends our player back once it has this, with whatever was bought in the shop
+				run.Game.maybeSendPlayerState()
+				return nil
 			}
 		}
 	}
@@ -528,6 +636,25 @@ func (game *Game) sendSnapshot() {
 		return
 	}
 
+	snapshot := game.makeSnapshot()
+
+	// the peer connector encodes the message itself, so only pay for measuring it when someone is looking
+	if game.Debug.IsEnabled() {
+		if payload, err := json.Marshal(snapshot); err == nil {
+			game.Multiplayer.SnapshotBytes = len(payload)
+		}
+	}
+
+	if err := game.Multiplayer.Peer.SendGameMessage(multiplayerEnvelope{
+		Kind:     "snapshot",
+		Snapshot: &snapshot,
+	}); err != nil && game.Counter%120 == 0 {
+		log.Printf("Unable to send snapshot: %v", err)
+	}
+}
+
+// the whole state of the world as the master sees it
+func (game *Game) makeSnapshot() snapshotMessage {
 	snapshot := snapshotMessage{
 		Counter:      game.Counter,
 		Difficulty:   game.Difficulty,
@@ -541,17 +668,12 @@ func (game *Game) sendSnapshot() {
 		BossMode:     game.BossMode,
 		End:          game.End.Load(),
 	}
-	if game.RemotePlayer != nil {
+	if game.RemotePlayer != nil && game.Multiplayer != nil && game.Multiplayer.PartnerHeard {
 		slavePlayer := serializePlayer(game.RemotePlayer)
 		snapshot.SlavePlayer = &slavePlayer
 	}
 
-	if err := game.Multiplayer.Peer.SendGameMessage(multiplayerEnvelope{
-		Kind:     "snapshot",
-		Snapshot: &snapshot,
-	}); err != nil && game.Counter%120 == 0 {
-		log.Printf("Unable to send snapshot: %v", err)
-	}
+	return snapshot
 }
 
 func (game *Game) maybeSendPlayerState() {
@@ -568,11 +690,15 @@ func (game *Game) maybeSendPlayerState() {
 	}
 }
 
-func (game *Game) AddPlayerBullets(bullets ...*Bullet) {
+func (game *Game) AddPlayerBullets(player *Player, bullets ...*Bullet) {
 	if len(bullets) == 0 {
 		return
 	}
 	owner := game.localBulletOwner()
+	// the partner only shoots on this machine when both players are on it
+	if player == game.RemotePlayer {
+		owner = multiplayerRoleSlave
+	}
 	for _, bullet := range bullets {
 		bullet.Owner = owner
 		bullet.GunKind = gunKindFromGun(bullet.Gun)
@@ -813,6 +939,8 @@ func (game *Game) sendLightningShot(createdAt uint64, bullet *Bullet) {
 			Y:         bullet.LightningOriginY,
 			Level:     bullet.LightningLevel,
 			Owner:     bullet.Owner,
+			AimX:      bullet.LightningAim.X,
+			AimY:      bullet.LightningAim.Y,
 		},
 	}); err != nil && game.Counter%120 == 0 {
 		log.Printf("Unable to send lightning_shot: %v", err)
@@ -826,7 +954,7 @@ func (game *Game) makeLightningShotBullets(message lightningShotMessage) ([]*Bul
 		elementType: ElementLightning,
 	}
 
-	bullets, err := gun.ShootWithSeed(game.ImageManager, message.X, message.Y, message.Seed)
+	bullets, err := gun.ShootWithSeed(game.ImageManager, message.X, message.Y, MakeAim(message.AimX, message.AimY), message.Seed)
 	if err != nil {
 		return nil, err
 	}
@@ -843,7 +971,7 @@ func (game *Game) makeLightningShotBullets(message lightningShotMessage) ([]*Bul
 		bullet.Gun = ownerGun
 	}
 
-	return bullets, nil
+	return applyGunUpgrades(ownerGun, bullets), nil
 }
 
 func (game *Game) noteCollectedPowerup(powerup Powerup) {
@@ -938,6 +1066,18 @@ func serializePlayer(player *Player) playerState {
 		Experience:    player.Experience,
 		Guns:          serializeGuns(player.Guns),
 		RespawnBlink:  player.RespawnBlink,
+
+		MaxEnergyBonus:   player.MaxEnergyBonus,
+		EnergyRegenBonus: player.EnergyRegenBonus,
+		BombCapacity:     player.BombCapacity,
+		Credits:          player.Credits,
+
+		Ship: player.Ship,
+
+		Lives:          player.Lives,
+		Continues:      player.Continues,
+		NextExtraLife:  player.NextExtraLife,
+		ReviveProgress: player.ReviveProgress,
 	}
 }
 
@@ -960,23 +1100,45 @@ func applyPlayerState(player *Player, state playerState) {
 	player.Experience = state.Experience
 	player.Guns = makeGunsFromState(state.Guns)
 	player.RespawnBlink = state.RespawnBlink
+	player.MaxEnergyBonus = state.MaxEnergyBonus
+	player.EnergyRegenBonus = state.EnergyRegenBonus
+	if state.BombCapacity > 0 {
+		player.BombCapacity = state.BombCapacity
+	}
+	player.Credits = state.Credits
+	player.Lives = state.Lives
+	player.Continues = state.Continues
+	player.NextExtraLife = state.NextExtraLife
+	player.ReviveProgress = state.ReviveProgress
+	if state.Ship != "" && state.Ship != player.Ship {
+		if err := player.SetShip(state.Ship); err != nil {
+			log.Printf("Unable to change ship to %v: %v", state.Ship, err)
+		}
+	}
 }
 
 func serializeGuns(guns []Gun) []gunState {
 	out := make([]gunState, 0, len(guns))
 	for _, gun := range guns {
+		var state gunState
 		switch current := gun.(type) {
 		case *BasicGun:
-			out = append(out, gunState{Kind: "basic", Enabled: current.enabled, Level: current.level, Experience: current.experience, Counter: current.counter})
+			state = gunState{Kind: "basic", Enabled: current.enabled, Level: current.level, Experience: current.experience, Counter: current.counter}
 		case *BeamGun:
-			out = append(out, gunState{Kind: "beam", Enabled: current.enabled, Level: current.level, Experience: current.experience, Counter: current.counter})
+			state = gunState{Kind: "beam", Enabled: current.enabled, Level: current.level, Experience: current.experience, Counter: current.counter}
 		case *MissleGun:
-			out = append(out, gunState{Kind: "missile", Enabled: current.enabled, Level: current.level, Experience: current.experience, Counter: current.counter})
+			state = gunState{Kind: "missile", Enabled: current.enabled, Level: current.level, Experience: current.experience, Counter: current.counter}
 		case *LightningGun:
-			out = append(out, gunState{Kind: "lightning", Enabled: current.enabled, Level: current.level, Experience: current.experience, Counter: current.counter})
+			state = gunState{Kind: "lightning", Enabled: current.enabled, Level: current.level, Experience: current.experience, Counter: current.counter}
 		case *DualBasicGun:
-			out = append(out, gunState{Kind: "dual-basic", Enabled: current.enabled, Level: current.level, Experience: current.experience, Counter: current.counter})
+			state = gunState{Kind: "dual-basic", Enabled: current.enabled, Level: current.level, Experience: current.experience, Counter: current.counter}
+		default:
+			continue
 		}
+		upgrades := gun.Upgrades()
+		state.Branch = upgrades.Branch
+		state.Tier = upgrades.Tier
+		out = append(out, state)
 	}
 	return out
 }
@@ -984,17 +1146,18 @@ func serializeGuns(guns []Gun) []gunState {
 func makeGunsFromState(states []gunState) []Gun {
 	out := make([]Gun, 0, len(states))
 	for _, state := range states {
+		upgrades := GunUpgrades{Branch: state.Branch, Tier: state.Tier}
 		switch state.Kind {
 		case "basic":
-			out = append(out, &BasicGun{enabled: state.Enabled, level: state.Level, experience: state.Experience, counter: state.Counter, elementType: ElementPhysical})
+			out = append(out, &BasicGun{enabled: state.Enabled, level: state.Level, experience: state.Experience, counter: state.Counter, elementType: ElementPhysical, upgrades: upgrades})
 		case "beam":
-			out = append(out, &BeamGun{enabled: state.Enabled, level: state.Level, experience: state.Experience, counter: state.Counter, elementType: ElementPlasma})
+			out = append(out, &BeamGun{enabled: state.Enabled, level: state.Level, experience: state.Experience, counter: state.Counter, elementType: ElementPlasma, upgrades: upgrades})
 		case "missile":
-			out = append(out, &MissleGun{enabled: state.Enabled, level: state.Level, experience: state.Experience, counter: state.Counter, elementType: ElementPhysical})
+			out = append(out, &MissleGun{enabled: state.Enabled, level: state.Level, experience: state.Experience, counter: state.Counter, elementType: ElementPhysical, upgrades: upgrades})
 		case "lightning":
-			out = append(out, &LightningGun{enabled: state.Enabled, level: state.Level, experience: state.Experience, counter: state.Counter, elementType: ElementLightning})
+			out = append(out, &LightningGun{enabled: state.Enabled, level: state.Level, experience: state.Experience, counter: state.Counter, elementType: ElementLightning, upgrades: upgrades})
 		case "dual-basic":
-			out = append(out, &DualBasicGun{enabled: state.Enabled, level: state.Level, experience: state.Experience, counter: state.Counter, elementType: ElementPhysical})
+			out = append(out, &DualBasicGun{enabled: state.Enabled, level: state.Level, experience: state.Experience, counter: state.Counter, elementType: ElementPhysical, upgrades: upgrades})
 		}
 	}
 	return out
@@ -1017,6 +1180,7 @@ func serializeBullet(bullet *Bullet) bulletState {
 		LightningX:     bullet.LightningOriginX,
 		LightningY:     bullet.LightningOriginY,
 		LightningLevel: bullet.LightningLevel,
+		Angle:          bullet.Angle,
 	}
 }
 
@@ -1098,6 +1262,7 @@ func (game *Game) makeBulletFromState(state bulletState) *Bullet {
 		LightningOriginX: state.LightningX,
 		LightningOriginY: state.LightningY,
 		LightningLevel:   state.LightningLevel,
+		Angle:            state.Angle,
 	}
 
 	ownerPlayer := game.bulletOwnerPlayer(bullet)
@@ -1209,6 +1374,8 @@ func serializePowerup(powerup Powerup) powerupState {
 		return powerupState{Kind: "weapon", X: current.x, Y: current.y, VelocityX: current.velocityX, VelocityY: current.velocityY, Activated: current.activated, Counter: current.counter}
 	case *PowerupBomb:
 		return powerupState{Kind: "bomb", X: current.x, Y: current.y, VelocityX: current.velocityX, VelocityY: current.velocityY, Activated: current.activated, Counter: current.counter}
+	case *PowerupEnergyIncrease:
+		return powerupState{Kind: "energy-increase", X: current.x, Y: current.y, VelocityX: current.velocityX, VelocityY: current.velocityY, Activated: current.activated, Counter: current.counter}
 	default:
 		return powerupState{}
 	}
@@ -1243,6 +1410,8 @@ func makePowerupFromState(state powerupState) (Powerup, error) {
 		return &PowerupWeapon{x: state.X, y: state.Y, velocityX: state.VelocityX, velocityY: state.VelocityY, activated: state.Activated, counter: state.Counter}, nil
 	case "bomb":
 		return &PowerupBomb{x: state.X, y: state.Y, velocityX: state.VelocityX, velocityY: state.VelocityY, activated: state.Activated, counter: state.Counter}, nil
+	case "energy-increase":
+		return &PowerupEnergyIncrease{x: state.X, y: state.Y, velocityX: state.VelocityX, velocityY: state.VelocityY, activated: state.Activated, counter: state.Counter, increase: 25}, nil
 	default:
 		return nil, fmt.Errorf("unknown powerup kind %q", state.Kind)
 	}
@@ -1261,6 +1430,8 @@ func serializeEnemy(enemy Enemy) enemyState {
 		Flip:     current.Flip,
 		Hurt:     current.hurt,
 		Movement: serializeMovement(current.move),
+		MaxLife:  current.MaxLife,
+		Shield:   current.Shield,
 	}
 }
 
@@ -1281,7 +1452,22 @@ func serializeMovement(move Movement) movementState {
 	case *CircularMovement:
 		return movementState{Kind: "circular", VelocityX: current.velocityX, VelocityY: current.velocityY, Radius: current.radius, Angle: float64(current.angle), Speed: current.speed}
 	case *Boss1Movement:
-		return movementState{Kind: "boss1", MoveX: current.moveX, MoveY: current.moveY, Counter: current.counter}
+		return movementState{Kind: "boss1", MoveX: current.moveX, MoveY: current.moveY, Counter: current.counter, Top: current.top}
+	case *KamikazeBehavior:
+		return movementState{Kind: string(BehaviorKamikaze), VelocityX: current.velocityX, VelocityY: current.velocityY, Counter: current.counter, Active: current.locked}
+	case *StraferBehavior:
+		return movementState{Kind: string(BehaviorStrafer), VelocityX: current.velocityX, VelocityY: current.velocityY, Counter: current.counter, Distance: current.distance, Direction: current.direction}
+	case *FleeBehavior:
+		state := movementState{Kind: string(BehaviorFlee), VelocityX: current.velocityX, VelocityY: current.velocityY, Active: current.fleeing}
+		if current.inner != nil {
+			inner := serializeMovement(current.inner)
+			state.Inner = &inner
+		}
+		return state
+	case *FormationBehavior:
+		return movementState{Kind: string(BehaviorFormation), VelocityX: current.velocityX, VelocityY: current.velocityY, Counter: current.counter, HomeX: current.homeX, HomeY: current.homeY, BaseX: current.baseX, Active: current.started}
+	case *CarrierBehavior:
+		return movementState{Kind: string(BehaviorCarrier), VelocityX: current.velocityX, VelocityY: current.velocityY, Counter: current.counter, Spawned: current.spawned}
 	default:
 		return movementState{}
 	}
@@ -1296,7 +1482,21 @@ func makeMovementFromState(state movementState) Movement {
 	case "circular":
 		return &CircularMovement{velocityX: state.VelocityX, velocityY: state.VelocityY, radius: state.Radius, angle: uint64(state.Angle), speed: state.Speed}
 	case "boss1":
-		return &Boss1Movement{moveX: state.MoveX, moveY: state.MoveY, counter: state.Counter}
+		return &Boss1Movement{moveX: state.MoveX, moveY: state.MoveY, counter: state.Counter, top: state.Top}
+	case string(BehaviorKamikaze):
+		return &KamikazeBehavior{velocityX: state.VelocityX, velocityY: state.VelocityY, counter: state.Counter, locked: state.Active}
+	case string(BehaviorStrafer):
+		return &StraferBehavior{velocityX: state.VelocityX, velocityY: state.VelocityY, counter: state.Counter, distance: state.Distance, direction: state.Direction}
+	case string(BehaviorFlee):
+		flee := &FleeBehavior{velocityX: state.VelocityX, velocityY: state.VelocityY, fleeing: state.Active}
+		if state.Inner != nil {
+			flee.inner = makeMovementFromState(*state.Inner)
+		}
+		return flee
+	case string(BehaviorFormation):
+		return &FormationBehavior{velocityX: state.VelocityX, velocityY: state.VelocityY, counter: state.Counter, homeX: state.HomeX, homeY: state.HomeY, baseX: state.BaseX, started: state.Active}
+	case string(BehaviorCarrier):
+		return &CarrierBehavior{velocityX: state.VelocityX, velocityY: state.VelocityY, counter: state.Counter, spawned: state.Spawned}
 	default:
 		return &LinearMovement{}
 	}
@@ -1347,6 +1547,8 @@ func (game *Game) makeEnemyFromState(state enemyState) (Enemy, error) {
 		current.Life = state.Life
 		current.hurt = state.Hurt
 		current.Flip = state.Flip
+		current.MaxLife = state.MaxLife
+		current.Shield = state.Shield
 		return current, nil
 	case "boss1":
 		pic, raw, err := game.ImageManager.LoadImage(gameImages.ImageBoss1)
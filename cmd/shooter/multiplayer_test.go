@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSnapshotWaitsForPartnerAfterShop(t *testing.T) {
+	partner := &Player{MaxHealth: 100, BombCapacity: 4, Guns: DefaultLoadout().Guns()}
+	game := &Game{
+		Player:       &Player{MaxHealth: 100, Guns: DefaultLoadout().Guns()},
+		RemotePlayer: partner,
+		Multiplayer:  &gameMultiplayer{Role: multiplayerRoleMaster},
+	}
+
+	// the copy of the partner from before the shop is not sent back
+	if snapshot := game.makeSnapshot(); snapshot.SlavePlayer != nil {
+		t.Fatalf("the partner was sent before it was heard from: %+v", snapshot.SlavePlayer)
+	}
+
+	shopped := serializePlayer(partner)
+	shopped.MaxHealth = 120
+	shopped.BombCapacity = 5
+	shopped.Credits = 30
+	data, err := json.Marshal(multiplayerEnvelope{Kind: "player_state", PlayerState: &shopped})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := game.processNetworkMessages(nil, [][]byte{data}); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := game.makeSnapshot()
+	if snapshot.SlavePlayer == nil || snapshot.SlavePlayer.MaxHealth != 120 || snapshot.SlavePlayer.BombCapacity != 5 || snapshot.SlavePlayer.Credits != 30 {
+		t.Errorf("the partner sent back is %+v", snapshot.SlavePlayer)
+	}
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log"
+	"math"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+
+	audioFiles "github.com/kazzmir/webgl-shooter/audio"
+)
+
+// seconds for one track to fade into another
+const MusicCrossfade = 2.0
+
+// seconds for a layer to fade in or out once its bar comes around
+const MusicLayerRamp = 1.0
+
+// how loud the music is while a stinger plays over it
+const MusicStingerDuck = 0.25
+
+// seconds for the music to duck under a stinger and come back afterwards
+const MusicDuckRamp = 0.3
+
+// this many enemies on screen brings in every layer
+const MusicBusyEnemies = 6
+
+// the layers of a track, split by frequency band. the low band carries the bass and drums, the mid band
+// the chords and melody, and the high band the hats and shimmer
+const (
+	MusicLayerLow = iota
+	MusicLayerMid
+	MusicLayerHigh
+	MusicLayers
+)
+
+// where the layers are split, in hz
+const MusicLowCutoff = 250
+const MusicHighCutoff = 2500
+
+type MusicTrack struct {
+	Name        audioFiles.AudioName
+	BPM         float64
+	BeatsPerBar int
+}
+
+var MusicLevel = MusicTrack{Name: audioFiles.AudioChillSong, BPM: 90, BeatsPerBar: 4}
+var MusicBoss = MusicTrack{Name: audioFiles.AudioStellarPulseSong, BPM: 128, BeatsPerBar: 4}
+
+func (track MusicTrack) FramesPerBar(sampleRate int) int {
+	return int(math.Round(float64(sampleRate) * 60 / track.BPM * float64(track.BeatsPerBar)))
+}
+
+// how loud each layer should be with this many enemies on screen. the low layer always plays so the
+// music never drops out, the rest come in as the screen fills up
+func musicLayerTargets(enemies int) [MusicLayers]float64 {
+	busy := math.Min(1, float64(enemies)/MusicBusyEnemies)
+	return [MusicLayers]float64{
+		MusicLayerLow:  1,
+		MusicLayerMid:  0.4 + 0.6*math.Min(1, busy*2),
+		MusicLayerHigh: 0.15 + 0.85*math.Max(0, busy*2-1),
+	}
+}
+
+func noteFrequency(semitonesFromA4 int) float64 {
+	return 440 * math.Pow(2, float64(semitonesFromA4)/12)
+}
+
+// a short arpeggio of sine tones, each note ringing out under the ones after it
+func synthesizeStinger(notes []int, noteLength float64, ring float64, sampleRate int) []float64 {
+	noteFrames := int(noteLength * float64(sampleRate))
+	ringFrames := int(ring * float64(sampleRate))
+	out := make([]float64, noteFrames*(len(notes)-1)+ringFrames)
+	attack := 0.005 * float64(sampleRate)
+
+	for i, note := range notes {
+		frequency := noteFrequency(note)
+		start := i * noteFrames
+		for frame := range out[start:] {
+			t := float64(frame) / float64(sampleRate)
+			envelope := math.Min(1, float64(frame)/attack) * math.Exp(-4*float64(frame)/float64(ringFrames))
+			tone := math.Sin(2*math.Pi*frequency*t) + 0.3*math.Sin(4*math.Pi*frequency*t)
+			out[start+frame] += 0.25 * envelope * tone
+		}
+	}
+
+	return out
+}
+
+// a rising major arpeggio
+func victoryStinger(sampleRate int) []float64 {
+	return synthesizeStinger([]int{3, 7, 10, 15}, 0.12, 1.2, sampleRate)
+}
+
+// a falling minor arpeggio
+func gameOverStinger(sampleRate int) []float64 {
+	return synthesizeStinger([]int{0, -4, -7, -12}, 0.3, 2, sampleRate)
+}
+
+// splits a signal into low, mid and high bands with two one pole low pass filters. the bands always add
+// back up to the original signal
+type bandSplitter struct {
+	lowAlpha  float64
+	highAlpha float64
+	low       float64
+	high      float64
+}
+
+func makeBandSplitter(sampleRate int) bandSplitter {
+	alpha := func(cutoff float64) float64 {
+		return 1 - math.Exp(-2*math.Pi*cutoff/float64(sampleRate))
+	}
+	return bandSplitter{
+		lowAlpha:  alpha(MusicLowCutoff),
+		highAlpha: alpha(MusicHighCutoff),
+	}
+}
+
+func (splitter *bandSplitter) split(sample float64) [MusicLayers]float64 {
+	splitter.low += splitter.lowAlpha * (sample - splitter.low)
+	splitter.high += splitter.highAlpha * (sample - splitter.high)
+	return [MusicLayers]float64{
+		MusicLayerLow:  splitter.low,
+		MusicLayerMid:  splitter.high - splitter.low,
+		MusicLayerHigh: sample - splitter.high,
+	}
+}
+
+// frames read from a track's stream at a time
+const MusicReadFrames = 1024
+
+// a track's pcm, which loops forever, see SoundHandler.Loop
+type musicSource struct {
+	stream io.Reader
+	volume float64
+}
+
+// one looping track in the mix
+type musicVoice struct {
+	track  MusicTrack
+	source musicSource
+	// frames played, which is what bars are counted from
+	position int
+	// pcm read from the stream but not played yet
+	buffer  []byte
+	pending []byte
+	// the stream failed, so the voice plays silence
+	broken bool
+	gain   float64
+	// change in gain per frame, stops at 0 or 1
+	fade     float64
+	channels [2]bandSplitter
+}
+
+// the next frame of the track split into layers
+func (voice *musicVoice) next() [2][MusicLayers]float64 {
+	var out [2][MusicLayers]float64
+
+	if len(voice.pending) < pcmFrameSize && !voice.broken {
+		if voice.buffer == nil {
+			voice.buffer = make([]byte, MusicReadFrames*pcmFrameSize)
+		}
+		count, err := io.ReadFull(voice.source.stream, voice.buffer)
+		voice.pending = voice.buffer[:count-count%pcmFrameSize]
+		if err != nil {
+			log.Printf("Music %v stopped: %v", voice.track.Name, err)
+			voice.broken = true
+		}
+	}
+
+	if len(voice.pending) >= pcmFrameSize {
+		for channel := range 2 {
+			sample := float64(int16(binary.LittleEndian.Uint16(voice.pending[channel*2:]))) / math.MaxInt16
+			out[channel] = voice.channels[channel].split(sample * voice.source.volume)
+		}
+		voice.pending = voice.pending[pcmFrameSize:]
+	}
+
+	voice.position += 1
+	voice.gain = math.Max(0, math.Min(1, voice.gain+voice.fade))
+	return out
+}
+
+// mixes the music tracks, layers and stingers into one stream of 16 bit stereo pcm. the game changes
+// what should play and the mixer holds each change until the next bar of the current track
+type MusicMixer struct {
+	lock       sync.Mutex
+	sampleRate int
+
+	current *musicVoice
+	// tracks fading out after a crossfade
+	fading  []*musicVoice
+	pending *musicVoice
+	// the number of the track asked for last, see Request
+	requested uint64
+
+	layers       [MusicLayers]float64
+	layerTargets [MusicLayers]float64
+	wantedLayers [MusicLayers]float64
+
+	stinger         []float64
+	stingerPosition int
+	// what the music returns to once the stinger is over, 0 after game over
+	afterStinger float64
+
+	duck       float64
+	duckTarget float64
+}
+
+func MakeMusicMixer(sampleRate int) *MusicMixer {
+	targets := musicLayerTargets(0)
+	return &MusicMixer{
+		sampleRate:   sampleRate,
+		layers:       targets,
+		layerTargets: targets,
+		wantedLayers: targets,
+		duck:         1,
+		duckTarget:   1,
+		afterStinger: 1,
+	}
+}
+
+// a number for the track about to be asked for. tracks load in the background and a slow one can finish
+// after a track asked for later, so Play drops any track that is not the latest request
+func (mixer *MusicMixer) Request() uint64 {
+	mixer.lock.Lock()
+	defer mixer.lock.Unlock()
+	mixer.requested += 1
+	return mixer.requested
+}
+
+// crossfade to a track on the next bar, unless another track was requested after this one
+func (mixer *MusicMixer) Play(track MusicTrack, source musicSource, request uint64) {
+	mixer.lock.Lock()
+	defer mixer.lock.Unlock()
+
+	if request != mixer.requested {
+		return
+	}
+
+	if mixer.current != nil && mixer.current.track == track {
+		mixer.pending = nil
+		return
+	}
+
+	mixer.pending = &musicVoice{
+		track:    track,
+		source:   source,
+		channels: [2]bandSplitter{makeBandSplitter(mixer.sampleRate), makeBandSplitter(mixer.sampleRate)},
+	}
+}
+
+// the layer gains to move to on the next bar
+func (mixer *MusicMixer) SetLayers(layers [MusicLayers]float64) {
+	mixer.lock.Lock()
+	defer mixer.lock.Unlock()
+	mixer.wantedLayers = layers
+}
+
+// play a stinger over the music right away, ducking the music while it plays. a stinger does not wait
+// for the bar since whatever comes after it, such as the shop, would cut it off. if final is true the
+// music stays silent afterwards until Resume
+func (mixer *MusicMixer) Stinger(samples []float64, final bool) {
+	mixer.lock.Lock()
+	defer mixer.lock.Unlock()
+	if final {
+		mixer.afterStinger = 0
+	}
+	mixer.stinger = samples
+	mixer.stingerPosition = 0
+	mixer.duckTarget = math.Min(MusicStingerDuck, mixer.afterStinger)
+}
+
+// bring the music back after a final stinger, such as when the players continue after game over
+func (mixer *MusicMixer) Resume() {
+	mixer.lock.Lock()
+	defer mixer.lock.Unlock()
+	mixer.afterStinger = 1
+	if mixer.stinger == nil {
+		mixer.duckTarget = 1
+	}
+}
+
+// true if the current track is at a bar boundary, or there is no track to wait for
+func (mixer *MusicMixer) onBar() bool {
+	if mixer.current == nil {
+		return true
+	}
+	return mixer.current.position%mixer.current.track.FramesPerBar(mixer.sampleRate) == 0
+}
+
+func (mixer *MusicMixer) startBar() {
+	crossfade := 1 / (MusicCrossfade * float64(mixer.sampleRate))
+
+	if mixer.pending != nil {
+		if mixer.current != nil {
+			mixer.current.fade = -crossfade
+			mixer.fading = append(mixer.fading, mixer.current)
+			mixer.pending.fade = crossfade
+		} else {
+			mixer.pending.gain = 1
+		}
+		mixer.current = mixer.pending
+		mixer.pending = nil
+	}
+
+	mixer.layerTargets = mixer.wantedLayers
+}
+
+func moveTowards(value float64, target float64, step float64) float64 {
+	if value < target {
+		return math.Min(target, value+step)
+	}
+	return math.Max(target, value-step)
+}
+
+func (mixer *MusicMixer) Read(data []byte) (int, error) {
+	mixer.lock.Lock()
+	defer mixer.lock.Unlock()
+
+	layerStep := 1 / (MusicLayerRamp * float64(mixer.sampleRate))
+	duckStep := 1 / (MusicDuckRamp * float64(mixer.sampleRate))
+
+	size := len(data) - len(data)%pcmFrameSize
+	for offset := 0; offset < size; offset += pcmFrameSize {
+		if mixer.onBar() {
+			mixer.startBar()
+		}
+
+		for layer := range MusicLayers {
+			mixer.layers[layer] = moveTowards(mixer.layers[layer], mixer.layerTargets[layer], layerStep)
+		}
+		mixer.duck = moveTowards(mixer.duck, mixer.duckTarget, duckStep)
+
+		var mix [2]float64
+		add := func(voice *musicVoice) {
+			gain := voice.gain
+			bands := voice.next()
+			for channel := range 2 {
+				for layer := range MusicLayers {
+					mix[channel] += bands[channel][layer] * mixer.layers[layer] * gain
+				}
+			}
+		}
+
+		if mixer.current != nil {
+			add(mixer.current)
+		}
+
+		keep := mixer.fading[:0]
+		for _, voice := range mixer.fading {
+			add(voice)
+			if voice.gain > 0 {
+				keep = append(keep, voice)
+			}
+		}
+		mixer.fading = keep
+
+		stinger := 0.0
+		if mixer.stinger != nil {
+			stinger = mixer.stinger[mixer.stingerPosition]
+			mixer.stingerPosition += 1
+			if mixer.stingerPosition >= len(mixer.stinger) {
+				mixer.stinger = nil
+				mixer.duckTarget = mixer.afterStinger
+			}
+		}
+
+		for channel := range 2 {
+			sample := (mix[channel]*mixer.duck + stinger) * math.MaxInt16
+			sample = math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(sample)))
+			binary.LittleEndian.PutUint16(data[offset+channel*2:], uint16(int16(sample)))
+		}
+	}
+
+	return size, nil
+}
+
+// decides what music plays during a level: the level track until the boss shows up, more layers the more
+// enemies are on screen, and a stinger when the level is won or lost
+type MusicDirector struct {
+	Mixer   *MusicMixer
+	manager *SoundManager
+	player  *audio.Player
+	// the track most recently asked for, which may still be loading
+	track   MusicTrack
+	enemies int
+}
+
+// start the music stream, which plays silence until a track is chosen with Play. the stream stops when
+// stop is done
+func MakeMusicDirector(manager *SoundManager, stop context.Context) *MusicDirector {
+	mixer := MakeMusicMixer(manager.SampleRate)
+	player, err := manager.Context.NewPlayer(mixer)
+	if err != nil {
+		log.Printf("Unable to create music player: %v", err)
+		return nil
+	}
+
+	// the music bus sets the volume from here on, see SoundManager.Update
+	manager.addVoice("", BusMusic, 1, player, nil, 1)
+
+	go func() {
+		<-stop.Done()
+		player.Close()
+	}()
+
+	return &MusicDirector{
+		Mixer:   mixer,
+		manager: manager,
+		player:  player,
+		enemies: -1,
+	}
+}
+
+// switch to a track on the next bar. asking for the track that is already playing does nothing
+func (director *MusicDirector) Play(track MusicTrack) {
+	if director == nil || director.track == track {
+		return
+	}
+	director.track = track
+	// the layers depend on the track, so pick them again on the next update
+	director.enemies = -1
+
+	handler, ok := director.manager.Sounds[track.Name]
+	if !ok {
+		log.Printf("No music for %v", track.Name)
+		return
+	}
+
+	// decoding a whole song takes a moment
+	request := director.Mixer.Request()
+	go func() {
+		stream, err := handler.Loop()
+		if err != nil {
+			log.Printf("Unable to play music %v: %v", track.Name, err)
+			return
+		}
+		director.Mixer.Play(track, musicSource{
+			stream: stream,
+			volume: handler.Volume,
+		}, request)
+	}()
+}
+
+func (director *MusicDirector) SetIntensity(enemies int) {
+	if director == nil || director.enemies == enemies {
+		return
+	}
+	director.enemies = enemies
+
+	// the boss fight always has every layer
+	if director.track == MusicBoss {
+		enemies = MusicBusyEnemies
+	}
+	director.Mixer.SetLayers(musicLayerTargets(enemies))
+}
+
+func (director *MusicDirector) Victory() {
+	if director == nil {
+		return
+	}
+	director.Mixer.Stinger(victoryStinger(director.manager.SampleRate), false)
+}
+
+func (director *MusicDirector) GameOver() {
+	if director == nil {
+		return
+	}
+	director.Mixer.Stinger(gameOverStinger(director.manager.SampleRate), true)
+}
+
+func (director *MusicDirector) Resume() {
+	if director == nil {
+		return
+	}
+	director.Mixer.Resume()
+}
+
+// enemies with their center inside the camera's view
+func (game *Game) enemiesOnScreen() int {
+	count := 0
+	for _, enemy := range game.Enemies {
+		if !enemy.IsAlive() {
+			continue
+		}
+		x, y := enemy.Coords()
+		if x >= game.Camera.x && x <= game.Camera.x+game.Camera.Width() && y >= game.Camera.y && y <= game.Camera.y+ScreenHeight {
+			count += 1
+		}
+	}
+	return count
+}
+
+// BossMode comes from snapshots on the slave, so the track is chosen from it rather than in SpawnBoss
+func (game *Game) updateMusic() {
+	if game.BossMode {
+		game.Music.Play(MusicBoss)
+	} else {
+		game.Music.Play(MusicLevel)
+	}
+	game.Music.SetIntensity(game.enemiesOnScreen())
+}
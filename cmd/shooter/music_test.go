@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// a track that plays the same sample forever
+type constantReader int16
+
+func (reader constantReader) Read(data []byte) (int, error) {
+	for i := 0; i+1 < len(data); i += 2 {
+		binary.LittleEndian.PutUint16(data[i:], uint16(reader))
+	}
+	return len(data) - len(data)%2, nil
+}
+
+func constantTrack(sample int16) musicSource {
+	return musicSource{stream: constantReader(sample), volume: 1}
+}
+
+// the left channel of the next frames from the mixer
+func readMusic(t *testing.T, mixer *MusicMixer, frames int) []int16 {
+	data := make([]byte, frames*pcmFrameSize)
+	count, err := mixer.Read(data)
+	if err != nil || count != len(data) {
+		t.Fatalf("read %v bytes with error %v", count, err)
+	}
+
+	out := make([]int16, frames)
+	for i := range out {
+		out[i] = int16(binary.LittleEndian.Uint16(data[i*pcmFrameSize:]))
+	}
+	return out
+}
+
+func TestBandSplitter(t *testing.T) {
+	splitter := makeBandSplitter(48000)
+	for range 1000 {
+		sample := rand.Float64()*2 - 1
+		bands := splitter.split(sample)
+		if sum := bands[MusicLayerLow] + bands[MusicLayerMid] + bands[MusicLayerHigh]; math.Abs(sum-sample) > 1e-9 {
+			t.Fatalf("bands add up to %v, want %v", sum, sample)
+		}
+	}
+}
+
+func TestMusicLayerTargets(t *testing.T) {
+	calm := musicLayerTargets(0)
+	if calm[MusicLayerLow] != 1 || calm[MusicLayerMid] >= 1 || calm[MusicLayerHigh] >= calm[MusicLayerMid] {
+		t.Errorf("calm layers %v", calm)
+	}
+
+	last := calm
+	for enemies := 1; enemies <= MusicBusyEnemies*2; enemies++ {
+		layers := musicLayerTargets(enemies)
+		for layer := range MusicLayers {
+			if layers[layer] < last[layer] {
+				t.Errorf("layer %v got quieter going to %v enemies", layer, enemies)
+			}
+		}
+		last = layers
+	}
+
+	if last != [MusicLayers]float64{1, 1, 1} {
+		t.Errorf("busy layers %v", last)
+	}
+}
+
+func TestMusicMixerCrossfadesOnBar(t *testing.T) {
+	// one bar is 400 frames
+	const sampleRate = 400
+	first := MusicTrack{Name: "first", BPM: 60, BeatsPerBar: 1}
+	second := MusicTrack{Name: "second", BPM: 120, BeatsPerBar: 2}
+
+	mixer := MakeMusicMixer(sampleRate)
+	mixer.layers = [MusicLayers]float64{1, 1, 1}
+	mixer.layerTargets = mixer.layers
+	mixer.wantedLayers = mixer.layers
+
+	mixer.Play(first, constantTrack(10000), mixer.Request())
+	start := readMusic(t, mixer, 100)
+	if start[0] != 10000 {
+		t.Fatalf("first track should start right away, got %v", start[0])
+	}
+
+	mixer.Play(second, constantTrack(-10000), mixer.Request())
+	waiting := readMusic(t, mixer, 301)
+	for i, sample := range waiting {
+		if sample != 10000 {
+			t.Fatalf("frame %v is %v before the bar", 100+i, sample)
+		}
+	}
+
+	crossfade := int(MusicCrossfade * sampleRate)
+	fading := readMusic(t, mixer, crossfade)
+	if middle := fading[crossfade/2-1]; math.Abs(float64(middle)) > 100 {
+		t.Errorf("halfway through the crossfade is %v", middle)
+	}
+	for i := 1; i < len(fading); i++ {
+		if fading[i] > fading[i-1] {
+			t.Fatalf("crossfade went up at frame %v", i)
+		}
+	}
+
+	if end := readMusic(t, mixer, 10); end[9] != -10000 || len(mixer.fading) != 0 {
+		t.Errorf("after the crossfade got %v with %v tracks fading", end[9], len(mixer.fading))
+	}
+}
+
+func TestMusicMixerDropsStaleTracks(t *testing.T) {
+	const sampleRate = 400
+	level := MusicTrack{Name: "level", BPM: 60, BeatsPerBar: 1}
+	boss := MusicTrack{Name: "boss", BPM: 60, BeatsPerBar: 1}
+
+	mixer := MakeMusicMixer(sampleRate)
+	mixer.layers = [MusicLayers]float64{1, 1, 1}
+	mixer.layerTargets = mixer.layers
+	mixer.wantedLayers = mixer.layers
+
+	// the level track is asked for first but finishes loading after the boss track
+	levelRequest := mixer.Request()
+	bossRequest := mixer.Request()
+	mixer.Play(boss, constantTrack(-10000), bossRequest)
+	mixer.Play(level, constantTrack(10000), levelRequest)
+
+	if samples := readMusic(t, mixer, 10); samples[9] != -10000 || mixer.current.track != boss {
+		t.Errorf("playing %v at %v, want the boss track", mixer.current.track.Name, samples[9])
+	}
+}
+
+func TestMusicMixerGameOverStinger(t *testing.T) {
+	const sampleRate = 400
+	track := MusicTrack{Name: "level", BPM: 60, BeatsPerBar: 1}
+
+	mixer := MakeMusicMixer(sampleRate)
+	mixer.Play(track, constantTrack(10000), mixer.Request())
+	readMusic(t, mixer, 100)
+
+	// the stinger starts in the middle of the bar
+	mixer.Stinger(make([]float64, 50), true)
+	readMusic(t, mixer, 1)
+	if mixer.stinger == nil || mixer.stingerPosition != 1 {
+		t.Fatalf("the stinger waited for the bar")
+	}
+
+	readMusic(t, mixer, 50+int(MusicDuckRamp*sampleRate))
+	for _, sample := range readMusic(t, mixer, 100) {
+		if sample != 0 {
+			t.Fatalf("music still playing after game over: %v", sample)
+		}
+	}
+
+	// continuing brings the music back
+	mixer.Resume()
+	readMusic(t, mixer, int(MusicDuckRamp*sampleRate))
+	if after := readMusic(t, mixer, 1); after[0] == 0 {
+		t.Errorf("music still silent after resuming")
+	}
+}
+
+func TestMusicMixerStreamEnds(t *testing.T) {
+	track := MusicTrack{Name: "short", BPM: 60, BeatsPerBar: 1}
+	data := make([]byte, 10*pcmFrameSize+1)
+	for i := 0; i+1 < len(data); i += 2 {
+		binary.LittleEndian.PutUint16(data[i:], uint16(int16(5000)))
+	}
+
+	mixer := MakeMusicMixer(400)
+	mixer.layers = [MusicLayers]float64{1, 1, 1}
+	mixer.layerTargets = mixer.layers
+	mixer.wantedLayers = mixer.layers
+	mixer.Play(track, musicSource{stream: bytes.NewReader(data), volume: 0.5}, mixer.Request())
+
+	// the partial frame at the end is dropped and the track goes quiet instead of failing the mix
+	out := readMusic(t, mixer, 20)
+	if out[0] != 2500 || out[9] != 2500 || out[10] != 0 || out[19] != 0 {
+		t.Errorf("short track played %v", out)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	audioFiles "github.com/kazzmir/webgl-shooter/audio"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// a column of options where one is selected, up and down move the selection and the other keys go to
+// the selected option. the menus, the loadout, the shop and the game over screen are all one of these
+type OptionList struct {
+	Options  []*MenuOption
+	Selected int
+
+	// where the options were drawn, for tapping and clicking them, see touch.go
+	layout OptionLayout
+}
+
+// the menu keys pressed this frame along with the ones a tap or click on the options stands for
+func (list *OptionList) Keys(run *Run) []ebiten.Key {
+	return append(run.menuKeys(), run.pointerKeys(&list.layout, list.Options, &list.Selected)...)
+}
+
+// up and down move the selection, any other key goes to the selected option if it responds to it
+func (list *OptionList) HandleKey(run *Run, soundManager *SoundManager, key ebiten.Key) error {
+	if len(list.Options) == 0 {
+		return nil
+	}
+	if list.Selected >= len(list.Options) {
+		list.Selected = len(list.Options) - 1
+	}
+
+	switch key {
+	case ebiten.KeyArrowUp:
+		list.Selected -= 1
+		if list.Selected < 0 {
+			list.Selected = len(list.Options) - 1
+		}
+		soundManager.PlayEffect(audioFiles.AudioBeep)
+	case ebiten.KeyArrowDown:
+		list.Selected = (list.Selected + 1) % len(list.Options)
+		soundManager.PlayEffect(audioFiles.AudioBeep)
+	default:
+		option := list.Options[list.Selected]
+		if option.DoesRespond(key) {
+			soundManager.PlayEffect(audioFiles.AudioBeep)
+			return option.Action(option, run, key)
+		}
+	}
+
+	return nil
+}
+
+// draw the options downwards from x, y, with padding around the text of each. the options are at least
+// minWidth wide and a list too long for the screen scrolls so the selected option stays on it. returns
+// how wide the options were and the y under the last one
+func (list *OptionList) Draw(screen *ebiten.Image, face text.GoTextFace, x float64, y float64, minWidth float64, padding float64, counter uint64) (float64, float64) {
+	angle := float64(counter%360) * math.Pi / 180.0 * 9
+	a := int((math.Sin(angle) + 1) * 128)
+	if a > 255 {
+		a = 255
+	}
+
+	_, height := text.Measure("X", &face, 0)
+
+	selected := list.Selected
+	if selected >= len(list.Options) {
+		selected = len(list.Options) - 1
+	}
+
+	optionWidth := minWidth
+	for _, option := range list.Options {
+		width, _ := text.Measure(option.Label(), &face, 0)
+		optionWidth = math.Max(optionWidth, width+20)
+	}
+
+	boxHeight := height + padding*2
+	rowHeight := boxHeight + padding*2
+	visibleRows := max(1, int((float64(screen.Bounds().Dy())-y)/rowHeight))
+	first := max(0, selected-visibleRows+1)
+
+	list.layout.Reset()
+	for i, option := range list.Options {
+		if i < first || i >= first+visibleRows {
+			continue
+		}
+
+		drawColor := color.RGBA{R: 255, G: 255, B: 255, A: 32}
+		if selected == i {
+			drawColor = color.RGBA{R: 255, G: 255, B: 255, A: uint8(a)}
+		}
+		vector.FillRect(screen, float32(x-10), float32(y-padding), float32(optionWidth), float32(boxHeight), premultiplyAlpha(drawColor), true)
+		list.layout.Add(i, x-10, y-padding, optionWidth, boxHeight)
+
+		if option.Slider != nil {
+			barX := x - 10 + optionWidth + 20
+			barY := y + height/2 - 6
+			vector.FillRect(screen, float32(barX), float32(barY), 200, 12, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, true)
+			vector.FillRect(screen, float32(barX), float32(barY), float32(200*option.Slider.Value()), 12, color.RGBA{R: 0x52, G: 0xc8, B: 0xff, A: 0xff}, true)
+			list.layout.AddSlider(i, barX, y-padding, 200, boxHeight)
+		}
+
+		drawText(screen, face, x, y, option.Label(), color.RGBA{R: 255, G: 0, B: 0, A: 255})
+
+		y += rowHeight
+	}
+
+	return optionWidth, y
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// how loud an effect just outside the view is compared to one at its edge
+const SpatialOffscreenVolume = 0.6
+
+// how many pixels outside the view an effect fades away to nothing
+const SpatialFalloff = 900
+
+// how much quieter an effect at the edge of the view is than one in the middle
+const SpatialEdgeAttenuation = 0.25
+
+// decoded sounds are 16 bit little endian stereo, so each frame is a left and a right sample
+const pcmFrameSize = 4
+
+// scales the left and right channels of decoded pcm, used to place an effect between the speakers
+type PannedReader struct {
+	source io.Reader
+	left   float64
+	right  float64
+}
+
+func MakePannedReader(source io.Reader, left float64, right float64) *PannedReader {
+	return &PannedReader{
+		source: source,
+		left:   left,
+		right:  right,
+	}
+}
+
+func scaleSample(data []byte, gain float64) {
+	sample := float64(int16(binary.LittleEndian.Uint16(data))) * gain
+	sample = math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(sample)))
+	binary.LittleEndian.PutUint16(data, uint16(int16(sample)))
+}
+
+func (reader *PannedReader) Read(data []byte) (int, error) {
+	size := len(data) - len(data)%pcmFrameSize
+	if size == 0 {
+		return 0, io.ErrShortBuffer
+	}
+
+	// only whole frames can be scaled, so keep reading until the last one is complete
+	count, err := io.ReadAtLeast(reader.source, data[:size], pcmFrameSize)
+	if extra := count % pcmFrameSize; extra != 0 && err == nil {
+		more, moreErr := io.ReadFull(reader.source, data[count:count+pcmFrameSize-extra])
+		count += more
+		err = moreErr
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+
+	// a partial frame at the very end of the stream is dropped
+	count -= count % pcmFrameSize
+	for i := 0; i < count; i += pcmFrameSize {
+		scaleSample(data[i:], reader.left)
+		scaleSample(data[i+2:], reader.right)
+	}
+
+	return count, err
+}
+
+// how loud an effect at x, y in world coordinates is in the left and right speakers for a listener
+// looking through camera. effects are panned by where they are across the view, get a little quieter
+// towards the edges of the view and fade out the further offscreen they are
+func spatialGains(camera *Camera, x float64, y float64) (float64, float64) {
+	halfWidth := camera.Width() / 2
+	halfHeight := float64(ScreenHeight) / 2
+	centerX := camera.x + halfWidth
+	centerY := camera.y + halfHeight
+
+	pan := math.Max(-1, math.Min(1, (x-centerX)/halfWidth))
+	// equal power panning, scaled so an effect in the middle plays at full volume in both speakers
+	angle := (pan + 1) * math.Pi / 4
+	left := math.Min(1, math.Sqrt2*math.Cos(angle))
+	right := math.Min(1, math.Sqrt2*math.Sin(angle))
+
+	offsetX := math.Abs(x-centerX) / halfWidth
+	offsetY := math.Abs(y-centerY) / halfHeight
+	volume := 1 - SpatialEdgeAttenuation*math.Min(1, math.Max(offsetX, offsetY))
+
+	outsideX := math.Max(0, math.Abs(x-centerX)-halfWidth)
+	outsideY := math.Max(0, math.Abs(y-centerY)-halfHeight)
+	if outside := math.Hypot(outsideX, outsideY); outside > 0 {
+		volume *= SpatialOffscreenVolume * math.Max(0, 1-outside/SpatialFalloff)
+	}
+
+	return left * volume, right * volume
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+)
+
+func TestPannedReader(t *testing.T) {
+	var input bytes.Buffer
+	for _, sample := range []int16{1000, 1000, -2000, 30000, 20000, -20000} {
+		binary.Write(&input, binary.LittleEndian, sample)
+	}
+
+	// reading a byte at a time splits frames, which must still be scaled whole
+	reader := MakePannedReader(iotest.OneByteReader(&input), 0.5, 2)
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+
+	want := []int16{500, 2000, -1000, math.MaxInt16, 10000, math.MinInt16}
+	got := make([]int16, len(output)/2)
+	binary.Read(bytes.NewReader(output), binary.LittleEndian, got)
+	if len(got) != len(want) {
+		t.Fatalf("got %v samples, want %v", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sample %v is %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSpatialGains(t *testing.T) {
+	camera := &Camera{x: 500, y: 0}
+	centerX := camera.x + camera.Width()/2
+
+	left, right := spatialGains(camera, centerX, ScreenHeight/2)
+	if math.Abs(left-1) > 1e-9 || math.Abs(right-1) > 1e-9 {
+		t.Errorf("an effect in the middle plays at %v %v", left, right)
+	}
+
+	left, right = spatialGains(camera, camera.x+10, ScreenHeight/2)
+	if left <= right {
+		t.Errorf("an effect on the left plays at %v %v", left, right)
+	}
+
+	nearLeft, nearRight := spatialGains(camera, camera.x+camera.Width()+100, ScreenHeight/2)
+	edgeLeft, edgeRight := spatialGains(camera, camera.x+camera.Width()-1, ScreenHeight/2)
+	if nearRight >= edgeRight || nearLeft > edgeLeft {
+		t.Errorf("offscreen effect at %v %v is not quieter than one at the edge at %v %v", nearLeft, nearRight, edgeLeft, edgeRight)
+	}
+
+	left, right = spatialGains(camera, camera.x, ScreenHeight+SpatialFalloff+ScreenHeight)
+	if left != 0 || right != 0 {
+		t.Errorf("a far away effect plays at %v %v", left, right)
+	}
+}
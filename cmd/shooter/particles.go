@@ -0,0 +1,335 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// emitter definitions, keyed by the name passed to ParticleSystem.Burst
+//
+//go:embed particles/emitters.json
+var ParticleEmitterData []byte
+
+// more particles than this are dropped rather than spawned. each particle is 4 vertices so this also
+// keeps a whole batch within the uint16 index range of DrawTriangles
+const MaxParticles = 8000
+
+type ParticleColorKey struct {
+	// position along the particle's life, from 0 to 1
+	T float64 `json:"t"`
+	// r, g, b, a from 0 to 255
+	Color [4]float64 `json:"color"`
+}
+
+type ParticleSizeKey struct {
+	T    float64 `json:"t"`
+	Size float64 `json:"size"`
+}
+
+type ParticleEmitterDefinition struct {
+	// particles spawned all at once by Burst
+	Burst int `json:"burst"`
+	// particles spawned per tick by a ParticleEmitter
+	Rate float64 `json:"rate"`
+	// range of the life of a particle in ticks
+	Lifetime [2]float64 `json:"lifetime"`
+	// range of the starting speed in pixels per tick
+	Speed [2]float64 `json:"speed"`
+	// direction of the cone in degrees, 0 is to the right and 90 is down
+	Angle float64 `json:"angle"`
+	// width of the cone in degrees, 360 sprays in every direction
+	Spread float64 `json:"spread"`
+	// added to the vertical velocity every tick
+	Gravity float64 `json:"gravity"`
+	// fraction of the velocity lost every tick
+	Drag float64 `json:"drag"`
+	// "additive" or "alpha"
+	Blend  string             `json:"blend"`
+	Colors []ParticleColorKey `json:"colors"`
+	Sizes  []ParticleSizeKey  `json:"sizes"`
+}
+
+func (definition *ParticleEmitterDefinition) additive() bool {
+	return definition.Blend == "additive"
+}
+
+// linearly interpolate the color curve at t
+func (definition *ParticleEmitterDefinition) colorAt(t float64) [4]float64 {
+	keys := definition.Colors
+	if len(keys) == 0 {
+		return [4]float64{255, 255, 255, 255}
+	}
+
+	if t <= keys[0].T {
+		return keys[0].Color
+	}
+
+	for i := 1; i < len(keys); i++ {
+		if t <= keys[i].T {
+			before := keys[i-1]
+			after := keys[i]
+			amount := (t - before.T) / (after.T - before.T)
+			var out [4]float64
+			for c := range out {
+				out[c] = before.Color[c] + (after.Color[c]-before.Color[c])*amount
+			}
+			return out
+		}
+	}
+
+	return keys[len(keys)-1].Color
+}
+
+func (definition *ParticleEmitterDefinition) sizeAt(t float64) float64 {
+	keys := definition.Sizes
+	if len(keys) == 0 {
+		return 1
+	}
+
+	if t <= keys[0].T {
+		return keys[0].Size
+	}
+
+	for i := 1; i < len(keys); i++ {
+		if t <= keys[i].T {
+			before := keys[i-1]
+			after := keys[i]
+			return before.Size + (after.Size-before.Size)*(t-before.T)/(after.T-before.T)
+		}
+	}
+
+	return keys[len(keys)-1].Size
+}
+
+func (definition *ParticleEmitterDefinition) validate() error {
+	if definition.Lifetime[0] <= 0 || definition.Lifetime[1] < definition.Lifetime[0] {
+		return fmt.Errorf("invalid lifetime %v", definition.Lifetime)
+	}
+
+	if definition.Speed[1] < definition.Speed[0] {
+		return fmt.Errorf("invalid speed %v", definition.Speed)
+	}
+
+	if definition.Blend != "additive" && definition.Blend != "alpha" {
+		return fmt.Errorf("unknown blend %q", definition.Blend)
+	}
+
+	for i := 1; i < len(definition.Colors); i++ {
+		if definition.Colors[i].T <= definition.Colors[i-1].T {
+			return fmt.Errorf("color keys are not in order")
+		}
+	}
+
+	for i := 1; i < len(definition.Sizes); i++ {
+		if definition.Sizes[i].T <= definition.Sizes[i-1].T {
+			return fmt.Errorf("size keys are not in order")
+		}
+	}
+
+	return nil
+}
+
+func LoadParticleDefinitions(data []byte) (map[string]*ParticleEmitterDefinition, error) {
+	var definitions map[string]*ParticleEmitterDefinition
+	err := json.Unmarshal(data, &definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, definition := range definitions {
+		err := definition.validate()
+		if err != nil {
+			return nil, fmt.Errorf("particle emitter %v: %w", name, err)
+		}
+	}
+
+	return definitions, nil
+}
+
+type Particle struct {
+	x, y                 float64
+	velocityX, velocityY float64
+	age, life            float64
+	definition           *ParticleEmitterDefinition
+}
+
+// particles are purely cosmetic, so each peer runs its own and they are never sent over the network
+type ParticleSystem struct {
+	Definitions map[string]*ParticleEmitterDefinition
+	Particles   []Particle
+
+	// soft round dot that every particle is drawn with, created on the first draw
+	texture *ebiten.Image
+	// reused between draws
+	vertices []ebiten.Vertex
+	indices  []uint16
+}
+
+func MakeParticleSystem() (*ParticleSystem, error) {
+	definitions, err := LoadParticleDefinitions(ParticleEmitterData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ParticleSystem{
+		Definitions: definitions,
+	}, nil
+}
+
+func (system *ParticleSystem) spawn(definition *ParticleEmitterDefinition, x float64, y float64) {
+	if len(system.Particles) >= MaxParticles {
+		return
+	}
+
+	angle := (definition.Angle + (rand.Float64()-0.5)*definition.Spread) * math.Pi / 180
+	speed := randomFloat(definition.Speed[0], definition.Speed[1])
+
+	system.Particles = append(system.Particles, Particle{
+		x:          x,
+		y:          y,
+		velocityX:  math.Cos(angle) * speed,
+		velocityY:  math.Sin(angle) * speed,
+		life:       randomFloat(definition.Lifetime[0], definition.Lifetime[1]),
+		definition: definition,
+	})
+}
+
+// spawn the burst of the named emitter at x, y in world coordinates
+func (system *ParticleSystem) Burst(name string, x float64, y float64) {
+	definition, ok := system.Definitions[name]
+	if !ok {
+		return
+	}
+
+	for range definition.Burst {
+		system.spawn(definition, x, y)
+	}
+}
+
+func (system *ParticleSystem) Update() {
+	alive := system.Particles[:0]
+	for _, particle := range system.Particles {
+		particle.age += 1
+		if particle.age >= particle.life {
+			continue
+		}
+
+		definition := particle.definition
+		particle.velocityY += definition.Gravity
+		particle.velocityX *= 1 - definition.Drag
+		particle.velocityY *= 1 - definition.Drag
+		particle.x += particle.velocityX
+		particle.y += particle.velocityY
+
+		alive = append(alive, particle)
+	}
+
+	system.Particles = alive
+}
+
+const particleTextureSize = 16
+
+func makeParticleTexture() *ebiten.Image {
+	dot := image.NewRGBA(image.Rect(0, 0, particleTextureSize, particleTextureSize))
+	center := float64(particleTextureSize) / 2
+	for y := range particleTextureSize {
+		for x := range particleTextureSize {
+			distance := math.Hypot(float64(x)+0.5-center, float64(y)+0.5-center) / center
+			alpha := uint8(255 * math.Max(0, 1-distance*distance))
+			dot.SetRGBA(x, y, color.RGBA{R: alpha, G: alpha, B: alpha, A: alpha})
+		}
+	}
+
+	return ebiten.NewImageFromImage(dot)
+}
+
+// draw every particle with one DrawTriangles call per blend mode
+func (system *ParticleSystem) Draw(screen *ebiten.Image, camera *Camera) {
+	if len(system.Particles) == 0 {
+		return
+	}
+
+	if system.texture == nil {
+		system.texture = makeParticleTexture()
+	}
+
+	system.drawBatch(screen, camera, false, &ebiten.DrawTrianglesOptions{})
+	system.drawBatch(screen, camera, true, &ebiten.DrawTrianglesOptions{Blend: ebiten.BlendLighter})
+}
+
+func (system *ParticleSystem) drawBatch(screen *ebiten.Image, camera *Camera, additive bool, options *ebiten.DrawTrianglesOptions) {
+	vertices := system.vertices[:0]
+	indices := system.indices[:0]
+
+	screenWidth := float64(screen.Bounds().Dx())
+	screenHeight := float64(screen.Bounds().Dy())
+
+	for _, particle := range system.Particles {
+		definition := particle.definition
+		if definition.additive() != additive {
+			continue
+		}
+
+		t := particle.age / particle.life
+		half := definition.sizeAt(t) / 2
+		x, y := camera.Apply(particle.x, particle.y)
+		if x+half < 0 || y+half < 0 || x-half > screenWidth || y-half > screenHeight {
+			continue
+		}
+
+		rgba := definition.colorAt(t)
+		r := float32(rgba[0] / 255)
+		g := float32(rgba[1] / 255)
+		b := float32(rgba[2] / 255)
+		a := float32(rgba[3] / 255)
+
+		base := uint16(len(vertices))
+		for _, corner := range [4][2]float64{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+			vertices = append(vertices, ebiten.Vertex{
+				DstX:   float32(x + corner[0]*half),
+				DstY:   float32(y + corner[1]*half),
+				SrcX:   float32((corner[0] + 1) / 2 * particleTextureSize),
+				SrcY:   float32((corner[1] + 1) / 2 * particleTextureSize),
+				ColorR: r,
+				ColorG: g,
+				ColorB: b,
+				ColorA: a,
+			})
+		}
+		indices = append(indices, base, base+1, base+2, base+1, base+3, base+2)
+	}
+
+	if len(indices) > 0 {
+		screen.DrawTriangles(vertices, indices, system.texture, options)
+	}
+
+	system.vertices = vertices
+	system.indices = indices
+}
+
+// a continuous source of particles, such as a thruster. the fraction of a particle left over each
+// tick is carried to the next one so low rates still emit
+type ParticleEmitter struct {
+	Name  string
+	carry float64
+}
+
+func (emitter *ParticleEmitter) Emit(system *ParticleSystem, x float64, y float64) {
+	definition, ok := system.Definitions[emitter.Name]
+	if !ok {
+		return
+	}
+
+	emitter.carry += definition.Rate
+	for emitter.carry >= 1 {
+		system.spawn(definition, x, y)
+		emitter.carry -= 1
+	}
+}
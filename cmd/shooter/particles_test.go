@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEmbeddedParticleDefinitions(t *testing.T) {
+	definitions, err := LoadParticleDefinitions(ParticleEmitterData)
+	if err != nil {
+		t.Fatalf("unable to load emitters: %v", err)
+	}
+
+	for _, name := range []string{"enemy-explosion", "enemy-debris", "bullet-impact", "player-hit", "thruster", "bomb-shockwave", "asteroid-fragments"} {
+		if _, ok := definitions[name]; !ok {
+			t.Errorf("missing emitter %v", name)
+		}
+	}
+}
+
+func TestInvalidParticleDefinition(t *testing.T) {
+	_, err := LoadParticleDefinitions([]byte(`{"bad": {"lifetime": [10, 20], "blend": "multiply"}}`))
+	if err == nil {
+		t.Fatal("unknown blend should be rejected")
+	}
+}
+
+func TestParticleCurves(t *testing.T) {
+	definition := &ParticleEmitterDefinition{
+		Colors: []ParticleColorKey{{T: 0, Color: [4]float64{0, 0, 0, 255}}, {T: 1, Color: [4]float64{200, 100, 50, 0}}},
+		Sizes:  []ParticleSizeKey{{T: 0.5, Size: 10}, {T: 1, Size: 2}},
+	}
+
+	if got := definition.colorAt(0.5); got != [4]float64{100, 50, 25, 127.5} {
+		t.Errorf("color at 0.5 is %v", got)
+	}
+
+	if got := definition.sizeAt(0.25); got != 10 {
+		t.Errorf("size before the first key is %v, want 10", got)
+	}
+
+	if got := definition.sizeAt(0.75); got != 6 {
+		t.Errorf("size at 0.75 is %v, want 6", got)
+	}
+}
+
+func TestParticlesExpire(t *testing.T) {
+	system := &ParticleSystem{
+		Definitions: map[string]*ParticleEmitterDefinition{
+			"test": {Burst: 10, Rate: 0.5, Lifetime: [2]float64{5, 5}, Speed: [2]float64{1, 1}, Blend: "alpha"},
+		},
+	}
+
+	system.Burst("test", 0, 0)
+	if len(system.Particles) != 10 {
+		t.Fatalf("%v particles after a burst, want 10", len(system.Particles))
+	}
+
+	emitter := ParticleEmitter{Name: "test"}
+	emitter.Emit(system, 0, 0)
+	emitter.Emit(system, 0, 0)
+	if len(system.Particles) != 11 {
+		t.Fatalf("%v particles after emitting twice at half a particle per tick, want 11", len(system.Particles))
+	}
+
+	for range 5 {
+		system.Update()
+	}
+
+	if len(system.Particles) != 0 {
+		t.Fatalf("%v particles still alive after their lifetime", len(system.Particles))
+	}
+}
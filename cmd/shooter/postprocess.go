@@ -0,0 +1,214 @@
+package main
+
+import (
+	"runtime"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+)
+
+type PostProcessQuality int
+
+const (
+	// no post processing at all
+	PostProcessLow PostProcessQuality = iota
+	// the cheap passes
+	PostProcessMedium
+	// everything, including bloom
+	PostProcessHigh
+)
+
+func (quality PostProcessQuality) String() string {
+	switch quality {
+	case PostProcessLow:
+		return "Low"
+	case PostProcessMedium:
+		return "Medium"
+	case PostProcessHigh:
+		return "High"
+	}
+
+	return "Unknown"
+}
+
+type PostProcessPass int
+
+const (
+	PassBloom PostProcessPass = iota
+	PassAberration
+	PassVignette
+	PassCRT
+)
+
+// the lowest quality a pass runs at
+func (pass PostProcessPass) MinimumQuality() PostProcessQuality {
+	switch pass {
+	case PassBloom:
+		return PostProcessHigh
+	default:
+		return PostProcessMedium
+	}
+}
+
+type PostProcessSettings struct {
+	Quality PostProcessQuality
+	Bloom   bool
+	// chromatic aberration when the player takes a hit
+	Aberration bool
+	Vignette   bool
+	CRT        bool
+}
+
+// browsers are often running on weak hardware, so wasm starts without bloom
+func DefaultPostProcessSettings() PostProcessSettings {
+	quality := PostProcessHigh
+	if runtime.GOOS == "js" {
+		quality = PostProcessMedium
+	}
+
+	return PostProcessSettings{
+		Quality:    quality,
+		Bloom:      true,
+		Aberration: true,
+		Vignette:   true,
+		CRT:        false,
+	}
+}
+
+// the passes to run in order, taking the quality setting into account
+func (settings *PostProcessSettings) Passes() []PostProcessPass {
+	var passes []PostProcessPass
+
+	enabled := map[PostProcessPass]bool{
+		PassBloom:      settings.Bloom,
+		PassAberration: settings.Aberration,
+		PassVignette:   settings.Vignette,
+		PassCRT:        settings.CRT,
+	}
+
+	for _, pass := range []PostProcessPass{PassBloom, PassAberration, PassVignette, PassCRT} {
+		if enabled[pass] && settings.Quality >= pass.MinimumQuality() {
+			passes = append(passes, pass)
+		}
+	}
+
+	return passes
+}
+
+// per frame input to the passes
+type PostProcessFrame struct {
+	// from 0 to 1, how recently the player was hit
+	Hurt float64
+}
+
+type PostProcessor struct {
+	Shaders  *ShaderManager
+	Settings *PostProcessSettings
+	// the colorblind palette is applied after every other pass
+	Accessibility *AccessibilitySettings
+	// the passes ping pong between these two
+	buffers [2]*ebiten.Image
+}
+
+func MakePostProcessor(shaders *ShaderManager, settings *PostProcessSettings, accessibility *AccessibilitySettings) *PostProcessor {
+	return &PostProcessor{
+		Shaders:       shaders,
+		Settings:      settings,
+		Accessibility: accessibility,
+	}
+}
+
+// an image of the given size, reusing buffer if it already is that size
+func ensureImage(buffer *ebiten.Image, width int, height int) *ebiten.Image {
+	if buffer != nil && buffer.Bounds().Dx() == width && buffer.Bounds().Dy() == height {
+		return buffer
+	}
+
+	if buffer != nil {
+		buffer.Deallocate()
+	}
+	return ebiten.NewImage(width, height)
+}
+
+func (processor *PostProcessor) ensureBuffers(width int, height int) {
+	for i, buffer := range processor.buffers {
+		processor.buffers[i] = ensureImage(buffer, width, height)
+	}
+}
+
+// the shader and uniforms for a pass, or nil if the pass would not change the image this frame
+func (processor *PostProcessor) passShader(pass PostProcessPass, frame PostProcessFrame) (*ebiten.Shader, map[string]any) {
+	switch pass {
+	case PassBloom:
+		return processor.Shaders.BloomShader, map[string]any{
+			"Threshold": float32(0.6),
+			"Intensity": float32(1.2),
+		}
+	case PassAberration:
+		if frame.Hurt <= 0 {
+			return nil, nil
+		}
+		return processor.Shaders.AberrationShader, map[string]any{
+			"Amount": float32(8 * frame.Hurt),
+		}
+	case PassVignette:
+		return processor.Shaders.VignetteShader, map[string]any{
+			"Strength": float32(0.55),
+		}
+	case PassCRT:
+		return processor.Shaders.CRTShader, map[string]any{
+			"Scanline": float32(0.3),
+		}
+	}
+
+	return nil, nil
+}
+
+// run the enabled passes over offscreen and draw the result to the screen with geoM
+func (processor *PostProcessor) Draw(screen ebiten.FinalScreen, offscreen *ebiten.Image, geoM ebiten.GeoM, filter ebiten.Filter, frame PostProcessFrame) {
+	source := offscreen
+
+	passes := processor.Settings.Passes()
+	if len(passes) > 0 {
+		width := offscreen.Bounds().Dx()
+		height := offscreen.Bounds().Dy()
+		processor.ensureBuffers(width, height)
+
+		for _, pass := range passes {
+			shader, uniforms := processor.passShader(pass, frame)
+			if shader == nil {
+				continue
+			}
+
+			// never write to the image being read from
+			target := processor.buffers[0]
+			if source == target {
+				target = processor.buffers[1]
+			}
+
+			target.Clear()
+			options := &ebiten.DrawRectShaderOptions{}
+			options.Images[0] = source
+			options.Uniforms = uniforms
+			target.DrawRectShader(width, height, shader, options)
+			source = target
+		}
+	}
+
+	if processor.Accessibility != nil && processor.Accessibility.Palette != PaletteNormal {
+		processor.ensureBuffers(offscreen.Bounds().Dx(), offscreen.Bounds().Dy())
+		target := processor.buffers[0]
+		if source == target {
+			target = processor.buffers[1]
+		}
+
+		target.Clear()
+		colorm.DrawImage(target, source, processor.Accessibility.Palette.ColorM(), nil)
+		source = target
+	}
+
+	screen.DrawImage(source, &ebiten.DrawImageOptions{
+		GeoM:   geoM,
+		Filter: filter,
+	})
+}
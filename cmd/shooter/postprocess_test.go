@@ -0,0 +1,32 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPostProcessPassesFollowQuality(t *testing.T) {
+	settings := PostProcessSettings{Quality: PostProcessHigh, Bloom: true, Aberration: true, Vignette: true, CRT: true}
+
+	if passes := settings.Passes(); !slices.Equal(passes, []PostProcessPass{PassBloom, PassAberration, PassVignette, PassCRT}) {
+		t.Errorf("high quality passes %v", passes)
+	}
+
+	settings.Quality = PostProcessMedium
+	if passes := settings.Passes(); !slices.Equal(passes, []PostProcessPass{PassAberration, PassVignette, PassCRT}) {
+		t.Errorf("medium quality should drop bloom, got %v", passes)
+	}
+
+	settings.Quality = PostProcessLow
+	if passes := settings.Passes(); len(passes) != 0 {
+		t.Errorf("low quality should not run any pass, got %v", passes)
+	}
+}
+
+func TestPostProcessPassesCanBeDisabled(t *testing.T) {
+	settings := PostProcessSettings{Quality: PostProcessHigh, Vignette: true}
+
+	if passes := settings.Passes(); !slices.Equal(passes, []PostProcessPass{PassVignette}) {
+		t.Errorf("only the vignette is enabled, got %v", passes)
+	}
+}
@@ -4,11 +4,11 @@ import (
 	audioFiles "github.com/kazzmir/webgl-shooter/audio"
 	gameImages "github.com/kazzmir/webgl-shooter/images"
 
+	"fmt"
 	"image"
 	"image/color"
 	"log"
 	"math"
-	"math/rand/v2"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -89,7 +89,11 @@ func isColliding(from image.Rectangle, collidable Collidable) bool {
 type Powerup interface {
 	Move()
 	Collide(player *Player, imageManager *ImageManager) bool
+	// the area that can be collected, an empty rectangle if the image is missing
+	Bounds(imageManager *ImageManager) image.Rectangle
 	Activate(player *Player, soundManager *SoundManager)
+	Coords() (float64, float64)
+	// false once collected. one that falls out of the world is dropped by Game.Update
 	IsAlive() bool
 	Draw(screen *ebiten.Image, imageManager *ImageManager, shaders *ShaderManager, extra ebiten.GeoM)
 }
@@ -119,15 +123,19 @@ func (powerup *PowerupEnergy) Move() {
 	powerup.angle += 1
 }
 
+func (powerup *PowerupEnergy) Coords() (float64, float64) {
+	return powerup.x, powerup.y
+}
+
 func (powerup *PowerupEnergy) IsAlive() bool {
-	return !powerup.activated && powerup.y < ScreenHeight+20
+	return !powerup.activated
 }
 
 func (powerup *PowerupEnergy) Activate(player *Player, soundManager *SoundManager) {
 	if !powerup.activated {
 		player.PowerupEnergy = 60 * 10
 		powerup.activated = true
-		soundManager.PlayEffect(audioFiles.AudioEnergy)
+		soundManager.PlayEffectAt(audioFiles.AudioEnergy, player.x, player.y)
 	}
 }
 
@@ -178,18 +186,21 @@ func (powerup *PowerupEnergy) Draw(screen *ebiten.Image, imageManager *ImageMana
 	screen.DrawRectShader(pic.Bounds().Dx(), pic.Bounds().Dy(), shaders.EdgeShader, shaderOptions)
 }
 
-func (powerup *PowerupEnergy) Collide(player *Player, imageManager *ImageManager) bool {
+func (powerup *PowerupEnergy) Bounds(imageManager *ImageManager) image.Rectangle {
 	pic, _, err := imageManager.LoadImage(gameImages.ImagePowerup1)
 	if err != nil {
-		return false
+		return image.Rectangle{}
 	}
 
 	translate := image.Point{
 		X: int(powerup.x - float64(pic.Bounds().Dx())/2),
 		Y: int(powerup.y - float64(pic.Bounds().Dy())/2),
 	}
-	bounds := pic.Bounds().Add(translate)
-	return isColliding(bounds, player)
+	return pic.Bounds().Add(translate)
+}
+
+func (powerup *PowerupEnergy) Collide(player *Player, imageManager *ImageManager) bool {
+	return isColliding(powerup.Bounds(imageManager), player)
 }
 
 type PowerupHealth struct {
@@ -206,29 +217,36 @@ func (powerup *PowerupHealth) Move() {
 	powerup.counter += 1
 }
 
+func (powerup *PowerupHealth) Coords() (float64, float64) {
+	return powerup.x, powerup.y
+}
+
 func (powerup *PowerupHealth) IsAlive() bool {
-	return !powerup.activated && powerup.y < ScreenHeight+20
+	return !powerup.activated
 }
 
-func (powerup *PowerupHealth) Collide(player *Player, imageManager *ImageManager) bool {
+func (powerup *PowerupHealth) Bounds(imageManager *ImageManager) image.Rectangle {
 	pic, _, err := imageManager.LoadImage(gameImages.ImagePowerup3)
 	if err != nil {
-		return false
+		return image.Rectangle{}
 	}
 
 	translate := image.Point{
 		X: int(powerup.x - float64(pic.Bounds().Dx())/2),
 		Y: int(powerup.y - float64(pic.Bounds().Dy())/2),
 	}
-	bounds := pic.Bounds().Add(translate)
-	return isColliding(bounds, player)
+	return pic.Bounds().Add(translate)
+}
+
+func (powerup *PowerupHealth) Collide(player *Player, imageManager *ImageManager) bool {
+	return isColliding(powerup.Bounds(imageManager), player)
 }
 
 func (powerup *PowerupHealth) Activate(player *Player, soundManager *SoundManager) {
 	if !powerup.activated {
 		player.Health = math.Min(player.MaxHealth, player.Health+20)
 		powerup.activated = true
-		soundManager.PlayEffect(audioFiles.AudioHealth)
+		soundManager.PlayEffectAt(audioFiles.AudioHealth, player.x, player.y)
 	}
 }
 
@@ -267,22 +285,29 @@ func (powerup *PowerupWeapon) Move() {
 	powerup.counter += 1
 }
 
+func (powerup *PowerupWeapon) Coords() (float64, float64) {
+	return powerup.x, powerup.y
+}
+
 func (powerup *PowerupWeapon) IsAlive() bool {
-	return !powerup.activated && powerup.y < ScreenHeight+20
+	return !powerup.activated
 }
 
-func (powerup *PowerupWeapon) Collide(player *Player, imageManager *ImageManager) bool {
+func (powerup *PowerupWeapon) Bounds(imageManager *ImageManager) image.Rectangle {
 	pic, _, err := imageManager.LoadImage(gameImages.ImagePowerup4)
 	if err != nil {
-		return false
+		return image.Rectangle{}
 	}
 
 	translate := image.Point{
 		X: int(powerup.x - float64(pic.Bounds().Dx())/2),
 		Y: int(powerup.y - float64(pic.Bounds().Dy())/2),
 	}
-	bounds := pic.Bounds().Add(translate)
-	return isColliding(bounds, player)
+	return pic.Bounds().Add(translate)
+}
+
+func (powerup *PowerupWeapon) Collide(player *Player, imageManager *ImageManager) bool {
+	return isColliding(powerup.Bounds(imageManager), player)
 }
 
 func (powerup *PowerupWeapon) Activate(player *Player, soundManager *SoundManager) {
@@ -290,7 +315,7 @@ func (powerup *PowerupWeapon) Activate(player *Player, soundManager *SoundManage
 		player.EnableNextGun()
 		powerup.activated = true
 		// FIXME: find a new sound
-		soundManager.PlayEffect(audioFiles.AudioHealth)
+		soundManager.PlayEffectAt(audioFiles.AudioHealth, player.x, player.y)
 	}
 }
 
@@ -320,8 +345,12 @@ type PowerupBomb struct {
 	counter   uint64
 }
 
+func (powerup *PowerupBomb) Coords() (float64, float64) {
+	return powerup.x, powerup.y
+}
+
 func (powerup *PowerupBomb) IsAlive() bool {
-	return !powerup.activated && powerup.y < ScreenHeight+20
+	return !powerup.activated
 }
 
 func (powerup *PowerupBomb) Move() {
@@ -335,22 +364,25 @@ func (powerup *PowerupBomb) Activate(player *Player, soundManager *SoundManager)
 		player.IncreaseBombs()
 		powerup.activated = true
 		// FIXME: find a new sound
-		soundManager.PlayEffect(audioFiles.AudioHealth)
+		soundManager.PlayEffectAt(audioFiles.AudioHealth, player.x, player.y)
 	}
 }
 
-func (powerup *PowerupBomb) Collide(player *Player, imageManager *ImageManager) bool {
+func (powerup *PowerupBomb) Bounds(imageManager *ImageManager) image.Rectangle {
 	pic, _, err := imageManager.LoadImage(gameImages.ImagePowerupBomb)
 	if err != nil {
-		return false
+		return image.Rectangle{}
 	}
 
 	translate := image.Point{
 		X: int(powerup.x - float64(pic.Bounds().Dx())/2),
 		Y: int(powerup.y - float64(pic.Bounds().Dy())/2),
 	}
-	bounds := pic.Bounds().Add(translate)
-	return isColliding(bounds, player)
+	return pic.Bounds().Add(translate)
+}
+
+func (powerup *PowerupBomb) Collide(player *Player, imageManager *ImageManager) bool {
+	return isColliding(powerup.Bounds(imageManager), player)
 }
 
 func (powerup *PowerupBomb) Draw(screen *ebiten.Image, imageManager *ImageManager, shaders *ShaderManager, extra ebiten.GeoM) {
@@ -361,67 +393,72 @@ func (powerup *PowerupBomb) Draw(screen *ebiten.Image, imageManager *ImageManage
 	drawGlow(screen, pic, shaders, powerup.x, powerup.y, powerup.counter, extra)
 }
 
-/*
 type PowerupEnergyIncrease struct {
-    x, y float64
-    velocityX, velocityY float64
-    activated bool
-    counter uint64
-    increase uint64
+	x, y                 float64
+	velocityX, velocityY float64
+	activated            bool
+	counter              uint64
+	increase             uint64
+}
+
+func (powerup *PowerupEnergyIncrease) Coords() (float64, float64) {
+	return powerup.x, powerup.y
 }
 
 func (powerup *PowerupEnergyIncrease) IsAlive() bool {
-    return !powerup.activated && powerup.y < ScreenHeight + 20
+	return !powerup.activated
 }
 
 func (powerup *PowerupEnergyIncrease) Move() {
-    powerup.x += powerup.velocityX
-    powerup.y += powerup.velocityY
-    powerup.counter += 1
+	powerup.x += powerup.velocityX
+	powerup.y += powerup.velocityY
+	powerup.counter += 1
 }
 
-func (powerup *PowerupEnergyIncrease) Activate(player *Player, soundManager *SoundManager){
-    if !powerup.activated {
-        player.IncreaseMaxEnergy(float64(powerup.increase))
-        powerup.activated = true
-		soundManager.PlayEffect(audioFiles.AudioHealth)
-    }
+func (powerup *PowerupEnergyIncrease) Activate(player *Player, soundManager *SoundManager) {
+	if !powerup.activated {
+		player.IncreaseMaxEnergy(float64(powerup.increase))
+		powerup.activated = true
+		soundManager.PlayEffectAt(audioFiles.AudioHealth, player.x, player.y)
+	}
 }
 
-func (powerup *PowerupEnergyIncrease) Collide(player *Player, imageManager *ImageManager) bool {
-    pic, _, err := imageManager.LoadImage(gameImages.ImagePowerup5)
-    if err != nil {
-        return false
-    }
+func (powerup *PowerupEnergyIncrease) Bounds(imageManager *ImageManager) image.Rectangle {
+	pic, _, err := imageManager.LoadImage(gameImages.ImagePowerup5)
+	if err != nil {
+		return image.Rectangle{}
+	}
 
-    translate := image.Point{
-        X: int(powerup.x - float64(pic.Bounds().Dx())/2),
-        Y: int(powerup.y - float64(pic.Bounds().Dy())/2),
-    }
-    bounds := pic.Bounds().Add(translate)
-    return isColliding(bounds, player)
+	translate := image.Point{
+		X: int(powerup.x - float64(pic.Bounds().Dx())/2),
+		Y: int(powerup.y - float64(pic.Bounds().Dy())/2),
+	}
+	return pic.Bounds().Add(translate)
 }
 
-func (powerup *PowerupEnergyIncrease) Draw(screen *ebiten.Image, imageManager *ImageManager, shaders *ShaderManager, extra ebiten.GeoM){
-    pic, _, err := imageManager.LoadImage(gameImages.ImagePowerup5)
-    if err != nil {
-        return
-    }
-    drawGlow(screen, pic, shaders, powerup.x, powerup.y, powerup.counter, extra)
+func (powerup *PowerupEnergyIncrease) Collide(player *Player, imageManager *ImageManager) bool {
+	return isColliding(powerup.Bounds(imageManager), player)
+}
+
+func (powerup *PowerupEnergyIncrease) Draw(screen *ebiten.Image, imageManager *ImageManager, shaders *ShaderManager, extra ebiten.GeoM) {
+	pic, _, err := imageManager.LoadImage(gameImages.ImagePowerup5)
+	if err != nil {
+		return
+	}
+	drawGlow(screen, pic, shaders, powerup.x, powerup.y, powerup.counter, extra)
 }
 
 func MakePowerupEnergyIncrease(x float64, y float64) Powerup {
-    return &PowerupEnergyIncrease{
-        x: x,
-        y: y,
-        velocityX: 0,
-        velocityY: 1.5,
-        activated: false,
-        counter: 0,
-        increase: 25,
-    }
-}
-*/
+	return &PowerupEnergyIncrease{
+		x:         x,
+		y:         y,
+		velocityX: 0,
+		velocityY: 1.5,
+		activated: false,
+		counter:   0,
+		increase:  25,
+	}
+}
 
 func MakePowerupWeapon(x float64, y float64) Powerup {
 	return &PowerupWeapon{
@@ -443,20 +480,23 @@ func MakePowerupBomb(x float64, y float64) Powerup {
 	}
 }
 
-func MakeRandomPowerup(x float64, y float64) Powerup {
-	switch rand.N(4) {
-	case 0:
-		return MakePowerupEnergy(x, y)
-	case 1:
-		return MakePowerupHealth(x, y)
-	case 2:
-		return MakePowerupWeapon(x, y)
-	case 3:
-		return MakePowerupBomb(x, y)
-		// case 4: return MakePowerupEnergyIncrease(x, y)
+// the names accepted by MakePowerupKind
+var powerupKinds = []string{"energy", "health", "weapon", "bomb", "energy-increase"}
+
+// MakePowerupKind creates the powerup named by kind, using the same names as powerupState
+func MakePowerupKind(kind string, x float64, y float64) (Powerup, error) {
+	switch kind {
+	case "energy":
+		return MakePowerupEnergy(x, y), nil
+	case "health":
+		return MakePowerupHealth(x, y), nil
+	case "weapon":
+		return MakePowerupWeapon(x, y), nil
+	case "bomb":
+		return MakePowerupBomb(x, y), nil
+	case "energy-increase":
+		return MakePowerupEnergyIncrease(x, y), nil
 	}
 
-	panic("should not reach here")
-
-	// return nil
+	return nil, fmt.Errorf("unknown powerup kind %q", kind)
 }
@@ -26,6 +26,21 @@ var GalaxyShaderData []byte
 //go:embed shaders/planet.kage
 var PlanetShaderData []byte
 
+//go:embed shaders/bloom.kage
+var BloomShaderData []byte
+
+//go:embed shaders/aberration.kage
+var AberrationShaderData []byte
+
+//go:embed shaders/vignette.kage
+var VignetteShaderData []byte
+
+//go:embed shaders/crt.kage
+var CRTShaderData []byte
+
+//go:embed shaders/lighting.kage
+var LightingShaderData []byte
+
 func LoadRedShader() (*ebiten.Shader, error) {
 	return ebiten.NewShader(RedShaderData)
 }
@@ -53,3 +68,23 @@ func LoadGalaxyShader() (*ebiten.Shader, error) {
 func LoadPlanetShader() (*ebiten.Shader, error) {
 	return ebiten.NewShader(PlanetShaderData)
 }
+
+func LoadBloomShader() (*ebiten.Shader, error) {
+	return ebiten.NewShader(BloomShaderData)
+}
+
+func LoadAberrationShader() (*ebiten.Shader, error) {
+	return ebiten.NewShader(AberrationShaderData)
+}
+
+func LoadVignetteShader() (*ebiten.Shader, error) {
+	return ebiten.NewShader(VignetteShaderData)
+}
+
+func LoadCRTShader() (*ebiten.Shader, error) {
+	return ebiten.NewShader(CRTShaderData)
+}
+
+func LoadLightingShader() (*ebiten.Shader, error) {
+	return ebiten.NewShader(LightingShaderData)
+}
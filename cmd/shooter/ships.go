@@ -0,0 +1,128 @@
+package main
+
+import (
+	gameImages "github.com/kazzmir/webgl-shooter/images"
+)
+
+type ShipKind string
+
+const (
+	ShipStandard    ShipKind = "standard"
+	ShipInterceptor ShipKind = "interceptor"
+	ShipHeavy       ShipKind = "heavy"
+)
+
+type ShipDefinition struct {
+	Kind        ShipKind
+	Name        string
+	Description string
+	// the hitbox is the opaque part of the sprite, so smaller ships are harder to hit
+	Image gameImages.Image
+	// multiplier applied to acceleration and top speed
+	Speed     float64
+	MaxHealth float64
+	// added to the energy regenerated each frame
+	EnergyRegen float64
+}
+
+var shipDefinitions = []ShipDefinition{
+	{
+		Kind:        ShipStandard,
+		Name:        "Vanguard",
+		Description: "Balanced all-rounder",
+		Image:       gameImages.ImagePlayer,
+		Speed:       1,
+		MaxHealth:   100,
+		EnergyRegen: 0,
+	},
+	{
+		Kind:        ShipInterceptor,
+		Name:        "Interceptor",
+		Description: "Fast with a small hitbox, but fragile",
+		Image:       gameImages.ImagePlayerInterceptor,
+		Speed:       1.3,
+		MaxHealth:   70,
+		EnergyRegen: 0.1,
+	},
+	{
+		Kind:        ShipHeavy,
+		Name:        "Bulwark",
+		Description: "Slow and large, with thick armor and a big reactor",
+		Image:       gameImages.ImagePlayerHeavy,
+		Speed:       0.8,
+		MaxHealth:   150,
+		EnergyRegen: 0.2,
+	},
+}
+
+// returns the standard ship if the kind is unknown
+func shipDefinition(kind ShipKind) ShipDefinition {
+	for _, ship := range shipDefinitions {
+		if ship.Kind == kind {
+			return ship
+		}
+	}
+
+	return shipDefinitions[0]
+}
+
+// gun kinds use the same names as gunState.Kind
+var primaryGunKinds = []string{"basic", "dual-basic"}
+
+// an empty kind means no secondary gun
+var secondaryGunKinds = []string{"", "beam", "lightning", "missile"}
+
+// the ship and guns the player starts a run with
+type Loadout struct {
+	Ship      ShipKind `json:"ship"`
+	Primary   string   `json:"primary"`
+	Secondary string   `json:"secondary,omitempty"`
+}
+
+func DefaultLoadout() Loadout {
+	return Loadout{
+		Ship:    ShipStandard,
+		Primary: "basic",
+	}
+}
+
+func (loadout Loadout) Guns() []Gun {
+	states := []gunState{{Kind: loadout.Primary, Enabled: true}}
+	if loadout.Secondary != "" {
+		states = append(states, gunState{Kind: loadout.Secondary, Enabled: true})
+	}
+
+	guns := makeGunsFromState(states)
+	if len(guns) == 0 {
+		guns = append(guns, &BasicGun{enabled: true, elementType: ElementPhysical})
+	}
+
+	return guns
+}
+
+func gunKindName(kind string) string {
+	if kind == "" {
+		return "None"
+	}
+
+	guns := makeGunsFromState([]gunState{{Kind: kind}})
+	if len(guns) == 0 {
+		return kind
+	}
+
+	return gunDisplayName(guns[0])
+}
+
+// move to the next or previous entry in choices, wrapping around
+func cycleChoice[T comparable](choices []T, current T, direction int) T {
+	index := 0
+	for i, choice := range choices {
+		if choice == current {
+			index = i
+			break
+		}
+	}
+
+	index = (index + direction + len(choices)) % len(choices)
+	return choices[index]
+}
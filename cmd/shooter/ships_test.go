@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLoadoutGuns(t *testing.T) {
+	for _, primary := range primaryGunKinds {
+		for _, secondary := range secondaryGunKinds {
+			loadout := Loadout{Ship: ShipStandard, Primary: primary, Secondary: secondary}
+			guns := loadout.Guns()
+
+			want := 1
+			if secondary != "" {
+				want = 2
+			}
+			if len(guns) != want {
+				t.Fatalf("loadout %+v made %d guns, want %d", loadout, len(guns), want)
+			}
+
+			states := serializeGuns(guns)
+			if states[0].Kind != primary {
+				t.Fatalf("primary gun is %q, want %q", states[0].Kind, primary)
+			}
+			for _, gun := range guns {
+				if !gun.IsEnabled() {
+					t.Fatalf("loadout gun %T is not enabled", gun)
+				}
+			}
+		}
+	}
+}
+
+func TestCycleChoiceWraps(t *testing.T) {
+	if got := cycleChoice(secondaryGunKinds, "", -1); got != "missile" {
+		t.Fatalf("cycling left from the first entry gave %q", got)
+	}
+	if got := cycleChoice(secondaryGunKinds, "missile", 1); got != "" {
+		t.Fatalf("cycling right from the last entry gave %q", got)
+	}
+	if got := shipDefinition("unknown").Kind; got != ShipStandard {
+		t.Fatalf("unknown ship resolved to %q", got)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+const MaxBombCapacity = 9
+
+// the shop is shown between levels and lets the player spend credits
+type Shop struct {
+	Font         *text.GoTextFaceSource
+	SoundManager *SoundManager
+	Counter      uint64
+	List         OptionList
+	// difficulty of the level that starts when the shop is closed
+	Difficulty float64
+	// feedback from the last purchase
+	Message string
+	// in multiplayer the slave waits for the master to leave the shop
+	Waiting bool
+	// the players shopping on this machine, each takes a turn. only local co-op has more than one
+	Players []*Player
+	Turn    int
+}
+
+func gunDisplayName(gun Gun) string {
+	switch gun.(type) {
+	case *BasicGun:
+		return "Basic gun"
+	case *DualBasicGun:
+		return "Dual gun"
+	case *BeamGun:
+		return "Beam gun"
+	case *MissleGun:
+		return "Missile gun"
+	case *LightningGun:
+		return "Lightning gun"
+	}
+	return "Gun"
+}
+
+func gunSlotCost(player *Player) uint64 {
+	return 250 * uint64(len(player.Guns))
+}
+
+func maxHealthCost(player *Player) uint64 {
+	return uint64(150 * (1 + math.Max(0, player.MaxHealth-100)/20))
+}
+
+func maxEnergyCost(player *Player) uint64 {
+	return uint64(150 * (1 + player.MaxEnergyBonus/25))
+}
+
+func bombCapacityCost(player *Player) uint64 {
+	return 100 * uint64(player.BombCapacity-4)
+}
+
+func hasAllGuns(player *Player) bool {
+	before := len(player.Guns)
+	probe := &Player{Guns: append([]Gun(nil), player.Guns...)}
+	probe.EnableNextGun()
+	return len(probe.Guns) == before
+}
+
+// try to spend credits, returns false if the player cannot afford it
+func (shop *Shop) spend(player *Player, cost uint64) bool {
+	if player.Credits < cost {
+		shop.Message = fmt.Sprintf("Not enough credits, need %v", cost)
+		return false
+	}
+
+	player.Credits -= cost
+	return true
+}
+
+func (shop *Shop) makeOptions(player *Player) []*MenuOption {
+	var options []*MenuOption
+
+	options = append(options, &MenuOption{
+		TextFunc: func() string {
+			if hasAllGuns(player) {
+				return "Gun slot: all unlocked"
+			}
+			return fmt.Sprintf("Buy gun slot - %v", gunSlotCost(player))
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			if hasAllGuns(player) {
+				return nil
+			}
+			if shop.spend(player, gunSlotCost(player)) {
+				player.EnableNextGun()
+				shop.Message = fmt.Sprintf("Unlocked %v", gunDisplayName(player.Guns[len(player.Guns)-1]))
+				shop.List.Options = shop.makeOptions(player)
+			}
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	for _, gun := range player.Guns {
+		for _, branch := range gunUpgradeBranches(gun) {
+			options = append(options, &MenuOption{
+				TextFunc: func() string {
+					upgrades := gun.Upgrades()
+					name := fmt.Sprintf("%v %v", gunDisplayName(gun), gunBranchName(branch))
+					if upgrades.Branch != GunBranchNone && upgrades.Branch != branch {
+						return name + ": locked"
+					}
+					if upgrades.Tier >= MaxGunBranchTier {
+						return name + ": maxed"
+					}
+					return fmt.Sprintf("%v %v/%v - %v", name, upgrades.tier(branch)+1, MaxGunBranchTier, gunUpgradeCost(upgrades))
+				},
+				Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+					upgrades := gun.Upgrades()
+					if !upgrades.CanBuy(branch) {
+						return nil
+					}
+					if shop.spend(player, gunUpgradeCost(upgrades)) {
+						upgrades.Buy(branch)
+						shop.Message = fmt.Sprintf("%v %v upgraded", gunDisplayName(gun), gunBranchName(branch))
+					}
+					return nil
+				},
+				Respond: []ebiten.Key{ebiten.KeyEnter},
+			})
+		}
+	}
+
+	options = append(options, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("Max health +20 - %v", maxHealthCost(player))
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			if shop.spend(player, maxHealthCost(player)) {
+				player.MaxHealth += 20
+				player.Health = player.MaxHealth
+				shop.Message = fmt.Sprintf("Max health is now %v", player.MaxHealth)
+			}
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	options = append(options, &MenuOption{
+		TextFunc: func() string {
+			return fmt.Sprintf("Max energy +25 - %v", maxEnergyCost(player))
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			if shop.spend(player, maxEnergyCost(player)) {
+				player.IncreaseMaxEnergy(25)
+				shop.Message = fmt.Sprintf("Max energy is now %.0f", player.GetMaxEnergy())
+			}
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	options = append(options, &MenuOption{
+		TextFunc: func() string {
+			if player.BombCapacity >= MaxBombCapacity {
+				return "Bomb capacity: maxed"
+			}
+			return fmt.Sprintf("Bomb capacity %v - %v", player.BombCapacity+1, bombCapacityCost(player))
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			if player.BombCapacity >= MaxBombCapacity {
+				return nil
+			}
+			if shop.spend(player, bombCapacityCost(player)) {
+				player.BombCapacity += 1
+				player.IncreaseBombs()
+				shop.Message = fmt.Sprintf("Bomb capacity is now %v", player.BombCapacity)
+			}
+			return nil
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	options = append(options, &MenuOption{
+		TextFunc: func() string {
+			if shop.Waiting {
+				return "Waiting for partner"
+			}
+			if shop.Turn+1 < len(shop.Players) {
+				return fmt.Sprintf("Player %v's turn", shop.Turn+2)
+			}
+			return "Next level"
+		},
+		Action: func(self *MenuOption, run *Run, key ebiten.Key) error {
+			return run.LeaveShop()
+		},
+		Respond: []ebiten.Key{ebiten.KeyEnter},
+	})
+
+	return options
+}
+
+func MakeShop(font *text.GoTextFaceSource, soundManager *SoundManager, players []*Player, difficulty float64) *Shop {
+	shop := &Shop{
+		Font:         font,
+		SoundManager: soundManager,
+		Difficulty:   difficulty,
+		Players:      players,
+	}
+	shop.List.Options = shop.makeOptions(shop.Player())
+	return shop
+}
+
+// the player whose turn it is
+func (shop *Shop) Player() *Player {
+	return shop.Players[shop.Turn]
+}
+
+// hand the shop to the next player, returns false once everyone has had a turn
+func (shop *Shop) NextTurn() bool {
+	if shop.Turn+1 >= len(shop.Players) {
+		return false
+	}
+
+	shop.Turn += 1
+	shop.Message = ""
+	shop.List = OptionList{Options: shop.makeOptions(shop.Player())}
+	return true
+}
+
+func (shop *Shop) Update(run *Run) error {
+	shop.Counter += 1
+
+	for _, key := range shop.List.Keys(run) {
+		err := shop.List.HandleKey(run, shop.SoundManager, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (shop *Shop) Draw(screen *ebiten.Image) {
+	var x float64 = 100
+	var y float64 = 60
+
+	player := shop.Player()
+	title := "Shop"
+	if len(shop.Players) > 1 {
+		title = fmt.Sprintf("Shop - Player %v", shop.Turn+1)
+	}
+	drawText(screen, text.GoTextFace{Source: shop.Font, Size: 28}, x, y, title, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	drawText(screen, text.GoTextFace{Source: shop.Font, Size: 18}, x+200, y+6, fmt.Sprintf("Credits: %v", player.Credits), color.RGBA{R: 0xff, G: 0xdc, B: 0x52, A: 0xff})
+	y += 60
+
+	_, y = shop.List.Draw(screen, text.GoTextFace{Source: shop.Font, Size: 18}, x, y, 300, 6, shop.Counter)
+
+	if shop.Message != "" {
+		drawText(screen, text.GoTextFace{Source: shop.Font, Size: 16}, x, y+10, shop.Message, color.RGBA{R: 200, G: 220, B: 255, A: 255})
+	}
+}
+
+// called when a level ends, the next level starts when the player leaves the shop
+func (run *Run) OpenShop(difficulty float64) {
+	for _, player := range run.Game.players() {
+		player.BankScore()
+	}
+
+	// a partner on the other machine shops there
+	players := []*Player{run.Player}
+	if run.Game.Coop != nil {
+		players = append(players, run.Game.RemotePlayer)
+	}
+
+	run.Shop = MakeShop(run.Menu.Font, run.SoundManager, players, difficulty)
+	run.Mode = RunShop
+}
+
+func (run *Run) LeaveShop() error {
+	if run.Shop.NextTurn() {
+		return nil
+	}
+
+	if run.Game != nil && run.Game.isSlave() {
+		// the master sends level_start when it leaves its own shop
+		run.Shop.Waiting = true
+		return nil
+	}
+
+	notifyPeer := run.Game != nil && run.Game.isMaster()
+	return run.StartNextLevel(run.Shop.Difficulty, notifyPeer, "")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall/js"
+)
+
+// the browser has no files, settings go in local storage under this prefix
+const settingPrefix = "shooter."
+
+// storage the page is not allowed to use, like in a sandboxed iframe, throws a SecurityError instead of
+// being missing, and syscall/js turns that into a panic. deferred so that becomes err instead
+func recoverStorage(err *error) {
+	if thrown := recover(); thrown != nil {
+		*err = fmt.Errorf("local storage is not available: %v", thrown)
+	}
+}
+
+func localStorage() (storage js.Value, err error) {
+	defer recoverStorage(&err)
+
+	storage = js.Global().Get("localStorage")
+	if storage.IsUndefined() || storage.IsNull() {
+		return js.Value{}, errors.New("local storage is not available")
+	}
+	return storage, nil
+}
+
+// the data last saved under name. fs.ErrNotExist if nothing was
+func loadSetting(name string) (data []byte, err error) {
+	storage, err := localStorage()
+	if err != nil {
+		return nil, err
+	}
+
+	defer recoverStorage(&err)
+
+	value := storage.Call("getItem", settingPrefix+name)
+	if value.IsNull() {
+		return nil, fmt.Errorf("%v: %w", name, fs.ErrNotExist)
+	}
+	return []byte(value.String()), nil
+}
+
+func saveSetting(name string, data []byte) (err error) {
+	storage, err := localStorage()
+	if err != nil {
+		return err
+	}
+
+	defer recoverStorage(&err)
+
+	storage.Call("setItem", settingPrefix+name, string(data))
+	return nil
+}
@@ -0,0 +1,37 @@
+//go:build !js
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// settings are kept in the user's config directory, such as ~/.config/webgl-shooter on linux
+func settingsDirectory() (string, error) {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(config, "webgl-shooter"), nil
+}
+
+// the data last saved under name. fs.ErrNotExist if nothing was
+func loadSetting(name string) ([]byte, error) {
+	directory, err := settingsDirectory()
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(directory, name))
+}
+
+func saveSetting(name string, data []byte) error {
+	directory, err := settingsDirectory()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(directory, name), data, 0644)
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// how far the virtual stick's knob can be dragged from where the touch started
+const TouchStickRadius = 70
+
+// the stick is more precise than a controller's, so only a small wobble is ignored
+const TouchDeadzone = 0.15
+
+// a touch that moves further than this before it is lifted is a drag and not a tap
+const TouchTapDistance = 20
+
+// the on screen buttons, drawn over the bottom right of the HUD
+type TouchButton int
+
+const (
+	TouchFire TouchButton = iota
+	TouchBomb
+	TouchBoost
+	TouchAutoFire
+	TouchMenu
+	// the number of buttons
+	TouchButtons
+)
+
+func (button TouchButton) String() string {
+	switch button {
+	case TouchFire:
+		return "Fire"
+	case TouchBomb:
+		return "Bomb"
+	case TouchBoost:
+		return "Boost"
+	case TouchAutoFire:
+		return "Auto"
+	case TouchMenu:
+		return "Menu"
+	}
+
+	return "Unknown"
+}
+
+// where the button is drawn and can be pressed in the HUD area, x and y are its center
+func (button TouchButton) Layout(area HudArea) (float64, float64, float64) {
+	switch button {
+	case TouchFire:
+		return area.Right - 110, area.Bottom - 110, 60
+	case TouchBomb:
+		return area.Right - 230, area.Bottom - 70, 40
+	case TouchBoost:
+		return area.Right - 70, area.Bottom - 230, 40
+	case TouchAutoFire:
+		return area.Right - 190, area.Bottom - 190, 30
+	case TouchMenu:
+		return area.CenterX(), area.Top + 30, 26
+	}
+
+	return 0, 0, 0
+}
+
+func (button TouchButton) Contains(area HudArea, x float64, y float64) bool {
+	centerX, centerY, radius := button.Layout(area)
+	return math.Hypot(x-centerX, y-centerY) <= radius
+}
+
+// the button under the point, or -1
+func touchButtonAt(area HudArea, x float64, y float64) TouchButton {
+	for button := range TouchButtons {
+		if button.Contains(area, x, y) {
+			return button
+		}
+	}
+	return -1
+}
+
+// the movement of a virtual stick dragged dx, dy from where it was touched, from -1 to 1 on each axis
+func touchStickInput(dx float64, dy float64) (float64, float64) {
+	distance := math.Hypot(dx, dy)
+	if distance > TouchStickRadius {
+		dx *= TouchStickRadius / distance
+		dy *= TouchStickRadius / distance
+	}
+	return applyDeadzone(dx/TouchStickRadius, dy/TouchStickRadius, TouchDeadzone)
+}
+
+type touchPoint struct {
+	x float64
+	y float64
+}
+
+type activeTouch struct {
+	start   touchPoint
+	current touchPoint
+	// the button the touch started on, or -1
+	button TouchButton
+}
+
+// the virtual stick and buttons for phones and tablets. they show up once the screen is touched and
+// hide again when a key is pressed, so a laptop with a touch screen does not keep them around
+type TouchControls struct {
+	Active bool
+	// keep shooting without holding the fire button, toggled by its own button
+	AutoFire bool
+
+	touches map[ebiten.TouchID]*activeTouch
+	// the touch dragging the virtual stick, or -1
+	stick ebiten.TouchID
+	// buttons pressed this frame
+	pressed [TouchButtons]bool
+	// short touches lifted this frame, see MenuKeys
+	taps []touchPoint
+	// the HUD area of the view the buttons were laid out in this frame
+	area HudArea
+}
+
+func MakeTouchControls() *TouchControls {
+	return &TouchControls{
+		Active:  hasTouchScreen(),
+		touches: make(map[ebiten.TouchID]*activeTouch),
+		stick:   -1,
+	}
+}
+
+// where a touch is on the view
+func touchPosition(display *DisplaySettings, id ebiten.TouchID) touchPoint {
+	x, y := display.PointerPosition(ebiten.TouchPosition(id))
+	return touchPoint{x: x, y: y}
+}
+
+// called once per frame, before anything reads the touches. area is the HUD area of the whole view
+func (touch *TouchControls) Update(display *DisplaySettings, area HudArea) {
+	touch.area = area
+	touch.taps = touch.taps[:0]
+	touch.pressed = [TouchButtons]bool{}
+
+	for _, id := range inpututil.AppendJustReleasedTouchIDs(nil) {
+		state, ok := touch.touches[id]
+		if !ok {
+			continue
+		}
+		if math.Hypot(state.current.x-state.start.x, state.current.y-state.start.y) < TouchTapDistance {
+			touch.taps = append(touch.taps, state.current)
+		}
+		if touch.stick == id {
+			touch.stick = -1
+		}
+		delete(touch.touches, id)
+	}
+
+	for _, id := range inpututil.AppendJustPressedTouchIDs(nil) {
+		touch.Active = true
+
+		point := touchPosition(display, id)
+		state := &activeTouch{start: point, current: point, button: touchButtonAt(area, point.x, point.y)}
+		touch.touches[id] = state
+
+		switch state.button {
+		case TouchAutoFire:
+			touch.AutoFire = !touch.AutoFire
+		case TouchMenu:
+			touch.pressed[TouchMenu] = true
+		case -1:
+			if touch.stick == -1 && point.x < area.CenterX() {
+				touch.stick = id
+			}
+		}
+	}
+
+	for _, id := range ebiten.AppendTouchIDs(nil) {
+		state, ok := touch.touches[id]
+		if !ok {
+			continue
+		}
+
+		state.current = touchPosition(display, id)
+
+		// a thumb can slide between the buttons without being lifted
+		if id != touch.stick {
+			switch button := touchButtonAt(area, state.current.x, state.current.y); button {
+			case TouchFire, TouchBomb, TouchBoost:
+				touch.pressed[button] = true
+			}
+		}
+	}
+
+	if len(inpututil.AppendJustPressedKeys(nil)) > 0 {
+		touch.Active = false
+	}
+}
+
+// what the virtual stick and buttons are doing, for the local player
+func (touch *TouchControls) PlayerInput() playerInputState {
+	var input playerInputState
+	if !touch.Active {
+		return input
+	}
+
+	if state, ok := touch.touches[touch.stick]; ok {
+		input.MoveX, input.MoveY = touchStickInput(state.current.x-state.start.x, state.current.y-state.start.y)
+	}
+	input.Shoot = touch.pressed[TouchFire] || touch.AutoFire
+	input.Bomb = touch.pressed[TouchBomb]
+	input.Jump = touch.pressed[TouchBoost]
+	input.OpenMenu = touch.pressed[TouchMenu]
+	return input
+}
+
+// the keys a list of options should see for the taps this frame, see optionKeys
+func (touch *TouchControls) MenuKeys(layout *OptionLayout, options []*MenuOption, selected *int) []ebiten.Key {
+	return optionKeys(layout, options, selected, touch.taps)
+}
+
+// the keys a list of options should see for taps or clicks at the points. the option under a point is
+// selected, options changed with left and right go down when pressed on their left half and up on the
+// right, anything else is pressed with enter
+func optionKeys(layout *OptionLayout, options []*MenuOption, selected *int, points []touchPoint) []ebiten.Key {
+	var keys []ebiten.Key
+	for _, tap := range points {
+		index, left := layout.At(tap.x, tap.y)
+		if index < 0 || index >= len(options) {
+			continue
+		}
+
+		*selected = index
+		option := options[index]
+		switch {
+		case left && option.DoesRespond(ebiten.KeyArrowLeft):
+			keys = append(keys, ebiten.KeyArrowLeft)
+		case !left && option.DoesRespond(ebiten.KeyArrowRight):
+			keys = append(keys, ebiten.KeyArrowRight)
+		default:
+			keys = append(keys, ebiten.KeyEnter)
+		}
+	}
+	return keys
+}
+
+// whether anything was tapped this frame
+func (touch *TouchControls) Tapped() bool {
+	return len(touch.taps) > 0
+}
+
+func (touch *TouchControls) Draw(screen *ebiten.Image, font *text.GoTextFaceSource) {
+	if !touch.Active {
+		return
+	}
+
+	outline := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x60}
+	fill := premultiplyAlpha(color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x20})
+	lit := premultiplyAlpha(color.RGBA{R: 0xff, G: 0xdc, B: 0x52, A: 0x80})
+
+	// the stick rests in the corner until it is touched, then follows where the touch started
+	area := touch.area
+	baseX, baseY := area.Left+140, area.Bottom-140
+	knobX, knobY := baseX, baseY
+	if state, ok := touch.touches[touch.stick]; ok {
+		baseX, baseY = state.start.x, state.start.y
+		moveX, moveY := touchStickInput(state.current.x-state.start.x, state.current.y-state.start.y)
+		knobX, knobY = baseX+moveX*TouchStickRadius, baseY+moveY*TouchStickRadius
+	}
+	vector.StrokeCircle(screen, float32(baseX), float32(baseY), TouchStickRadius, 2, outline, true)
+	vector.FillCircle(screen, float32(knobX), float32(knobY), TouchStickRadius/2, fill, true)
+
+	face := text.GoTextFace{Source: font, Size: 14}
+	for button := range TouchButtons {
+		x, y, radius := button.Layout(area)
+		background := fill
+		if touch.pressed[button] || (button == TouchAutoFire && touch.AutoFire) {
+			background = lit
+		}
+		vector.FillCircle(screen, float32(x), float32(y), float32(radius), background, true)
+		vector.StrokeCircle(screen, float32(x), float32(y), float32(radius), 2, outline, true)
+
+		label := button.String()
+		if button == TouchAutoFire {
+			label = "Auto: Off"
+			if touch.AutoFire {
+				label = "Auto: On"
+			}
+		}
+		width, height := text.Measure(label, &face, 0)
+		drawText(screen, face, x-width/2, y-height/2, label, color.White)
+	}
+}
+
+// where the options of a list were drawn last frame, so a tap or a click can be matched to one
+type OptionLayout struct {
+	rows []optionRow
+	// the bars of options with a MenuSlider
+	sliders []optionRow
+}
+
+type optionRow struct {
+	index  int
+	x      float64
+	y      float64
+	width  float64
+	height float64
+}
+
+// forget the rows of the last frame, called before the options are drawn
+func (layout *OptionLayout) Reset() {
+	layout.rows = layout.rows[:0]
+	layout.sliders = layout.sliders[:0]
+}
+
+func (layout *OptionLayout) Add(index int, x float64, y float64, width float64, height float64) {
+	layout.rows = append(layout.rows, optionRow{index: index, x: x, y: y, width: width, height: height})
+}
+
+// the index of the option drawn at the point and whether the point is on its left half, or -1
+func (layout *OptionLayout) At(x float64, y float64) (int, bool) {
+	for _, row := range layout.rows {
+		if row.contains(x, y) {
+			return row.index, x < row.x+row.width/2
+		}
+	}
+	return -1, false
+}
+
+func (layout *OptionLayout) AddSlider(index int, x float64, y float64, width float64, height float64) {
+	layout.sliders = append(layout.sliders, optionRow{index: index, x: x, y: y, width: width, height: height})
+}
+
+// the index of the option whose slider is at the point and the value there, or -1
+func (layout *OptionLayout) SliderAt(x float64, y float64) (int, float64) {
+	for _, slider := range layout.sliders {
+		if slider.contains(x, y) {
+			return slider.index, slider.value(x)
+		}
+	}
+	return -1, 0
+}
+
+// the value of the option's slider at x, even past either end of the bar
+func (layout *OptionLayout) SliderValue(index int, x float64) float64 {
+	for _, slider := range layout.sliders {
+		if slider.index == index {
+			return slider.value(x)
+		}
+	}
+	return 0
+}
+
+func (row optionRow) contains(x float64, y float64) bool {
+	return x >= row.x && x < row.x+row.width && y >= row.y && y < row.y+row.height
+}
+
+// how far along the row x is, from 0 at the left to 1 at the right
+func (row optionRow) value(x float64) float64 {
+	return max(0, min(1, (x-row.x)/row.width))
+}
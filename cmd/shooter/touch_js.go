@@ -0,0 +1,16 @@
+package main
+
+import (
+	"syscall/js"
+)
+
+// phones and tablets report touch points, so the touch controls can be shown before the first touch
+func hasTouchScreen() bool {
+	navigator := js.Global().Get("navigator")
+	if navigator.IsUndefined() || navigator.IsNull() {
+		return false
+	}
+
+	points := navigator.Get("maxTouchPoints")
+	return points.Type() == js.TypeNumber && points.Int() > 0
+}
@@ -0,0 +1,8 @@
+//go:build !js
+
+package main
+
+// desktops only show the touch controls once the screen is actually touched
+func hasTouchScreen() bool {
+	return false
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestTouchStickInput(t *testing.T) {
+	if x, y := touchStickInput(3, -3); x != 0 || y != 0 {
+		t.Errorf("a wobble moved %v, %v", x, y)
+	}
+
+	// dragging past the edge of the stick is full speed in the same direction
+	x, y := touchStickInput(TouchStickRadius*3, TouchStickRadius*4)
+	if math.Abs(math.Hypot(x, y)-1) > 1e-9 || math.Abs(x/y-0.75) > 1e-9 {
+		t.Errorf("a long drag moved %v, %v", x, y)
+	}
+
+	if x, _ := touchStickInput(-TouchStickRadius/2, 0); x >= 0 || x <= -1 {
+		t.Errorf("half a drag left moved %v", x)
+	}
+}
+
+func TestOptionLayout(t *testing.T) {
+	var layout OptionLayout
+	layout.Add(0, 90, 90, 200, 40)
+	layout.Add(1, 90, 150, 200, 40)
+
+	if index, left := layout.At(100, 160); index != 1 || !left {
+		t.Errorf("left of the second option was %v %v", index, left)
+	}
+	if index, left := layout.At(250, 100); index != 0 || left {
+		t.Errorf("right of the first option was %v %v", index, left)
+	}
+	if index, _ := layout.At(100, 135); index != -1 {
+		t.Errorf("the gap between options was %v", index)
+	}
+
+	layout.Reset()
+	if index, _ := layout.At(100, 160); index != -1 {
+		t.Errorf("an option was left after a reset: %v", index)
+	}
+}
+
+func TestTouchMenuKeys(t *testing.T) {
+	options := []*MenuOption{
+		{Text: "Start", Respond: []ebiten.Key{ebiten.KeyEnter}},
+		{Text: "Volume", Respond: []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyEnter}},
+	}
+
+	var layout OptionLayout
+	layout.Add(0, 0, 0, 200, 40)
+	layout.Add(1, 0, 50, 200, 40)
+
+	selected := 0
+	touch := &TouchControls{taps: []touchPoint{{x: 20, y: 60}}}
+	if keys := touch.MenuKeys(&layout, options, &selected); selected != 1 || !slices.Equal(keys, []ebiten.Key{ebiten.KeyArrowLeft}) {
+		t.Errorf("tapping the left of the volume selected %v and pressed %v", selected, keys)
+	}
+
+	touch.taps = []touchPoint{{x: 180, y: 10}}
+	if keys := touch.MenuKeys(&layout, options, &selected); selected != 0 || !slices.Equal(keys, []ebiten.Key{ebiten.KeyEnter}) {
+		t.Errorf("tapping start selected %v and pressed %v", selected, keys)
+	}
+
+	// options drawn last frame that are gone now are ignored
+	touch.taps = []touchPoint{{x: 20, y: 60}}
+	if keys := touch.MenuKeys(&layout, options[:1], &selected); selected != 0 || len(keys) != 0 {
+		t.Errorf("tapping a removed option selected %v and pressed %v", selected, keys)
+	}
+}
+
+func TestOptionLayoutSliders(t *testing.T) {
+	var layout OptionLayout
+	layout.Add(0, 0, 0, 200, 40)
+	layout.AddSlider(0, 220, 0, 200, 40)
+
+	if index, value := layout.SliderAt(270, 20); index != 0 || value != 0.25 {
+		t.Errorf("a quarter along the slider was %v %v", index, value)
+	}
+	if index, _ := layout.SliderAt(100, 20); index != -1 {
+		t.Errorf("the option's label was slider %v", index)
+	}
+
+	// a drag past the ends stops at them
+	if value := layout.SliderValue(0, 1000); value != 1 {
+		t.Errorf("dragging past the right end was %v", value)
+	}
+	if value := layout.SliderValue(0, 0); value != 0 {
+		t.Errorf("dragging past the left end was %v", value)
+	}
+}
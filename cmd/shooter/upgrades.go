@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math"
+)
+
+type GunBranch string
+
+const (
+	GunBranchNone   GunBranch = ""
+	GunBranchSpread GunBranch = "spread"
+	GunBranchRate   GunBranch = "rate"
+	GunBranchPower  GunBranch = "power"
+)
+
+const MaxGunBranchTier = 3
+
+// upgrades bought in the shop. a gun can only follow one branch, and each purchase raises the tier
+type GunUpgrades struct {
+	Branch GunBranch
+	Tier   int
+}
+
+func (upgrades *GunUpgrades) CanBuy(branch GunBranch) bool {
+	if upgrades.Tier >= MaxGunBranchTier {
+		return false
+	}
+
+	return upgrades.Branch == GunBranchNone || upgrades.Branch == branch
+}
+
+func (upgrades *GunUpgrades) Buy(branch GunBranch) bool {
+	if !upgrades.CanBuy(branch) {
+		return false
+	}
+
+	upgrades.Branch = branch
+	upgrades.Tier += 1
+	return true
+}
+
+func (upgrades *GunUpgrades) tier(branch GunBranch) int {
+	if upgrades.Branch == branch {
+		return upgrades.Tier
+	}
+	return 0
+}
+
+// multiplier applied to a gun's Rate()
+func (upgrades *GunUpgrades) RateFactor() float64 {
+	return 1 + 0.2*float64(upgrades.tier(GunBranchRate))
+}
+
+// multiplier applied to the strength of each bullet
+func (upgrades *GunUpgrades) PowerFactor() float64 {
+	return 1 + 0.25*float64(upgrades.tier(GunBranchPower))
+}
+
+func gunBranchName(branch GunBranch) string {
+	switch branch {
+	case GunBranchSpread:
+		return "Spread"
+	case GunBranchRate:
+		return "Rate"
+	case GunBranchPower:
+		return "Power"
+	}
+	return "None"
+}
+
+// the pair of branches each gun can choose between
+func gunUpgradeBranches(gun Gun) []GunBranch {
+	switch gun.(type) {
+	case *BasicGun, *DualBasicGun:
+		return []GunBranch{GunBranchSpread, GunBranchRate}
+	case *BeamGun:
+		return []GunBranch{GunBranchSpread, GunBranchPower}
+	case *MissleGun, *LightningGun:
+		return []GunBranch{GunBranchPower, GunBranchRate}
+	}
+	return nil
+}
+
+func gunUpgradeCost(upgrades *GunUpgrades) uint64 {
+	return uint64(120 * math.Pow(2, float64(upgrades.Tier)))
+}
+
+// apply the spread and power branches to freshly created bullets
+func applyGunUpgrades(gun Gun, bullets []*Bullet) []*Bullet {
+	upgrades := gun.Upgrades()
+	if upgrades == nil || upgrades.Tier == 0 {
+		return bullets
+	}
+
+	power := upgrades.PowerFactor()
+	if power != 1 {
+		for _, bullet := range bullets {
+			bullet.Strength *= power
+		}
+	}
+
+	spread := upgrades.tier(GunBranchSpread)
+	if spread == 0 {
+		return bullets
+	}
+
+	out := make([]*Bullet, 0, len(bullets)*3)
+	angle := 0.08 + 0.04*float64(spread)
+	for _, bullet := range bullets {
+		out = append(out, bullet)
+
+		speed := math.Hypot(bullet.velocityX, bullet.velocityY)
+		if speed == 0 {
+			continue
+		}
+		heading := math.Atan2(bullet.velocityY, bullet.velocityX)
+		for _, side := range []float64{-1, 1} {
+			extra := *bullet
+			extra.velocityX = math.Cos(heading+side*angle) * speed
+			extra.velocityY = math.Sin(heading+side*angle) * speed
+			// the heading and Angle both turn clockwise, so the picture turns with the bullet
+			extra.Angle = bullet.Angle + side*angle
+			extra.Strength = bullet.Strength * (0.4 + 0.1*float64(spread))
+			out = append(out, &extra)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math"
+)
+
+// how close the player can get to the top or bottom of the view before a following camera moves
+const CameraEdgeMarginY = 250
+
+type CameraMode int
+
+const (
+	// the camera follows the player up and down
+	CameraFollow CameraMode = iota
+	// the camera climbs on its own at the level's scroll speed and the player is kept inside the view
+	CameraScroll
+)
+
+// the world is always LogicalWidth wide, only its height changes from level to level
+type WorldLayout struct {
+	// never shorter than the view
+	Height float64
+	Mode   CameraMode
+	// pixels per tick the camera climbs in CameraScroll mode
+	ScrollSpeed float64
+}
+
+// the classic single screen tall level
+var ClassicLayout = WorldLayout{Height: ScreenHeight, Mode: CameraFollow}
+
+// levels cycle through these. the scrolling level climbs its whole height in about the time it takes
+// for the boss to show up
+var worldLayouts = []WorldLayout{
+	ClassicLayout,
+	{Height: ScreenHeight * 2, Mode: CameraFollow},
+	{Height: ScreenHeight * 3, Mode: CameraScroll, ScrollSpeed: ScreenHeight * 2 / (60 * 120)},
+}
+
+// the layout of a level. both peers know the difficulty of a level, so they pick the same layout
+// without having to send it
+func worldLayoutFor(difficulty float64) WorldLayout {
+	return worldLayouts[levelFor(difficulty)%len(worldLayouts)]
+}
+
+// which level a difficulty belongs to, counting from 0
+func levelFor(difficulty float64) int {
+	// each level is 1.5 times as difficult as the one before, starting at 1
+	return int(math.Round(math.Log(math.Max(1, difficulty)) / math.Log(1.5)))
+}
+
+// where players start, near the bottom of a world this tall
+func playerStartY(worldHeight float64) float64 {
+	return worldHeight - 100
+}
+
+// the camera starts at the bottom of the world, where the players are. width is the width of the view
+func MakeCamera(layout WorldLayout, width float64) *Camera {
+	camera := &Camera{
+		x:           (LogicalWidth - width) / 2,
+		y:           layout.Height - ScreenHeight,
+		width:       width,
+		worldHeight: layout.Height,
+	}
+	if layout.Mode == CameraScroll {
+		camera.scrollSpeed = layout.ScrollSpeed
+	}
+	camera.Clamp()
+	return camera
+}
+
+// move a scrolling camera up the level, does nothing for a camera that follows the player
+func (camera *Camera) Scroll() {
+	camera.y -= camera.scrollSpeed
+	camera.Clamp()
+}
+
+func (camera *Camera) IsScrolling() bool {
+	return camera.scrollSpeed > 0
+}
+
+// keep the player inside the view of a scrolling camera, the bottom edge pushes the player along
+func (camera *Camera) Contain(player *Player) {
+	if !camera.IsScrolling() {
+		return
+	}
+
+	halfHeight := float64(player.rawImage.Bounds().Dy()) / 2
+	player.y = math.Max(camera.y+halfHeight, math.Min(player.y, camera.y+ScreenHeight-halfHeight))
+}
+
+// whether y is within margin of the band of the world the camera shows, whatever the x
+func (camera *Camera) SeesHeight(y float64, margin float64) bool {
+	return y > camera.y-margin && y < camera.y+ScreenHeight+margin
+}
+
+// whether any player can see the height y, give or take margin. only the height counts, things off to
+// the side are still in play as they always were, see onLogicalScreen. the partner in a network game has
+// a camera of its own that the master never hears about, so it is taken to be the view centered on the
+// partner
+func (game *Game) inView(y float64, margin float64) bool {
+	if game.Coop != nil && game.Coop.Split {
+		return game.Coop.Views[0].SeesHeight(y, margin) || game.Coop.Views[1].SeesHeight(y, margin)
+	}
+	if game.Camera.SeesHeight(y, margin) {
+		return true
+	}
+
+	if game.Coop == nil && game.isMaster() && game.RemotePlayer != nil {
+		partner := Camera{
+			x:           game.RemotePlayer.x - game.Camera.Width()/2,
+			y:           game.RemotePlayer.y - ScreenHeight/2,
+			worldHeight: game.Camera.WorldHeight(),
+		}
+		partner.Clamp()
+		return partner.SeesHeight(y, margin)
+	}
+
+	return false
+}
+
+// whether x, y is inside the world of the level, give or take margin. bullets and enemies that leave it
+// are gone
+func (game *Game) inWorld(x float64, y float64, margin float64) bool {
+	return onLogicalScreen(x, y, margin, game.Camera.WorldHeight())
+}
+
+// whether something that drifts down the world, like an asteroid or a powerup, has not fallen off the
+// bottom of it yet. they start above the world so the top does not count
+func (game *Game) aboveBottom(y float64, margin float64) bool {
+	return y < game.Camera.WorldHeight()+margin
+}
+
+// wrap a position into [low, high), used by background layers that repeat forever
+func wrapRange(value float64, low float64, high float64) float64 {
+	size := high - low
+	return low + math.Mod(math.Mod(value-low, size)+size, size)
+}
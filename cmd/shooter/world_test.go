@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestWorldLayoutFor(t *testing.T) {
+	difficulty := 1.0
+	for level := range len(worldLayouts) * 2 {
+		want := worldLayouts[level%len(worldLayouts)]
+		if got := worldLayoutFor(difficulty); got != want {
+			t.Errorf("level %v with difficulty %v got layout %+v, want %+v", level, difficulty, got, want)
+		}
+		difficulty *= 1.5
+	}
+}
+
+func TestCameraVerticalBounds(t *testing.T) {
+	const worldHeight = ScreenHeight * 2
+	camera := MakeCamera(WorldLayout{Height: worldHeight, Mode: CameraFollow}, ScreenWidth)
+	if camera.y != ScreenHeight {
+		t.Fatalf("camera starts at %v, want the bottom of the world", camera.y)
+	}
+
+	camera.y = -100
+	camera.Clamp()
+	if camera.y != 0 {
+		t.Errorf("camera above the world at %v", camera.y)
+	}
+
+	camera.y = worldHeight
+	camera.Clamp()
+	if camera.y != ScreenHeight {
+		t.Errorf("camera below the world at %v", camera.y)
+	}
+
+	// a following camera never moves on its own
+	camera.Scroll()
+	if camera.y != ScreenHeight {
+		t.Errorf("following camera scrolled to %v", camera.y)
+	}
+
+	camera = MakeCamera(WorldLayout{Height: worldHeight, Mode: CameraScroll, ScrollSpeed: 10}, ScreenWidth)
+	for range 200 {
+		camera.Scroll()
+	}
+	if camera.y != 0 {
+		t.Errorf("scrolling camera stopped at %v, want the top of the world", camera.y)
+	}
+}
+
+func TestBulletsOutOfView(t *testing.T) {
+	game := &Game{Camera: &Camera{x: 0, y: ScreenHeight, width: 800, worldHeight: ScreenHeight * 3}}
+
+	if !game.inView(ScreenHeight+100, BulletViewMargin) {
+		t.Errorf("a bullet in the middle of the view is out of view")
+	}
+	// enemies waiting above the view are out of reach
+	if game.inView(ScreenHeight-200, BulletViewMargin) {
+		t.Errorf("a bullet where enemies spawn is in view")
+	}
+
+	// the partner of a network game sees the screen around it
+	game.Multiplayer = &gameMultiplayer{Role: multiplayerRoleMaster}
+	game.RemotePlayer = &Player{x: 400, y: 250}
+	if !game.inView(200, BulletViewMargin) {
+		t.Errorf("a bullet next to the partner is out of view")
+	}
+}
+
+func TestSideBulletsStayInPlay(t *testing.T) {
+	game := &Game{Camera: &Camera{x: 0, y: 0, width: ScreenWidth, worldHeight: ScreenHeight}}
+
+	// an enemy off to the right of the view still shoots at the player
+	bullet := &Bullet{x: 1800, y: 300, health: 1}
+	if !game.inView(bullet.y, BulletViewMargin) || !game.inWorld(bullet.x, bullet.y, 10) || !bullet.IsAlive() {
+		t.Errorf("a bullet at %v, %v right of the view was removed", bullet.x, bullet.y)
+	}
+}
+
+func TestWorldHeightFollowsLayout(t *testing.T) {
+	tall := &Game{Camera: MakeCamera(WorldLayout{Height: ScreenHeight * 3, Mode: CameraFollow}, ScreenWidth)}
+	classic := &Game{Camera: MakeCamera(ClassicLayout, ScreenWidth)}
+
+	// the same spot is inside a tall world and below a single screen one
+	if !tall.inWorld(400, ScreenHeight*2, 10) || !tall.aboveBottom(ScreenHeight*2, 20) {
+		t.Errorf("the middle of a tall world is outside of it")
+	}
+	if classic.inWorld(400, ScreenHeight*2, 10) || classic.aboveBottom(ScreenHeight*2, 20) {
+		t.Errorf("below a single screen world is inside of it")
+	}
+
+	if y := playerStartY(tall.Camera.WorldHeight()); y != ScreenHeight*3-100 {
+		t.Errorf("players start at %v in a tall world", y)
+	}
+}
+
+func TestWrapRange(t *testing.T) {
+	for _, test := range []struct{ value, want float64 }{
+		{5, 5},
+		{-60, 840},
+		{900, 0},
+	} {
+		if got := wrapRange(test.value, -50, 850); got != test.want {
+			t.Errorf("wrap %v got %v, want %v", test.value, got, test.want)
+		}
+	}
+}
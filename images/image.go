@@ -12,6 +12,8 @@ import (
 type Image string
 
 const ImagePlayer = Image("player")
+const ImagePlayerInterceptor = Image("player-interceptor")
+const ImagePlayerHeavy = Image("player-heavy")
 const ImageStar1 = Image("star1")
 const ImageStar2 = Image("star2")
 const ImagePlanet = Image("planet")
@@ -60,6 +62,12 @@ const ImageLightningIcon = Image("lightning")
 //go:embed player/player.png
 var playerImage []byte
 
+//go:embed player/interceptor.png
+var playerInterceptorImage []byte
+
+//go:embed player/heavy.png
+var playerHeavyImage []byte
+
 //go:embed player/bullet.png
 var bulletImage []byte
 
@@ -198,6 +206,10 @@ func LoadImage(name Image) (image.Image, error) {
 	switch name {
 	case ImagePlayer:
 		return loadEmbeddedImage(playerImage)
+	case ImagePlayerInterceptor:
+		return loadEmbeddedImage(playerInterceptorImage)
+	case ImagePlayerHeavy:
+		return loadEmbeddedImage(playerHeavyImage)
 	case ImageStar1:
 		return loadEmbeddedImage(star1Image)
 	case ImageStar2: